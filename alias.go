@@ -0,0 +1,251 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"reflect"
+	"strings"
+)
+
+// SetSelectorFold sets whether the selector lookups of Eval are case-insensitive,
+// and tolerant of the json and mapstructure names, such as user.first_name@ for User.FirstName@.
+// NOTE:
+//  The exact selector is always preferred;
+//  It should be called before the vm is used.
+func (vm *VM) SetSelectorFold(enable bool) *VM {
+	vm.selectorFold = enable
+	return vm
+}
+
+// JSONTag the json tag of the struct field, interpreted like the JSON encoder
+type JSONTag struct {
+	Name      string // the wire name, "" if Inline or Ignored
+	OmitEmpty bool   // the omitempty option
+	String    bool   // the string option, the number or bool value is encoded as string
+	Inline    bool   // the fields are flattened, the embedded struct without name or the inline option
+	Ignored   bool   // the field is not encoded, json:"-" or unexported
+}
+
+// ParseJSONTag parses the json tag of the struct field, such as json:"name,omitempty,string".
+// NOTE:
+//  json:"-," is the name "-";
+//  The embedded struct without the name, or with the inline option of encoding/json v2, is inlined;
+//  The unexported field is ignored unless it is an embedded struct, but not a pointer.
+func ParseJSONTag(structField reflect.StructField) JSONTag {
+	tag, hasTag := structField.Tag.Lookup("json")
+	if tag == "-" {
+		return JSONTag{Ignored: true}
+	}
+	var r JSONTag
+	opts := strings.Split(tag, ",")
+	r.Name = opts[0]
+	for _, opt := range opts[1:] {
+		switch opt {
+		case "omitempty":
+			r.OmitEmpty = true
+		case "string":
+			r.String = true
+		case "inline":
+			r.Inline = true
+		}
+	}
+	t := structField.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	isStruct := t.Kind() == reflect.Struct
+	if structField.Anonymous && isStruct && (r.Name == "" || !hasTag) {
+		r.Inline = true
+	}
+	if r.Inline && isStruct {
+		if structField.PkgPath != "" && structField.Type.Kind() == reflect.Ptr {
+			// encoding/json cannot allocate the embedded pointer of the unexported struct type
+			return JSONTag{Ignored: true}
+		}
+		r.Name = ""
+		return r
+	}
+	r.Inline = false
+	if structField.PkgPath != "" {
+		return JSONTag{Ignored: true}
+	}
+	if r.Name == "" {
+		r.Name = structField.Name
+	}
+	return r
+}
+
+// jsonFieldName returns the json name of the field,
+// "" if it is an inlined struct whose fields are flattened by the JSON encoder.
+func jsonFieldName(structField reflect.StructField) (name string, ignored bool) {
+	tag := ParseJSONTag(structField)
+	return tag.Name, tag.Ignored
+}
+
+// mapstructureFieldName returns the mapstructure name of the field,
+// "" if it is an embedded struct with the squash option, whose fields are flattened by mapstructure.
+func mapstructureFieldName(structField reflect.StructField) string {
+	return KeyFieldName(structField, "mapstructure")
+}
+
+// KeyFieldName returns the key of the field by the mapstructure-style tag, such as mapstructure of Viper and koanf,
+// the field name if the tag has no key, "" if it is an embedded struct with the squash option.
+func KeyFieldName(structField reflect.StructField, tagName string) string {
+	name := structField.Tag.Get(tagName)
+	var opts string
+	if i := strings.IndexByte(name, ','); i >= 0 {
+		name, opts = name[:i], name[i:]
+	}
+	if structField.Anonymous && strings.Contains(opts, ",squash") {
+		return ""
+	}
+	if name == "" || name == "-" {
+		return structField.Name
+	}
+	return name
+}
+
+// joinJSONName joins the json or mapstructure names of the parent and the child field.
+func joinJSONName(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
+
+// getExpr returns the expression entry by the selector,
+// tries the case-insensitive and json name aliases if the selector fold is enabled.
+func (s *Struct) getExpr(selector string) (*exprEntry, bool) {
+	e, ok := s.exprs[selector]
+	if ok || !s.vm.selectorFold {
+		return e, ok
+	}
+	e, ok = s.getAliases().fold[strings.ToLower(selector)]
+	return e, ok
+}
+
+type selectorAliases struct {
+	json         map[string]*exprEntry // the json selectors
+	mapstructure map[string]*exprEntry // the mapstructure selectors
+	fold         map[string]*exprEntry // lower-case of the go, json and mapstructure selectors
+	mapFold      map[string]*exprEntry // lower-case of the mapstructure and go selectors, without the json ones
+}
+
+// getAliases returns the selector aliases, built on first use.
+func (s *Struct) getAliases() *selectorAliases {
+	s.aliasOnce.Do(func() {
+		a := &selectorAliases{
+			json:         make(map[string]*exprEntry, len(s.exprList)),
+			mapstructure: make(map[string]*exprEntry, len(s.exprList)),
+			fold:         make(map[string]*exprEntry, len(s.exprList)*3),
+			mapFold:      make(map[string]*exprEntry, len(s.exprList)*2),
+		}
+		for _, e := range s.exprList {
+			a.add(a.fold, strings.ToLower(e.selector), e)
+		}
+		for _, e := range s.exprList {
+			f, ok := s.fields[e.field]
+			if !ok || f.jsonIgnored {
+				continue
+			}
+			jsonSelector := f.jsonName + e.selector[len(e.field):]
+			a.add(a.json, jsonSelector, e)
+			a.add(a.fold, strings.ToLower(jsonSelector), e)
+		}
+		for _, e := range s.exprList {
+			f, ok := s.fields[e.field]
+			if !ok {
+				continue
+			}
+			mapSelector := f.mapName + e.selector[len(e.field):]
+			a.add(a.mapstructure, mapSelector, e)
+			a.add(a.fold, strings.ToLower(mapSelector), e)
+			a.add(a.mapFold, strings.ToLower(mapSelector), e)
+		}
+		for _, e := range s.exprList {
+			a.add(a.mapFold, strings.ToLower(e.selector), e)
+		}
+		s.aliases = a
+	})
+	return s.aliases
+}
+
+// add adds the alias, the first one wins when they conflict.
+func (*selectorAliases) add(m map[string]*exprEntry, alias string, e *exprEntry) {
+	if _, ok := m[alias]; !ok {
+		m[alias] = e
+	}
+}
+
+// EvalByJSONPath evaluate the value of the struct tag expression by the json selector expression,
+// which uses the json tag names instead of the go field names.
+// NOTE:
+//  format: jsonName, jsonName.exprName, jsonName1.jsonName2.exprName1
+//  such as user.first_name@min;
+//  The go field name is used if the field has no json name;
+//  The fields of the embedded struct without json name, or with the inline option, are flattened like encoding/json;
+//  The fields that are not encoded, such as json:"-" and the unexported ones, are not found.
+func (t *TagExpr) EvalByJSONPath(selector string) interface{} {
+	a := t.s.getAliases()
+	e, ok := a.json[selector]
+	if !ok && t.s.vm.selectorFold {
+		e, ok = a.fold[strings.ToLower(selector)]
+	}
+	if !ok {
+		return nil
+	}
+	v, _ := t.safeRun(e)
+	return v
+}
+
+// EvalByMapPath evaluate the value of the struct tag expression by the mapstructure selector expression,
+// which uses the mapstructure tag names instead of the go field names, such as server.port@.
+// NOTE:
+//  format: mapName, mapName.exprName, mapName1.mapName2.exprName1;
+//  The go field name is used if the field has no mapstructure name;
+//  The fields of the embedded struct with the squash option are flattened like mapstructure;
+//  The lookup is case-insensitive like mapstructure, the exact selector is preferred,
+//  then the mapstructure names are preferred to the go field names, the json names are never matched.
+func (t *TagExpr) EvalByMapPath(selector string) interface{} {
+	a := t.s.getAliases()
+	e, ok := a.mapstructure[selector]
+	if !ok {
+		e, ok = a.mapFold[strings.ToLower(selector)]
+	}
+	if !ok {
+		return nil
+	}
+	v, _ := t.safeRun(e)
+	return v
+}
+
+// FieldPath returns the json or mapstructure names path of the field, for the error messages.
+// NOTE:
+//  field format: fieldName, fieldName1.fieldName2;
+//  tagName is json or mapstructure, otherwise the go field name is returned;
+//  Return false if the field is not found, or it is not encoded by the JSON encoder for json.
+func (s *Struct) FieldPath(field, tagName string) (string, bool) {
+	f, ok := s.fields[field]
+	if !ok {
+		return "", false
+	}
+	switch tagName {
+	case "json":
+		return f.jsonName, !f.jsonIgnored
+	case "mapstructure":
+		return f.mapName, true
+	}
+	return field, true
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+// Arena bump allocator of the transient evaluation objects,
+// such as the TagExpr handlers and the argument lists of the functions.
+// NOTE:
+//  It is not concurrency safe, use one arena per goroutine;
+//  The errors are still allocated from the heap, such as *EvalError, *NotBoolError and the validator errors and warnings,
+//  so it saves the allocations of the passing evaluations, not the failing ones;
+//  Call Reset when the request is done, then the objects allocated from it must not be used.
+type Arena struct {
+	tagExprs []TagExpr
+	tagUsed  int
+	args     []interface{}
+	argsUsed int
+}
+
+// NewArena creates an arena.
+func NewArena() *Arena {
+	return &Arena{
+		tagExprs: make([]TagExpr, 16),
+		args:     make([]interface{}, 256),
+	}
+}
+
+// Reset releases all the objects allocated from the arena, for reuse.
+func (a *Arena) Reset() {
+	for i := 0; i < a.tagUsed; i++ {
+		a.tagExprs[i] = TagExpr{}
+	}
+	for i := 0; i < a.argsUsed; i++ {
+		a.args[i] = nil
+	}
+	a.tagUsed = 0
+	a.argsUsed = 0
+}
+
+func (a *Arena) newTagExpr() *TagExpr {
+	if a.tagUsed == len(a.tagExprs) {
+		// the objects in the old block are still referenced, never move them
+		a.tagExprs = make([]TagExpr, 2*len(a.tagExprs)+1)
+		a.tagUsed = 0
+	}
+	t := &a.tagExprs[a.tagUsed]
+	a.tagUsed++
+	return t
+}
+
+func (a *Arena) allocArgs(n int) []interface{} {
+	if a.argsUsed+n > len(a.args) {
+		size := 2 * len(a.args)
+		if size < n {
+			size = n
+		}
+		a.args = make([]interface{}, size)
+		a.argsUsed = 0
+	}
+	args := a.args[a.argsUsed : a.argsUsed+n : a.argsUsed+n]
+	a.argsUsed += n
+	return args
+}
+
+// RunWithArena returns the tag expression handler of the @structPtr,
+// the handler and its transient evaluation objects are allocated from the arena.
+// NOTE:
+//  The args passed to the functions registered by RegFunc and to the plugins are not allocated from the arena,
+//  so they stay valid after the arena is reset.
+func (vm *VM) RunWithArena(structPtr interface{}, arena *Arena) (*TagExpr, error) {
+	s, ptr, err := vm.getStruct(structPtr)
+	if err != nil {
+		return nil, err
+	}
+	te := arena.newTagExpr()
+	te.s = s
+	te.vm = vm
+	te.ptr = ptr
+	te.arena = arena
+	return te, nil
+}
+
+// allocArgs returns the argument list of the function, from the arena if any.
+func (t *TagExpr) allocArgs(n int) []interface{} {
+	if t == nil || t.arena == nil {
+		return make([]interface{}, n)
+	}
+	return t.arena.allocArgs(n)
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"fmt"
+)
+
+// NodeKind the kind of the syntax tree node
+type NodeKind int
+
+// The kinds of the syntax tree node
+const (
+	// LiteralNode bool, string, float64 or nil literal, the value is in Value
+	LiteralNode NodeKind = iota + 1
+	// SelectorNode field value, the field is in Name ("" is the current field),
+	// the subscripts are in Args
+	SelectorNode
+	// GroupNode parenthesized expression, the content is Args[0]
+	GroupNode
+	// OperatorNode binary operator, the operator is in Op, the operands are Args[0] and Args[1]
+	OperatorNode
+	// FuncNode function call, the name is in Name, the arguments are in Args;
+	// the pattern of regexp and the format of sprintf are the literal Args[0]
+	FuncNode
+	// DiveNode the element being iterated by count, any and all, Name is #k or #v
+	DiveNode
+	// RuleNode the result of another expression, the selector is in Name, such as Name@ for @Name
+	RuleNode
+	// ParamNode the named parameter, the name is in Name, such as max_items for %max_items%
+	ParamNode
+)
+
+// Node the read-only view of the syntax tree node of the expression
+type Node struct {
+	Kind   NodeKind
+	Op     string
+	Name   string
+	Value  interface{}
+	Prefix string // the bool prefix, "", "!" or "!!"
+	Args   []*Node
+}
+
+// AST returns the syntax tree of the expression, after the constant folding.
+func (p *Expr) AST() *Node {
+	return newNode(p.expr.RightOperand())
+}
+
+func newNode(e ExprNode) *Node {
+	switch r := e.(type) {
+	case nil:
+		return nil
+	case *boolExprNode:
+		return &Node{Kind: LiteralNode, Value: r.val}
+	case *stringExprNode:
+		return &Node{Kind: LiteralNode, Value: r.val}
+	case *digitalExprNode:
+		return &Node{Kind: LiteralNode, Value: r.val}
+	case *nilExprNode:
+		return &Node{Kind: LiteralNode}
+	case *diveExprNode:
+		name := "#v"
+		if r.key {
+			name = "#k"
+		}
+		return &Node{Kind: DiveNode, Name: name, Prefix: boolPrefixString(r.boolPrefix)}
+	case *ruleExprNode:
+		return &Node{Kind: RuleNode, Name: r.selector, Prefix: boolPrefixString(r.boolPrefix)}
+	case *paramExprNode:
+		return &Node{Kind: ParamNode, Name: r.name, Prefix: boolPrefixString(r.boolPrefix)}
+	case *groupExprNode:
+		return &Node{Kind: GroupNode, Prefix: boolPrefixString(r.boolPrefix), Args: []*Node{newNode(r.rightOperand)}}
+	case *selectorExprNode:
+		return &Node{Kind: SelectorNode, Name: r.field, Prefix: boolPrefixString(r.boolPrefix), Args: newArgNodes(r.subExprs)}
+	case *lenFnExprNode:
+		return &Node{Kind: FuncNode, Name: "len", Args: newArgNodes([]ExprNode{r.rightOperand})}
+	case *regexpFnExprNode:
+		return &Node{Kind: FuncNode, Name: "regexp", Args: []*Node{
+			{Kind: LiteralNode, Value: r.re.String()},
+			newNode(unwrapGroup(r.rightOperand)),
+		}}
+	case *sprintfFnExprNode:
+		return &Node{Kind: FuncNode, Name: "sprintf", Args: append(
+			[]*Node{{Kind: LiteralNode, Value: r.format}},
+			newArgNodes(r.args)...,
+		)}
+	case *funcExprNode:
+		return &Node{Kind: FuncNode, Name: r.name, Prefix: boolPrefixString(r.boolPrefix), Args: newArgNodes(r.args)}
+	case *pluginExprNode:
+		return &Node{Kind: FuncNode, Name: r.name, Prefix: boolPrefixString(r.boolPrefix), Args: newArgNodes(r.args)}
+	case *diveFnExprNode:
+		args := []ExprNode{r.collection}
+		if r.predicate != nil {
+			args = append(args, r.predicate)
+		}
+		return &Node{Kind: FuncNode, Name: r.name, Prefix: boolPrefixString(r.boolPrefix), Args: newArgNodes(args)}
+	case *existsFnExprNode:
+		return &Node{Kind: FuncNode, Name: r.name, Prefix: boolPrefixString(r.boolPrefix), Args: newArgNodes([]ExprNode{r.rightOperand})}
+	}
+	if op := operatorString(e); op != "" {
+		return &Node{Kind: OperatorNode, Op: op, Args: []*Node{newNode(e.LeftOperand()), newNode(e.RightOperand())}}
+	}
+	return &Node{Kind: FuncNode, Name: fmt.Sprintf("%T", e)}
+}
+
+// newArgNodes returns the nodes of the arguments, without the wrapping groups.
+func newArgNodes(args []ExprNode) []*Node {
+	nodes := make([]*Node, len(args))
+	for i, arg := range args {
+		nodes[i] = newNode(unwrapGroup(arg))
+	}
+	return nodes
+}
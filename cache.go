@@ -0,0 +1,342 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// ruleCacheVersion the version of the rule cache format, the caches of the other versions are rejected
+const ruleCacheVersion = 2
+
+// ruleCacheHeader the options that the cached syntax trees depend on
+type ruleCacheHeader struct {
+	Version     int
+	CostOrder   bool
+	ParseLimits ParseLimits
+}
+
+// cachedNode the serializable syntax tree node
+type cachedNode struct {
+	Kind        string // the node type, or the operator such as + and &&
+	Name        string // the field, function, rule, parameter or regexp pattern
+	Str         string // the string literal, the subscript name of the selector or the format of sprintf
+	Num         float64
+	Exact       string // the exact value of the number, such as 1/3
+	Bool        bool   // the bool literal, or whether the dive node is #k
+	Prefix      string // the bool prefix, "", "!" or "!!"
+	Safe        []bool
+	Left, Right *cachedNode
+	Args        []*cachedNode
+}
+
+// SaveRuleCache writes the parsed expressions of all the registered structs,
+// which are loaded by LoadRuleCache at the next start to skip the parsing.
+// NOTE:
+//  The expressions are keyed by their sources, the ones of the same source are saved once;
+//  The expressions that cannot be serialized are skipped, and parsed at the next start.
+func (vm *VM) SaveRuleCache(w io.Writer) error {
+	root := vm
+	if vm.parent != nil {
+		root = vm.parent
+	}
+	nodes := make(map[string]*cachedNode)
+	root.rw.RLock()
+	for _, s := range root.structJar {
+		for _, e := range s.exprList {
+			if _, ok := nodes[e.expr.src]; ok {
+				continue
+			}
+			if n, err := encodeNode(e.expr.expr); err == nil {
+				nodes[e.expr.src] = n
+			}
+		}
+	}
+	root.rw.RUnlock()
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(vm.ruleCacheHeader()); err != nil {
+		return err
+	}
+	return enc.Encode(nodes)
+}
+
+// LoadRuleCache reads the expressions written by SaveRuleCache, such as from an embedded file,
+// the expressions of the same sources are built from the cache instead of being parsed.
+// NOTE:
+//  The cache is rejected if it is saved by another version, or with the other SetCostOrder or SetParseLimits;
+//  The regexps are still compiled, the cached expression is parsed if its function is not registered;
+//  The caches loaded more than once are merged;
+//  It should be called before the vm is used.
+func (vm *VM) LoadRuleCache(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	var header ruleCacheHeader
+	if err := dec.Decode(&header); err != nil {
+		return err
+	}
+	if header != vm.ruleCacheHeader() {
+		return fmt.Errorf("rule cache mismatch: saved with %+v, want %+v", header, vm.ruleCacheHeader())
+	}
+	var nodes map[string]*cachedNode
+	if err := dec.Decode(&nodes); err != nil {
+		return err
+	}
+	if vm.ruleCache == nil {
+		vm.ruleCache = nodes
+		return nil
+	}
+	for src, n := range nodes {
+		vm.ruleCache[src] = n
+	}
+	return nil
+}
+
+func (vm *VM) ruleCacheHeader() ruleCacheHeader {
+	return ruleCacheHeader{
+		Version:     ruleCacheVersion,
+		CostOrder:   vm.costOrder,
+		ParseLimits: vm.getParseLimits(),
+	}
+}
+
+// loadCachedExpr builds the expression of the source from the rule cache, false if it is not cached.
+func (vm *VM) loadCachedExpr(src string) (*Expr, bool) {
+	if vm == nil {
+		return nil, false
+	}
+	if vm.parent != nil {
+		vm = vm.parent
+	}
+	vm.LoadRegistry()
+	if vm.ruleCache == nil {
+		return nil, false
+	}
+	n, ok := vm.ruleCache[src]
+	if !ok {
+		return nil, false
+	}
+	e, err := vm.decodeNode(n)
+	if err != nil {
+		return nil, false
+	}
+	return &Expr{expr: e, vm: vm, src: src}, true
+}
+
+func encodeNode(e ExprNode) (*cachedNode, error) {
+	if e == nil {
+		return nil, nil
+	}
+	var n *cachedNode
+	switch r := e.(type) {
+	case *groupExprNode:
+		n = &cachedNode{Kind: "group", Prefix: boolPrefixString(r.boolPrefix)}
+	case *boolExprNode:
+		n = &cachedNode{Kind: "bool", Bool: r.val}
+	case *stringExprNode:
+		n = &cachedNode{Kind: "string", Str: r.val}
+	case *digitalExprNode:
+		n = &cachedNode{Kind: "number", Num: r.val}
+		if r.exact != nil {
+			n.Exact = r.exact.RatString()
+		}
+	case *nilExprNode:
+		n = &cachedNode{Kind: "nil"}
+	case *diveExprNode:
+		n = &cachedNode{Kind: "dive", Bool: r.key, Prefix: boolPrefixString(r.boolPrefix)}
+	case *ruleExprNode:
+		n = &cachedNode{Kind: "rule", Name: r.selector, Prefix: boolPrefixString(r.boolPrefix)}
+	case *paramExprNode:
+		n = &cachedNode{Kind: "param", Name: r.name, Prefix: boolPrefixString(r.boolPrefix)}
+	case *selectorExprNode:
+		n = &cachedNode{Kind: "selector", Name: r.field, Str: r.name, Safe: r.safe, Prefix: boolPrefixString(r.boolPrefix)}
+		if err := encodeArgs(n, r.subExprs); err != nil {
+			return nil, err
+		}
+	case *lenFnExprNode:
+		n = &cachedNode{Kind: "len"}
+	case *regexpFnExprNode:
+		n = &cachedNode{Kind: "regexp", Name: r.re.String()}
+	case *sprintfFnExprNode:
+		n = &cachedNode{Kind: "sprintf", Str: r.format}
+		if err := encodeArgs(n, r.args); err != nil {
+			return nil, err
+		}
+	case *funcExprNode:
+		n = &cachedNode{Kind: "func", Name: r.name, Prefix: boolPrefixString(r.boolPrefix)}
+		if err := encodeArgs(n, r.args); err != nil {
+			return nil, err
+		}
+	case *diveFnExprNode:
+		n = &cachedNode{Kind: "dive_fn", Name: r.name, Prefix: boolPrefixString(r.boolPrefix)}
+		args := []ExprNode{r.collection}
+		if r.predicate != nil {
+			args = append(args, r.predicate)
+		}
+		if err := encodeArgs(n, args); err != nil {
+			return nil, err
+		}
+	case *existsFnExprNode:
+		n = &cachedNode{Kind: "exists", Name: r.name, Prefix: boolPrefixString(r.boolPrefix)}
+	default:
+		op := operatorString(e)
+		if op == "" {
+			return nil, fmt.Errorf("unsupported expression node: %T", e)
+		}
+		n = &cachedNode{Kind: op}
+	}
+	var err error
+	if n.Left, err = encodeNode(e.LeftOperand()); err != nil {
+		return nil, err
+	}
+	if n.Right, err = encodeNode(e.RightOperand()); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func encodeArgs(n *cachedNode, args []ExprNode) error {
+	n.Args = make([]*cachedNode, len(args))
+	for i, arg := range args {
+		var err error
+		if n.Args[i], err = encodeNode(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cachedOperators the constructors of the operator nodes
+var cachedOperators = map[string]func() ExprNode{
+	"+": newAdditionExprNode, "-": newSubtractionExprNode, "*": newMultiplicationExprNode,
+	"/": newDivisionExprNode, "%": newRemainderExprNode,
+	"==": newEqualExprNode, "!=": newNotEqualExprNode,
+	">": newGreaterExprNode, ">=": newGreaterEqualExprNode, "<": newLessExprNode, "<=": newLessEqualExprNode,
+	"&&": newAndExprNode, "||": newOrExprNode,
+}
+
+func (vm *VM) decodeNode(n *cachedNode) (ExprNode, error) {
+	if n == nil {
+		return nil, nil
+	}
+	args, err := vm.decodeArgs(n.Args)
+	if err != nil {
+		return nil, err
+	}
+	var e ExprNode
+	switch n.Kind {
+	case "group":
+		e = &groupExprNode{boolPrefix: parseBoolPrefix(n.Prefix)}
+	case "bool":
+		e = &boolExprNode{val: n.Bool}
+	case "string":
+		e = &stringExprNode{val: n.Str}
+	case "number":
+		de := &digitalExprNode{val: n.Num}
+		if n.Exact != "" {
+			if de.exact, _ = new(big.Rat).SetString(n.Exact); de.exact == nil {
+				return nil, fmt.Errorf("invalid exact number: %s", n.Exact)
+			}
+		}
+		e = de
+	case "nil":
+		e = &nilExprNode{}
+	case "dive":
+		e = &diveExprNode{key: n.Bool, boolPrefix: parseBoolPrefix(n.Prefix)}
+	case "rule":
+		e = &ruleExprNode{selector: n.Name, boolPrefix: parseBoolPrefix(n.Prefix)}
+	case "param":
+		e = &paramExprNode{name: n.Name, boolPrefix: parseBoolPrefix(n.Prefix)}
+	case "selector":
+		e = &selectorExprNode{field: n.Name, name: n.Str, subExprs: args, safe: n.Safe, boolPrefix: parseBoolPrefix(n.Prefix)}
+	case "len":
+		e = &lenFnExprNode{}
+	case "regexp":
+		re, err := vm.compileRegexp(n.Name)
+		if err != nil {
+			return nil, err
+		}
+		e = &regexpFnExprNode{re: re}
+	case "sprintf":
+		e = &sprintfFnExprNode{format: n.Str, args: args}
+	case "func":
+		fn, ok := funcList[n.Name]
+		if !ok {
+			return nil, fmt.Errorf("unregistered function: %s", n.Name)
+		}
+		e = &funcExprNode{name: n.Name, fn: fn, args: args, boolPrefix: parseBoolPrefix(n.Prefix)}
+	case "dive_fn":
+		de := &diveFnExprNode{name: n.Name, boolPrefix: parseBoolPrefix(n.Prefix)}
+		switch len(args) {
+		case 2:
+			de.predicate = args[1]
+			fallthrough
+		case 1:
+			de.collection = args[0]
+		default:
+			return nil, fmt.Errorf("invalid %s arguments", n.Name)
+		}
+		e = de
+	case "exists":
+		e = &existsFnExprNode{name: n.Name, boolPrefix: parseBoolPrefix(n.Prefix)}
+	default:
+		newOp, ok := cachedOperators[n.Kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown expression node: %s", n.Kind)
+		}
+		e = newOp()
+	}
+	left, err := vm.decodeNode(n.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := vm.decodeNode(n.Right)
+	if err != nil {
+		return nil, err
+	}
+	if left != nil {
+		left.SetParent(e)
+		e.SetLeftOperand(left)
+	}
+	if right != nil {
+		right.SetParent(e)
+		e.SetRightOperand(right)
+	}
+	return e, nil
+}
+
+// parseBoolPrefix parses the bool prefix of boolPrefixString.
+func parseBoolPrefix(s string) *bool {
+	if s == "" {
+		return nil
+	}
+	r := s == "!!"
+	return &r
+}
+
+func (vm *VM) decodeArgs(nodes []*cachedNode) ([]ExprNode, error) {
+	if nodes == nil {
+		return nil, nil
+	}
+	args := make([]ExprNode, len(nodes))
+	for i, n := range nodes {
+		var err error
+		if args[i], err = vm.decodeNode(n); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
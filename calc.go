@@ -0,0 +1,91 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"strings"
+)
+
+const calcExprName = "calc"
+
+// Calc evaluates the calc expressions, such as {calc:(First)$+' '+(Last)$},
+// and sets the values to their fields in the dependency order.
+// NOTE:
+//  The field that is referenced by another calc expression is computed first,
+//  the cyclic references are rejected when registering the struct;
+//  The values are set in the same way as Sanitize.
+func (t *TagExpr) Calc() error {
+	return t.assign(t.s.calcs, calcExprName)
+}
+
+// sortCalcs sorts the calc expressions in the dependency order,
+// the independent ones keep the registration order.
+func sortCalcs(calcs []*exprEntry) ([]*exprEntry, error) {
+	if len(calcs) < 2 {
+		return calcs, nil
+	}
+	byField := make(map[string]*exprEntry, len(calcs))
+	for _, e := range calcs {
+		byField[e.field] = e
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[*exprEntry]int, len(calcs))
+	sorted := make([]*exprEntry, 0, len(calcs))
+	var visit func(e *exprEntry, path []string) error
+	visit = func(e *exprEntry, path []string) error {
+		switch state[e] {
+		case done:
+			return nil
+		case visiting:
+			return newKindError(ErrSyntax, "cyclic calc expressions: %s", strings.Join(append(path, e.field), " -> "))
+		}
+		state[e] = visiting
+		for _, field := range referencedFields(e.expr.AST()) {
+			if dep, ok := byField[field]; ok && dep != e {
+				if err := visit(dep, append(path, e.field)); err != nil {
+					return err
+				}
+			}
+		}
+		state[e] = done
+		sorted = append(sorted, e)
+		return nil
+	}
+	for _, e := range calcs {
+		if err := visit(e, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// referencedFields returns the field names of the selectors in the syntax tree,
+// "" is the current field.
+func referencedFields(node *Node) []string {
+	if node == nil {
+		return nil
+	}
+	var fields []string
+	if node.Kind == SelectorNode {
+		fields = append(fields, node.Name)
+	}
+	for _, arg := range node.Args {
+		fields = append(fields, referencedFields(arg)...)
+	}
+	return fields
+}
@@ -0,0 +1,148 @@
+package cel
+
+import (
+	"reflect"
+	"testing"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+)
+
+func TestExport(t *testing.T) {
+	var cases = []struct {
+		v   interface{}
+		cel string
+	}{
+		{v: &struct {
+			A string `te:"$>0&&(B)$<=10*2"`
+			B int
+		}{}, cel: "(self.A > 0) && (self.B <= 20)"},
+		{v: &struct {
+			A string `te:"len($)>=3||$==''"`
+		}{}, cel: "(size(self.A) >= 3) || (self.A == '')"},
+		{v: &struct {
+			A string `te:"regexp('^\\w+$')"`
+		}{}, cel: "self.A.matches(r'^\\w+$')"},
+		{v: &struct {
+			A []string `te:"!($[0]=='a')"`
+		}{}, cel: "!(self.A[0] == 'a')"},
+		{v: &struct {
+			A []float64 `te:"any($,#v==1.5)&&(B)$!=nil"`
+			B *int
+		}{}, cel: "self.A.exists(v, v == 1.5) && (self.B != null)"},
+		{v: &struct {
+			A []int `te:"count($,#v>0)==2"`
+		}{}, cel: "self.A.filter(v, v > 0).size() == 2"},
+	}
+	for _, c := range cases {
+		got, err := Export(ruleOf(t, c.v), "A")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.cel {
+			t.Fatalf("%T: got: %q, want: %q", c.v, got, c.cel)
+		}
+	}
+	for _, bad := range []interface{}{
+		&struct {
+			A string `te:"sprintf('%v',$)"`
+		}{},
+		&struct {
+			A string `te:"iban($)"`
+		}{},
+		&struct {
+			A []int `te:"count($,#k>0)"`
+		}{},
+		&struct {
+			A bool `te:"!!$"`
+		}{},
+	} {
+		if _, err := Export(ruleOf(t, bad), "A"); err == nil {
+			t.Fatalf("%T: want error", bad)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	// the te tag of the field A is the expression imported from cel
+	var cases = []struct {
+		cel   string
+		v     interface{}
+		valid bool
+	}{
+		{cel: "self.A > 0 && self.B <= 20", v: &struct {
+			A int `te:"$>0&&(B)$<=20"`
+			B int
+		}{1, 20}, valid: true},
+		{cel: "size(self.A) >= 3 || self.A == ''", v: &struct {
+			A string `te:"len($)>=3||$==''"`
+		}{"ab"}, valid: false},
+		{cel: "self.A.size() == 2", v: &struct {
+			A string `te:"len($)==2"`
+		}{"ab"}, valid: true},
+		{cel: `self.A.matches(r'^\w+$')`, v: &struct {
+			A string `te:"regexp('^\\w+$',$)"`
+		}{"ab_1"}, valid: true},
+		{cel: `self.A.startsWith("a.") && !self.A.endsWith('z')`, v: &struct {
+			A string `te:"regexp('^a\\x2e',$)&&!(regexp('z$',$))"`
+		}{"a.b"}, valid: true},
+		{cel: "self.A[1] == 'y' && has(self.B)", v: &struct {
+			A []string `te:"$[1]=='y'&&(B)$!=nil"`
+			B int
+		}{[]string{"x", "y"}, 1}, valid: true},
+		{cel: "has(self.B)", v: &struct {
+			A int `te:"(B)$!=nil"`
+			B *int
+		}{}, valid: false},
+		// unlike CEL, the zero value is regarded as nil even if the pointer is set
+		{cel: "self.B != null", v: &struct {
+			A int `te:"(B)$!=nil"`
+			B *int
+		}{0, new(int)}, valid: false},
+		{cel: "(self.A - self.B) * 2 == -4", v: &struct {
+			A int `te:"($-(B)$)*2==-4"`
+			B int
+		}{1, 3}, valid: true},
+		{cel: "self.A - (self.B - 1) == 0", v: &struct {
+			A int `te:"$-((B)$-1)==0"`
+			B int
+		}{2, 3}, valid: true},
+		{cel: "self.B", v: &struct {
+			A int `te:"(B)$"`
+			B bool
+		}{0, true}, valid: true},
+	}
+	for _, c := range cases {
+		got, err := Import(c.cel, "A")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := reflect.TypeOf(c.v).Elem().Field(0).Tag.Get("te"); got != want {
+			t.Fatalf("cel: %q, got: %q, want: %q", c.cel, got, want)
+		}
+		te, err := tagexpr.New("te").Run(c.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := te.EvalBool("A@"); r != c.valid {
+			t.Fatalf("cel: %q, got: %v, want: %v", c.cel, r, c.valid)
+		}
+	}
+	for _, bad := range []string{"self", "self.A in [1, 2]", "self.A > 0 ? 1 : 2", "int(self.A) > 0", "self.A.matches(self.B)", "self.A == 'it\\'s'", "self.A["} {
+		if _, err := Import(bad, "A"); err == nil {
+			t.Fatalf("cel: %q, want error", bad)
+		}
+	}
+}
+
+// ruleOf returns the expression of the field A of the struct pointer v.
+func ruleOf(t *testing.T, v interface{}) *tagexpr.Expr {
+	te, err := tagexpr.New("te").Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, ok := te.Expr("A@")
+	if !ok {
+		t.Fatalf("%T: A@ not found", v)
+	}
+	return expr
+}
@@ -0,0 +1,191 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cel converts between the struct tag expressions and Google CEL,
+// so that the rules can be enforced in the CEL-speaking systems, such as Envoy and Kubernetes admission.
+// NOTE:
+//  The root object is self, such as self.A.B for the field selector (A.B)$;
+//  Only the common subset is supported, otherwise return error;
+//  The nil of tagexpr is the null of CEL, and (A)$!=nil is converted to and from has(self.A),
+//  but tagexpr dereferences the pointers and regards the zero number, string and bool as nil,
+//  so (A)$!=nil is false for the zero value even if the pointer field is set, while has(self.A) is true for the present field;
+//  Compare with the zero value instead, such as self.A != 0, if the presence of the zero value matters.
+package cel
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+)
+
+// RootName the name of the root object in CEL
+const RootName = "self"
+
+// Export converts the expression into CEL,
+// field is the selector of the field that the expression belongs to, such as A.B.
+// NOTE:
+//  The comparison with nil is exported as the one with null, see the package NOTE for the non-pointer fields.
+func Export(expr *tagexpr.Expr, field string) (string, error) {
+	return exportNode(expr.AST(), field)
+}
+
+func exportNode(n *tagexpr.Node, field string) (string, error) {
+	if n == nil {
+		return "", fmt.Errorf("empty expression")
+	}
+	var s string
+	var err error
+	switch n.Kind {
+	case tagexpr.LiteralNode:
+		s, err = exportLiteral(n.Value)
+	case tagexpr.SelectorNode:
+		s, err = exportSelector(n, field)
+	case tagexpr.GroupNode:
+		s, err = exportNode(n.Args[0], field)
+		s = "(" + s + ")"
+	case tagexpr.OperatorNode:
+		s, err = exportOperator(n, field)
+	case tagexpr.FuncNode:
+		s, err = exportFunc(n, field)
+	case tagexpr.DiveNode:
+		if n.Name != "#v" {
+			return "", fmt.Errorf("unsupported in CEL: %s", n.Name)
+		}
+		s = "v"
+	default:
+		return "", fmt.Errorf("unsupported node kind: %d", n.Kind)
+	}
+	if err != nil {
+		return "", err
+	}
+	switch n.Prefix {
+	case "!":
+		if n.Kind != tagexpr.GroupNode {
+			s = "(" + s + ")"
+		}
+		s = "!" + s
+	case "!!":
+		return "", fmt.Errorf("unsupported in CEL: !!")
+	}
+	return s, nil
+}
+
+func exportLiteral(v interface{}) (string, error) {
+	switch r := v.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(r), nil
+	case float64:
+		if r == math.Trunc(r) && math.Abs(r) < 1<<53 {
+			return strconv.FormatInt(int64(r), 10), nil
+		}
+		return strconv.FormatFloat(r, 'g', -1, 64), nil
+	case string:
+		return quote(r)
+	}
+	return "", fmt.Errorf("unsupported literal: %v", v)
+}
+
+// quote returns the CEL string literal, the backslashes are kept as they are by the raw string.
+func quote(s string) (string, error) {
+	switch {
+	case !strings.Contains(s, "'"):
+		if strings.Contains(s, `\`) {
+			return "r'" + s + "'", nil
+		}
+		return "'" + s + "'", nil
+	case !strings.Contains(s, `"`):
+		if strings.Contains(s, `\`) {
+			return `r"` + s + `"`, nil
+		}
+		return `"` + s + `"`, nil
+	}
+	return "", fmt.Errorf("unsupported string literal: %q", s)
+}
+
+func exportSelector(n *tagexpr.Node, field string) (string, error) {
+	name := n.Name
+	if name == "" {
+		name = field
+	}
+	s := RootName
+	if name != "" {
+		s += "." + name
+	}
+	for _, sub := range n.Args {
+		idx, err := exportNode(sub, field)
+		if err != nil {
+			return "", err
+		}
+		s += "[" + idx + "]"
+	}
+	return s, nil
+}
+
+func exportOperator(n *tagexpr.Node, field string) (string, error) {
+	var operands [2]string
+	for i, arg := range n.Args {
+		s, err := exportNode(arg, field)
+		if err != nil {
+			return "", err
+		}
+		// the precedences of the relations are the same in CEL
+		if arg.Kind == tagexpr.OperatorNode && !(arg.Op == n.Op && (n.Op == "&&" || n.Op == "||")) {
+			s = "(" + s + ")"
+		}
+		operands[i] = s
+	}
+	return operands[0] + " " + n.Op + " " + operands[1], nil
+}
+
+func exportFunc(n *tagexpr.Node, field string) (string, error) {
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		if n.Name == "regexp" && i == 0 {
+			continue
+		}
+		s, err := exportNode(arg, field)
+		if err != nil {
+			return "", err
+		}
+		args[i] = s
+	}
+	switch n.Name {
+	case "len":
+		return "size(" + args[0] + ")", nil
+	case "regexp":
+		pattern, err := quote(n.Args[0].Value.(string))
+		if err != nil {
+			return "", err
+		}
+		return args[1] + ".matches(" + pattern + ")", nil
+	case "count", "any", "all":
+		if len(args) != 2 {
+			return "", fmt.Errorf("unsupported in CEL: %s without predicate", n.Name)
+		}
+		switch n.Name {
+		case "count":
+			return args[0] + ".filter(v, " + args[1] + ").size()", nil
+		case "any":
+			return args[0] + ".exists(v, " + args[1] + ")", nil
+		default:
+			return args[0] + ".all(v, " + args[1] + ")", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported in CEL: %s()", n.Name)
+}
@@ -0,0 +1,445 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Import converts the CEL expression into the struct tag expression,
+// field is the selector of the field that the expression belongs to, such as A.B,
+// the value of which is written as $.
+// NOTE:
+//  has(self.A) and self.A!=null are imported as (A)$!=nil, which is false for the zero value of A,
+//  even if it is present, see the package NOTE.
+func Import(cel string, field string) (string, error) {
+	p := &parser{field: field}
+	if err := p.tokenize(cel); err != nil {
+		return "", err
+	}
+	v, err := p.parseBinary(0)
+	if err != nil {
+		return "", err
+	}
+	if p.pos < len(p.tokens) {
+		return "", fmt.Errorf("unexpected token: %q", p.tokens[p.pos].text)
+	}
+	if v, err = p.operand(v); err != nil {
+		return "", err
+	}
+	return v.s, nil
+}
+
+type tokenKind int
+
+const (
+	identToken tokenKind = iota
+	numberToken
+	stringToken
+	punctToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+type parser struct {
+	field  string
+	tokens []token
+	pos    int
+}
+
+var puncts = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", "[", "]", ".", ",", "?", ":"}
+
+func (p *parser) tokenize(s string) error {
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		c := s[0]
+		switch {
+		case c == '\'' || c == '"' || (c == 'r' || c == 'R') && len(s) > 1 && (s[1] == '\'' || s[1] == '"'):
+			raw := c == 'r' || c == 'R'
+			if raw {
+				s = s[1:]
+			}
+			quote := s[0]
+			i := 1
+			for i < len(s) && s[i] != quote {
+				if s[i] == '\\' && !raw {
+					i++
+				}
+				i++
+			}
+			if i >= len(s) {
+				return fmt.Errorf("unterminated string literal")
+			}
+			lit := s[1:i]
+			if !raw {
+				var err error
+				if lit, err = strconv.Unquote(`"` + strings.Replace(lit, `"`, `\"`, -1) + `"`); err != nil {
+					return fmt.Errorf("invalid string literal: %s", s[:i+1])
+				}
+			}
+			p.tokens = append(p.tokens, token{stringToken, lit})
+			s = s[i+1:]
+		case c >= '0' && c <= '9':
+			i := 0
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || s[i] == 'e' || s[i] == 'E' ||
+				(s[i] == '+' || s[i] == '-') && i > 0 && (s[i-1] == 'e' || s[i-1] == 'E')) {
+				i++
+			}
+			p.tokens = append(p.tokens, token{numberToken, s[:i]})
+			s = s[i:]
+			if s != "" && (s[0] == 'u' || s[0] == 'U') {
+				s = s[1:]
+			}
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			i := 0
+			for i < len(s) && (s[i] == '_' || s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z' || s[i] >= '0' && s[i] <= '9') {
+				i++
+			}
+			p.tokens = append(p.tokens, token{identToken, s[:i]})
+			s = s[i:]
+		default:
+			found := false
+			for _, punct := range puncts {
+				if strings.HasPrefix(s, punct) {
+					p.tokens = append(p.tokens, token{punctToken, punct})
+					s = s[len(punct):]
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("unexpected character: %q", c)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return token{kind: punctToken}
+}
+
+func (p *parser) accept(punct string) bool {
+	if t := p.peek(); t.kind == punctToken && t.text == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.accept(punct) {
+		return fmt.Errorf("expect %q, got %q", punct, p.peek().text)
+	}
+	return nil
+}
+
+// precedences of the binary operators, the same as the struct tag expression
+var precedences = map[string]int{
+	"||": 1, "&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+// value the converted operand, the path is set if it is a member of the root object
+type value struct {
+	path    []string // field names from the root
+	subs    []string // subscripts after the path
+	literal bool     // whether it is a string or number literal
+	str     *string  // the string literal
+	s       string   // the converted expression
+	prec    int      // the precedence of the top operator, 7 for the operand
+}
+
+func (p *parser) selector(v *value) (string, error) {
+	if v.path == nil {
+		return v.s, nil
+	}
+	if len(v.path) == 0 {
+		return "", fmt.Errorf("unsupported in tagexpr: the root object")
+	}
+	s := "$"
+	if name := strings.Join(v.path, "."); name != p.field {
+		s = "(" + name + ")$"
+	}
+	for _, sub := range v.subs {
+		s += "[" + sub + "]"
+	}
+	return s, nil
+}
+
+// operand returns the expression of the operand, resolves the selector.
+func (p *parser) operand(v *value) (*value, error) {
+	if v.path == nil {
+		return v, nil
+	}
+	s, err := p.selector(v)
+	if err != nil {
+		return nil, err
+	}
+	return &value{s: s, prec: 7}, nil
+}
+
+func (p *parser) parseBinary(minPrec int) (*value, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		prec, ok := precedences[t.text]
+		if t.kind != punctToken || !ok || prec <= minPrec {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseBinary(prec)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.operand(left); err != nil {
+			return nil, err
+		}
+		if right, err = p.operand(right); err != nil {
+			return nil, err
+		}
+		l, r := left.s, right.s
+		if left.prec < prec {
+			l = "(" + l + ")"
+		}
+		if right.prec <= prec {
+			r = "(" + r + ")"
+		}
+		left = &value{s: l + t.text + r, prec: prec}
+	}
+}
+
+func (p *parser) parseUnary() (*value, error) {
+	switch {
+	case p.accept("!"):
+		v, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if v, err = p.operand(v); err != nil {
+			return nil, err
+		}
+		return &value{s: "!(" + v.s + ")", prec: 7}, nil
+	case p.accept("-"):
+		v, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if v.literal && v.str == nil {
+			return &value{s: "-" + v.s, prec: 7, literal: true}, nil
+		}
+		if v, err = p.operand(v); err != nil {
+			return nil, err
+		}
+		return &value{s: "(0-" + v.s + ")", prec: 7}, nil
+	}
+	return p.parseMember()
+}
+
+func (p *parser) parseMember() (*value, error) {
+	v, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.peek()
+			if t.kind != identToken {
+				return nil, fmt.Errorf("expect identifier, got %q", t.text)
+			}
+			p.pos++
+			if p.accept("(") {
+				args, err := p.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+				if v, err = p.call(t.text, append([]*value{v}, args...)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if v.path == nil || len(v.subs) > 0 {
+				return nil, fmt.Errorf("unsupported in tagexpr: member %s of the non-root value", t.text)
+			}
+			v.path = append(v.path, t.text)
+		case p.accept("["):
+			idx, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect("]"); err != nil {
+				return nil, err
+			}
+			if v.path == nil {
+				return nil, fmt.Errorf("unsupported in tagexpr: index of the non-field value")
+			}
+			if idx, err = p.operand(idx); err != nil {
+				return nil, err
+			}
+			v.subs = append(v.subs, idx.s)
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (p *parser) parseArgs() ([]*value, error) {
+	var args []*value
+	if p.accept(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(")") {
+			return args, nil
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (*value, error) {
+	t := p.peek()
+	p.pos++
+	switch t.kind {
+	case numberToken:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", t.text)
+		}
+		return &value{s: strconv.FormatFloat(f, 'f', -1, 64), prec: 7, literal: true}, nil
+	case stringToken:
+		if err := checkLiteral(t.text); err != nil {
+			return nil, err
+		}
+		s := t.text
+		return &value{s: "'" + s + "'", prec: 7, literal: true, str: &s}, nil
+	case identToken:
+		switch t.text {
+		case "true", "false":
+			return &value{s: t.text, prec: 7}, nil
+		case "null":
+			return &value{s: "nil", prec: 7}, nil
+		case RootName:
+			return &value{path: []string{}}, nil
+		}
+		if p.accept("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return p.call(t.text, args)
+		}
+		return nil, fmt.Errorf("unsupported in tagexpr: identifier %s", t.text)
+	case punctToken:
+		if t.text == "(" {
+			v, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			if v, err = p.operand(v); err != nil {
+				return nil, err
+			}
+			return &value{s: "(" + v.s + ")", prec: 7}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected token: %q", t.text)
+}
+
+// call converts the function call, the receiver of the method is args[0].
+func (p *parser) call(name string, args []*value) (*value, error) {
+	if name == "has" {
+		if len(args) != 1 || args[0].path == nil {
+			return nil, fmt.Errorf("has() requires a field")
+		}
+		s, err := p.selector(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &value{s: s + "!=nil", prec: 3}, nil
+	}
+	for i, arg := range args {
+		var err error
+		if args[i], err = p.operand(arg); err != nil {
+			return nil, err
+		}
+	}
+	switch name {
+	case "size":
+		if len(args) == 1 {
+			return &value{s: "len(" + args[0].s + ")", prec: 7}, nil
+		}
+	case "matches", "startsWith", "endsWith", "contains":
+		if len(args) != 2 || args[1].str == nil {
+			return nil, fmt.Errorf("%s() requires a string literal argument", name)
+		}
+		pattern := *args[1].str
+		switch name {
+		case "startsWith":
+			pattern = "^" + regexpQuote(pattern)
+		case "endsWith":
+			pattern = regexpQuote(pattern) + "$"
+		case "contains":
+			pattern = regexpQuote(pattern)
+		}
+		if err := checkLiteral(pattern); err != nil {
+			return nil, err
+		}
+		return &value{s: "regexp('" + pattern + "'," + args[0].s + ")", prec: 7}, nil
+	}
+	return nil, fmt.Errorf("unsupported in tagexpr: %s()", name)
+}
+
+// checkLiteral checks whether s can be written as the string literal of the expression.
+func checkLiteral(s string) error {
+	if strings.Contains(s, "'") || strings.Count(s, "(") != strings.Count(s, ")") {
+		return fmt.Errorf("unsupported string literal in tagexpr: %q", s)
+	}
+	return nil
+}
+
+func regexpQuote(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`\.+*?()|[]{}^$`, r) {
+			fmt.Fprintf(&b, `\x%02x`, r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
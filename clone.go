@@ -0,0 +1,62 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+// Clone returns a vm that shares the registered structs and their parsed expressions with vm,
+// whose runtime options are copied from vm and then overridden by opts, such as:
+//  strict := vm.Clone(func(c *tagexpr.VM) { c.SetUnreachable(true).SetParams(tagexpr.Params{"plan": "pro"}) })
+// NOTE:
+//  The runtime options are the ones of SetUnreachable, SetParams, SetFlagProvider, SetRecover, SetCoverage,
+//  SetPasswordPolicy, SetMarkupPolicy and SetJWTAlgs;
+//  The registration options, such as SetCostOrder, SetCompatTag and SetParseLimits, are the ones of the first vm,
+//  setting them on the clone has no effect on the shared structs;
+//  The regexp cache is shared, while the enums are copied, RegisterEnum on the clone does not change vm.
+func (vm *VM) Clone(opts ...func(*VM)) *VM {
+	root := vm
+	if vm.parent != nil {
+		root = vm.parent
+	}
+	c := &VM{
+		tagName:         vm.tagName,
+		passwordPolicy:  vm.passwordPolicy,
+		markupPolicy:    vm.markupPolicy,
+		regexps:         vm.regexps,
+		costOrder:       vm.costOrder,
+		selectorFold:    vm.selectorFold,
+		compatTagName:   vm.compatTagName,
+		coverage:        vm.coverage,
+		parseLimits:     vm.parseLimits,
+		registerLimits:  vm.registerLimits,
+		unsupported:     vm.unsupported,
+		unsupportedWarn: vm.unsupportedWarn,
+		noRecover:       vm.noRecover,
+		unreachable:     vm.unreachable,
+		params:          vm.params,
+		flagProvider:    vm.flagProvider,
+		jwtAlgs:         vm.jwtAlgs,
+		parent:          root,
+	}
+	if vm.enums != nil {
+		// the sets are replaced but never changed by RegisterEnum, so they are shared
+		c.enums = make(map[string]map[interface{}]bool, len(vm.enums))
+		for name, set := range vm.enums {
+			c.enums[name] = set
+		}
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
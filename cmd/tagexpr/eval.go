@@ -0,0 +1,136 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+func evalCmd(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	tagName := flags.String("tag", defaultTagName, "the tag name of the expressions")
+	typeName := flags.String("type", "", "the struct type, such as github.com/user/project/model.User")
+	jsonFile := flags.String("json", "", "the JSON file of the sample data")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *typeName == "" || *jsonFile == "" {
+		fmt.Fprint(stderr, "-type and -json are required\n", usage)
+		return 2
+	}
+	src, err := evalSource(*typeName)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	jsonPath, err := filepath.Abs(*jsonFile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	// The program is generated in the current directory, so that the package of the type
+	// is resolved by the go.mod or GOPATH of the current project.
+	dir, err := ioutil.TempDir(".", ".tagexpr-eval")
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "main.go"), src, 0644); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	cmd := exec.Command("go", append([]string{"run", "./" + filepath.Base(dir), *tagName, jsonPath}, flags.Args()...)...)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err = cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return 1
+		}
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// evalSource returns the source of the program that evaluates the expressions of the type.
+func evalSource(typeName string) ([]byte, error) {
+	i := strings.LastIndexByte(typeName, '.')
+	if i <= 0 || i == len(typeName)-1 || strings.LastIndexByte(typeName, '/') > i {
+		return nil, fmt.Errorf("invalid type %q, expect pkg.T", typeName)
+	}
+	var buf strings.Builder
+	err := evalTemplate.Execute(&buf, map[string]string{
+		"Package": typeName[:i],
+		"Type":    typeName[i+1:],
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(buf.String()), nil
+}
+
+var evalTemplate = template.Must(template.New("eval").Parse(`// Code generated by tagexpr eval. DO NOT EDIT.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+	target "{{.Package}}"
+)
+
+func main() {
+	tagName, jsonFile, selectors := os.Args[1], os.Args[2], os.Args[3:]
+	data, err := ioutil.ReadFile(jsonFile)
+	if err != nil {
+		fatal(err)
+	}
+	v := new(target.{{.Type}})
+	if err = json.Unmarshal(data, v); err != nil {
+		fatal(err)
+	}
+	te, err := tagexpr.New(tagName).Run(v)
+	if err != nil {
+		fatal(err)
+	}
+	if len(selectors) == 0 {
+		te.Range(func(selector string, eval func() interface{}) bool {
+			selectors = append(selectors, selector)
+			return true
+		})
+	}
+	for _, selector := range selectors {
+		fmt.Printf("%s: %#v\n", selector, te.Eval(selector))
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+`))
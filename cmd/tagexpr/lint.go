@@ -0,0 +1,156 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+)
+
+func lintCmd(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	tagName := flags.String("tag", defaultTagName, "the tag name of the expressions")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	files, err := expandPatterns(patterns)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	l := &linter{vm: tagexpr.New(*tagName), tagName: *tagName}
+	var problems int
+	for _, file := range files {
+		n, err := l.lintFile(file, stdout)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		problems += n
+	}
+	if problems > 0 {
+		return 1
+	}
+	return 0
+}
+
+// expandPatterns returns the go files of the packages,
+// the pattern dir/... matches the dir and all its subdirectories.
+func expandPatterns(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, ".go") {
+			files = append(files, pattern)
+			continue
+		}
+		dir, recursive := pattern, false
+		if strings.HasSuffix(pattern, "...") {
+			dir, recursive = filepath.Clean(strings.TrimSuffix(pattern, "...")), true
+		}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path == dir {
+					return nil
+				}
+				name := info.Name()
+				if !recursive || name == "vendor" || name == "testdata" ||
+					strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(path, ".go") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+type linter struct {
+	vm      *tagexpr.VM
+	tagName string
+}
+
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// lintFile prints the problems of the file and returns the number of them.
+func (l *linter) lintFile(filename string, w io.Writer) (int, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	var problems int
+	ast.Inspect(file, func(n ast.Node) bool {
+		st, ok := n.(*ast.StructType)
+		if !ok {
+			return true
+		}
+		for _, field := range st.Fields.List {
+			if field.Tag == nil {
+				continue
+			}
+			raw, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				continue
+			}
+			tag, ok := reflect.StructTag(raw).Lookup(l.tagName)
+			if !ok {
+				continue
+			}
+			if err = l.check(tag); err != nil {
+				fmt.Fprintf(w, "%s: %s\n", fset.Position(field.Tag.Pos()), err.Error())
+				problems++
+			}
+		}
+		return true
+	})
+	return problems, nil
+}
+
+// check parses the tag value by a struct type that has the only field with the tag.
+func (l *linter) check(tag string) error {
+	t := reflect.StructOf([]reflect.StructField{{
+		Name: "X",
+		Type: interfaceType,
+		Tag:  reflect.StructTag(l.tagName + ":" + strconv.Quote(tag)),
+	}})
+	_, err := l.vm.Struct(t)
+	return err
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command tagexpr lints and evaluates the struct tag expressions.
+//
+// Usage:
+//
+//	tagexpr lint [-tag name] [packages]
+//	tagexpr eval [-tag name] -type pkg.T -json data.json [selectors]
+//	tagexpr repl [-json data.json]
+//
+// The lint command parses the tag expressions of all the struct fields in the packages,
+// such as ./..., and reports the syntax errors.
+// The eval command decodes the JSON data into the struct type and prints the results of the selectors,
+// or all the selectors if none is given.
+// The repl command evaluates the expressions read from the standard input against the JSON object,
+// type :help for the commands.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const defaultTagName = "tagexpr"
+
+const usage = `usage:
+	tagexpr lint [-tag name] [packages]
+	tagexpr eval [-tag name] -type pkg.T -json data.json [selectors]
+	tagexpr repl [-json data.json]
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "lint":
+		return lintCmd(args[1:], stdout, stderr)
+	case "eval":
+		return evalCmd(args[1:], stdout, stderr)
+	case "repl":
+		return replCmd(args[1:], stdin, stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+}
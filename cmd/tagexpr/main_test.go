@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tagexpr-lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a.go":     "package a\n\ntype T struct {\n\tA int `tagexpr:\"$>0\"`\n\tB int `tagexpr:\"len($\"`\n}\n",
+		"sub/b.go": "package sub\n\ntype T struct {\n\tA int `vd:\"$>>\"`\n}\n",
+	}
+	for name, src := range files {
+		name = filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if err = ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		args     []string
+		code     int
+		problems []string
+	}{
+		{[]string{"lint", dir}, 1, []string{"a.go:5:8: \"len($\" (syntax incorrect)"}},
+		{[]string{"lint", "-tag", "vd", dir + "/..."}, 1, []string{"b.go:4:8: \"$>>\" (syntax incorrect)"}},
+		{[]string{"lint", "-tag", "vd", dir}, 0, nil},
+		{[]string{"lint", filepath.Join(dir, "missing")}, 2, nil},
+	}
+	for _, c := range cases {
+		var stdout, stderr bytes.Buffer
+		code := run(c.args, nil, &stdout, &stderr)
+		if code != c.code {
+			t.Fatalf("%v: code: got %d, want %d, stderr: %s", c.args, code, c.code, stderr.String())
+		}
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		if len(c.problems) == 0 && stdout.Len() > 0 || len(c.problems) > 0 && len(lines) != len(c.problems) {
+			t.Fatalf("%v: got %q", c.args, stdout.String())
+		}
+		for i, p := range c.problems {
+			if !strings.Contains(lines[i], p) {
+				t.Fatalf("%v: got %q, want %q", c.args, lines[i], p)
+			}
+		}
+	}
+}
+
+func TestEvalSource(t *testing.T) {
+	src, err := evalSource("github.com/user/project/model.User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`target "github.com/user/project/model"`, "new(target.User)"} {
+		if !bytes.Contains(src, []byte(s)) {
+			t.Fatalf("missing %q in:\n%s", s, src)
+		}
+	}
+	for _, typeName := range []string{"User", "model.", "github.com/user/project.v2/model"} {
+		if _, err = evalSource(typeName); err == nil {
+			t.Fatalf("%s: error expected", typeName)
+		}
+	}
+}
+
+func TestRepl(t *testing.T) {
+	f, err := ioutil.TempFile("", "tagexpr-repl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"name":"Alice"}`)
+	f.Close()
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"repl", "-json", f.Name()}, strings.NewReader("len((Name)$)\n"), &stdout, &stderr)
+	if code != 0 || stdout.String() != "> 5\n> " {
+		t.Fatalf("code: %d, stdout: %q, stderr: %q", code, stdout.String(), stderr.String())
+	}
+}
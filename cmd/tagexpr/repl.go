@@ -0,0 +1,49 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/bytedance/go-tagexpr/repl"
+)
+
+func replCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	jsonFile := flags.String("json", "", "the JSON file to load")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	s := repl.New()
+	if *jsonFile != "" {
+		data, err := ioutil.ReadFile(*jsonFile)
+		if err == nil {
+			err = s.LoadJSON(data)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+	if err := s.Run(stdin, stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
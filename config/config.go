@@ -0,0 +1,128 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config loads the configuration by Viper, koanf or the like into a struct,
+// and validates it by the struct tag expressions with the config path in the error messages,
+// such as "server.port must be between 1 and 65535".
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+	"github.com/bytedance/go-tagexpr/validator"
+)
+
+// DefaultKeyTag the key tag of Viper, which decodes by mapstructure
+const DefaultKeyTag = "mapstructure"
+
+// UnmarshalFunc unmarshals the configuration into the struct pointer, such as:
+//  func(v interface{}) error { return viper.Unmarshal(v) }
+//  func(v interface{}) error { return k.Unmarshal("", v) } // koanf
+type UnmarshalFunc func(structPtr interface{}) error
+
+// Loader configuration loader
+type Loader struct {
+	vd     *validator.Validator
+	keyTag string
+}
+
+// New creates a configuration loader that validates by vd.
+func New(vd *validator.Validator) *Loader {
+	return &Loader{vd: vd, keyTag: DefaultKeyTag}
+}
+
+// SetKeyTag sets the tag name of the config keys, such as koanf, the default is mapstructure.
+func (l *Loader) SetKeyTag(keyTag string) *Loader {
+	l.keyTag = keyTag
+	return l
+}
+
+// Load unmarshals the configuration into structPtr by unmarshal, then validates it.
+func (l *Loader) Load(unmarshal UnmarshalFunc, structPtr interface{}) error {
+	if err := unmarshal(structPtr); err != nil {
+		return err
+	}
+	return l.Validate(structPtr)
+}
+
+// Validate validates structPtr, the *validator.Error is converted to *Error with the config path.
+func (l *Loader) Validate(structPtr interface{}) error {
+	err := l.vd.Validate(structPtr)
+	var verr *validator.Error
+	if !errors.As(err, &verr) {
+		return err
+	}
+	msg := verr.Msg
+	if msg == "" {
+		msg = "is invalid"
+	}
+	return &Error{
+		Path: l.Path(reflect.TypeOf(structPtr), verr.FieldSelector),
+		Msg:  msg,
+		Err:  err,
+	}
+}
+
+// Path returns the config path of the field selector, such as Server.Port to server.port.
+// NOTE:
+//  The key is the name of the key tag, or the lowercase field name if it has no key tag;
+//  The embedded struct with the squash option, such as `mapstructure:",squash"`, has no key;
+//  The names after a non-struct field, such as a map or a slice, are lowercased as they are.
+func (l *Loader) Path(structType reflect.Type, fieldSelector string) string {
+	var keys []string
+	t := structType
+	for _, name := range strings.Split(fieldSelector, ".") {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			keys = append(keys, strings.ToLower(name))
+			continue
+		}
+		f, ok := t.FieldByName(name)
+		if !ok {
+			keys = append(keys, strings.ToLower(name))
+			continue
+		}
+		key := tagexpr.KeyFieldName(f, l.keyTag)
+		if key == f.Name {
+			key = strings.ToLower(key)
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+		t = f.Type
+	}
+	return strings.Join(keys, ".")
+}
+
+// Error the configuration validation error
+type Error struct {
+	Path string // config path of the field, such as server.port
+	Msg  string
+	Err  error // the validation error
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	return e.Path + " " + e.Msg
+}
+
+// Unwrap returns the validation error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,57 @@
+package config_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+	"github.com/bytedance/go-tagexpr/config"
+	"github.com/bytedance/go-tagexpr/validator"
+)
+
+func Example() {
+	type Server struct {
+		Host string `mapstructure:"host" vd:"$!=''"`
+		Port int    `mapstructure:"port" vd:"{@:$>=1&&$<=65535}{msg:'must be between 1 and 65535'}"`
+	}
+	type Config struct {
+		Server  Server `mapstructure:"server"`
+		Workers int    `vd:"$>0"`
+	}
+	// It is viper.Unmarshal in practice, here the config is decoded from JSON.
+	unmarshal := func(data string) config.UnmarshalFunc {
+		return func(v interface{}) error { return json.Unmarshal([]byte(data), v) }
+	}
+	loader := config.New(validator.New("vd"))
+
+	var c Config
+	err := loader.Load(unmarshal(`{"Server":{"Host":"localhost","Port":70000},"Workers":4}`), &c)
+	fmt.Println(err)
+	fmt.Println(errors.Is(err, tagexpr.ErrValidation))
+
+	err = loader.Load(unmarshal(`{"Server":{"Host":"localhost","Port":8080}}`), new(Config))
+	fmt.Println(err)
+
+	type Backend struct {
+		Addr string `mapstructure:"addr"`
+	}
+	type Upstream struct {
+		Backends []Backend           `mapstructure:"backends"`
+		Weights  map[string]*Backend `mapstructure:"weights"`
+		Primary  *Backend            `mapstructure:"primary"`
+	}
+	typ := reflect.TypeOf(&Upstream{})
+	fmt.Println(loader.Path(typ, "Backends.Addr"))
+	fmt.Println(loader.Path(typ, "Weights.Addr"))
+	fmt.Println(loader.Path(typ, "Primary.Addr"))
+
+	// Output:
+	// server.port must be between 1 and 65535
+	// true
+	// workers is invalid
+	// backends.addr
+	// weights.addr
+	// primary.addr
+}
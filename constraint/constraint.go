@@ -0,0 +1,332 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package constraint extracts the declarative constraints, such as the length, range, pattern and enum,
+// from the struct tag expressions, for generating the schemas of the other systems.
+package constraint
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+)
+
+// Constraints the declarative constraints of the field value
+type Constraints struct {
+	Required         bool
+	MinLength        *int
+	MaxLength        *int
+	Minimum          *float64
+	Maximum          *float64
+	ExclusiveMinimum bool
+	ExclusiveMaximum bool
+	Pattern          string
+	Enum             []interface{}
+	// Partial is true if some conditions of the expression are not translated
+	Partial bool
+}
+
+// Field the struct field and its constraints
+type Field struct {
+	reflect.StructField
+	// JSONName the json name of the field, the go field name if it has no json tag
+	JSONName string
+	// OmitEmpty whether the json tag has omitempty option
+	OmitEmpty bool
+	Constraints
+	// Elem the schema of the struct, or the element struct of the slice, array and map,
+	// the pointers are dereferenced
+	Elem *Schema
+}
+
+// Schema the constraints of the struct type
+type Schema struct {
+	Type   reflect.Type
+	Fields []*Field
+}
+
+// Extractor constraint extractor
+type Extractor struct {
+	tagName string
+	vm      *tagexpr.VM
+}
+
+// New creates a constraint extractor that uses @tagName as the tag name.
+func New(tagName string) *Extractor {
+	return &Extractor{
+		tagName: tagName,
+		vm:      tagexpr.New(tagName),
+	}
+}
+
+// ExtractTag returns the constraints of the tag value of the field whose type is fieldType,
+// it is used when only the source of the struct is available.
+func (e *Extractor) ExtractTag(tag string, fieldType reflect.Type) (Constraints, error) {
+	t := reflect.StructOf([]reflect.StructField{{
+		Name: "X",
+		Type: fieldType,
+		Tag:  reflect.StructTag(e.tagName + ":" + strconv.Quote(tag)),
+	}})
+	s, err := e.vm.Struct(t)
+	if err != nil {
+		return Constraints{}, err
+	}
+	var c Constraints
+	s.Range(func(selector string, expr *tagexpr.Expr) bool {
+		if selector == "X@" {
+			c = extract(expr.AST(), "X")
+			return false
+		}
+		return true
+	})
+	return c, nil
+}
+
+// Extract returns the schema of the struct.
+func (e *Extractor) Extract(structOrStructPtr interface{}) (*Schema, error) {
+	if structOrStructPtr == nil {
+		return nil, errors.New("cannot extract nil interface")
+	}
+	return e.extract(reflect.TypeOf(structOrStructPtr), map[reflect.Type]*Schema{})
+}
+
+func (e *Extractor) extract(t reflect.Type, visited map[reflect.Type]*Schema) (*Schema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if schema, ok := visited[t]; ok {
+		return schema, nil
+	}
+	s, err := e.vm.Struct(t)
+	if err != nil {
+		return nil, err
+	}
+	schema := &Schema{Type: t}
+	visited[t] = schema
+	exprs := make(map[string]*tagexpr.Expr)
+	s.Range(func(selector string, expr *tagexpr.Expr) bool {
+		exprs[selector] = expr
+		return true
+	})
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := tagexpr.ParseJSONTag(sf)
+		if tag.Ignored {
+			continue
+		}
+		if tag.Inline {
+			inline, err := e.extract(structElem(sf.Type), visited)
+			if err != nil {
+				return nil, err
+			}
+			schema.Fields = append(schema.Fields, inline.Fields...)
+			continue
+		}
+		f := &Field{StructField: sf, JSONName: tag.Name, OmitEmpty: tag.OmitEmpty}
+		if expr, ok := exprs[sf.Name+"@"]; ok {
+			f.Constraints = extract(expr.AST(), sf.Name)
+		}
+		if elem := structElem(sf.Type); elem != nil {
+			if f.Elem, err = e.extract(elem, visited); err != nil {
+				return nil, err
+			}
+		}
+		schema.Fields = append(schema.Fields, f)
+	}
+	return schema, nil
+}
+
+// structElem returns the struct type of the field or its element, nil if it is not a struct.
+func structElem(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			return t
+		default:
+			return nil
+		}
+	}
+}
+
+// extract extracts the constraints from the conditions of the && chain.
+func extract(n *tagexpr.Node, field string) Constraints {
+	var c Constraints
+	var conds []*tagexpr.Node
+	flattenAnd(n, &conds)
+	for _, cond := range conds {
+		if !c.add(cond, field) {
+			c.Partial = true
+		}
+	}
+	return c
+}
+
+func flattenAnd(n *tagexpr.Node, conds *[]*tagexpr.Node) {
+	switch {
+	case n == nil:
+	case n.Kind == tagexpr.OperatorNode && n.Op == "&&":
+		flattenAnd(n.Args[0], conds)
+		flattenAnd(n.Args[1], conds)
+	case n.Kind == tagexpr.GroupNode && n.Prefix == "":
+		flattenAnd(n.Args[0], conds)
+	default:
+		*conds = append(*conds, n)
+	}
+}
+
+// isCurrent reports whether n is the value of the current field.
+func isCurrent(n *tagexpr.Node, field string) bool {
+	return n.Kind == tagexpr.SelectorNode && n.Prefix == "" && len(n.Args) == 0 &&
+		(n.Name == "" || n.Name == field)
+}
+
+// isLength reports whether n is the length of the current field.
+func isLength(n *tagexpr.Node, field string) bool {
+	return n.Kind == tagexpr.FuncNode && n.Name == "len" && len(n.Args) == 1 && isCurrent(n.Args[0], field)
+}
+
+// mirrors the comparison operators when the operands are swapped
+var mirrors = map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<=", "==": "==", "!=": "!="}
+
+// add adds the constraint of the condition, returns false if it is not translated.
+func (c *Constraints) add(n *tagexpr.Node, field string) bool {
+	switch n.Kind {
+	case tagexpr.FuncNode:
+		if n.Name == "regexp" && n.Prefix == "" && isCurrent(n.Args[1], field) && c.Pattern == "" {
+			c.Pattern = n.Args[0].Value.(string)
+			return true
+		}
+		return false
+	case tagexpr.OperatorNode:
+		if n.Op == "||" {
+			return c.addEnum(n, field)
+		}
+	case tagexpr.GroupNode:
+		// !($=='') is the same as $!=''
+		if inner := n.Args[0]; n.Prefix == "!" && inner.Kind == tagexpr.OperatorNode && inner.Op == "==" {
+			return c.add(&tagexpr.Node{Kind: tagexpr.OperatorNode, Op: "!=", Args: inner.Args}, field)
+		}
+		return false
+	default:
+		return false
+	}
+	left, op, right := n.Args[0], n.Op, n.Args[1]
+	if left.Kind == tagexpr.LiteralNode {
+		left, op, right = right, mirrors[op], left
+	}
+	if op == "" || right.Kind != tagexpr.LiteralNode {
+		return false
+	}
+	switch {
+	case isLength(left, field):
+		f, ok := right.Value.(float64)
+		if !ok {
+			return false
+		}
+		return c.addLength(op, int(f))
+	case isCurrent(left, field):
+		switch v := right.Value.(type) {
+		case nil:
+			if op == "!=" {
+				c.Required = true
+				return true
+			}
+		case string:
+			switch op {
+			case "!=":
+				if v == "" {
+					c.Required = true
+					return true
+				}
+			case "==":
+				c.Enum = append(c.Enum, v)
+				return true
+			}
+		case float64:
+			return c.addRange(op, v)
+		}
+	}
+	return false
+}
+
+func (c *Constraints) addLength(op string, n int) bool {
+	switch op {
+	case ">":
+		n++
+		fallthrough
+	case ">=":
+		c.MinLength = &n
+		if n > 0 {
+			c.Required = true
+		}
+	case "<":
+		n--
+		fallthrough
+	case "<=":
+		c.MaxLength = &n
+	case "==":
+		min, max := n, n
+		c.MinLength, c.MaxLength = &min, &max
+	default:
+		return false
+	}
+	return true
+}
+
+func (c *Constraints) addRange(op string, f float64) bool {
+	switch op {
+	case ">", ">=":
+		c.Minimum, c.ExclusiveMinimum = &f, op == ">"
+	case "<", "<=":
+		c.Maximum, c.ExclusiveMaximum = &f, op == "<"
+	case "==":
+		c.Enum = append(c.Enum, f)
+	default:
+		return false
+	}
+	return true
+}
+
+// addEnum adds the enum of the || chain, such as $=='a'||$=='b'.
+func (c *Constraints) addEnum(n *tagexpr.Node, field string) bool {
+	var values []interface{}
+	var walk func(n *tagexpr.Node) bool
+	walk = func(n *tagexpr.Node) bool {
+		switch {
+		case n.Kind == tagexpr.OperatorNode && n.Op == "||":
+			return walk(n.Args[0]) && walk(n.Args[1])
+		case n.Kind == tagexpr.GroupNode && n.Prefix == "":
+			return walk(n.Args[0])
+		case n.Kind == tagexpr.OperatorNode && n.Op == "==":
+			left, right := n.Args[0], n.Args[1]
+			if left.Kind == tagexpr.LiteralNode {
+				left, right = right, left
+			}
+			if isCurrent(left, field) && right.Kind == tagexpr.LiteralNode && right.Value != nil {
+				values = append(values, right.Value)
+				return true
+			}
+		}
+		return false
+	}
+	if !walk(n) {
+		return false
+	}
+	c.Enum = append(c.Enum, values...)
+	return true
+}
@@ -0,0 +1,101 @@
+package constraint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	type Inner struct {
+		V int `vd:"$>0"`
+	}
+	type T struct {
+		A string   `vd:"len($)>=3&&len($)<=8&&regexp('^[a-z]+$')"`
+		B int      `json:"b,omitempty" vd:"10>$&&$>=-1"`
+		C string   `vd:"($=='x'||$=='y')"`
+		D *Inner   `vd:"$!=nil&&!!$"`
+		E []string `vd:"len($)==2"`
+		F float64  `vd:"$>0.5||$<-0.5"`
+		g int
+		H int `json:"-"`
+	}
+	schema, err := New("vd").Extract(new(T))
+	if err != nil {
+		t.Fatal(err)
+	}
+	intp := func(i int) *int { return &i }
+	f64p := func(f float64) *float64 { return &f }
+	cases := []struct {
+		name string
+		c    Constraints
+	}{
+		{"A", Constraints{Required: true, MinLength: intp(3), MaxLength: intp(8), Pattern: "^[a-z]+$"}},
+		{"b", Constraints{Minimum: f64p(-1), Maximum: f64p(10), ExclusiveMaximum: true}},
+		{"C", Constraints{Enum: []interface{}{"x", "y"}}},
+		{"D", Constraints{Required: true, Partial: true}},
+		{"E", Constraints{MinLength: intp(2), MaxLength: intp(2)}},
+		{"F", Constraints{Partial: true}},
+	}
+	if len(schema.Fields) != len(cases) {
+		t.Fatalf("fields: got %d, want %d", len(schema.Fields), len(cases))
+	}
+	for i, c := range cases {
+		f := schema.Fields[i]
+		if f.JSONName != c.name {
+			t.Fatalf("%d: name: got %q, want %q", i, f.JSONName, c.name)
+		}
+		if !reflect.DeepEqual(f.Constraints, c.c) {
+			t.Fatalf("%s: got %+v, want %+v", c.name, f.Constraints, c.c)
+		}
+	}
+	if d := schema.Fields[3]; d.Elem == nil || d.Elem.Fields[0].Minimum == nil || !d.Elem.Fields[0].ExclusiveMinimum {
+		t.Fatalf("D.Elem: got %+v", d.Elem)
+	}
+	if !schema.Fields[1].OmitEmpty {
+		t.Fatal("b: omitempty expected")
+	}
+}
+
+func TestExtractTag(t *testing.T) {
+	e := New("vd")
+	c, err := e.ExtractTag("{@:!($=='')&&len($)<=32}{msg:'required'}", reflect.TypeOf(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	max := 32
+	if want := (Constraints{Required: true, MaxLength: &max}); !reflect.DeepEqual(c, want) {
+		t.Fatalf("got %+v, want %+v", c, want)
+	}
+	if _, err = e.ExtractTag("len($", reflect.TypeOf(0)); err == nil {
+		t.Fatal("syntax error expected")
+	}
+}
+
+func TestExtractInline(t *testing.T) {
+	type Base struct {
+		ID int `json:"id" vd:"$>0"`
+	}
+	type Meta struct {
+		Tag string `json:"tag" vd:"len($)<=3"`
+	}
+	type T struct {
+		Base
+		*Meta `json:",inline"`
+		Named Base   `json:"named"`
+		Name  string `json:"name,string"`
+	}
+	schema, err := New("vd").Extract(new(T))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range schema.Fields {
+		names = append(names, f.JSONName)
+	}
+	if !reflect.DeepEqual(names, []string{"id", "tag", "named", "name"}) {
+		t.Fatalf("got %v", names)
+	}
+	if c := schema.Fields[1].Constraints; c.MaxLength == nil || *c.MaxLength != 3 {
+		t.Fatalf("got %+v", c)
+	}
+}
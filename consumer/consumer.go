@@ -0,0 +1,185 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package consumer decodes the payloads of the Kafka or other queue messages into the structs by the codec
+// of their content types, and validates them by the struct tag expressions,
+// with the per-message error callback and the skip or dead-letter decision for the event-driven services.
+package consumer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"sync"
+
+	"github.com/bytedance/go-tagexpr/message"
+	"github.com/bytedance/go-tagexpr/validator"
+)
+
+// DefaultContentType the content type of the message without one
+const DefaultContentType = "application/json"
+
+// ErrUnknownCodec the content type of the message has no registered codec
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// Codec decodes the message payload into the struct pointer, such as json.Unmarshal or proto.Unmarshal.
+type Codec func(data []byte, structPtr interface{}) error
+
+var codecs = struct {
+	sync.RWMutex
+	m map[string]Codec
+}{m: map[string]Codec{DefaultContentType: json.Unmarshal}}
+
+// RegisterCodec registers the codec of the content type, such as application/x-protobuf or avro/binary,
+// application/json is registered by default.
+// NOTE:
+//  The codec of the same content type is replaced;
+//  The content types are matched by the media types without the parameters, case-insensitively,
+//  such as application/json for "Application/JSON; charset=utf-8".
+func RegisterCodec(contentType string, codec Codec) {
+	codecs.Lock()
+	codecs.m[mediaType(contentType)] = codec
+	codecs.Unlock()
+}
+
+func getCodec(contentType string) (Codec, bool) {
+	if contentType == "" {
+		contentType = DefaultContentType
+	}
+	codecs.RLock()
+	codec, ok := codecs.m[mediaType(contentType)]
+	codecs.RUnlock()
+	return codec, ok
+}
+
+// mediaType returns the lowercase media type of the content type without the parameters,
+// the content type as it is if it is malformed.
+func mediaType(contentType string) string {
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		return t
+	}
+	return contentType
+}
+
+// Message the queue message
+type Message struct {
+	Topic       string
+	Partition   int32
+	Offset      int64
+	Key         []byte
+	Value       []byte            // the payload
+	ContentType string            // the codec of the payload, DefaultContentType if empty
+	Headers     map[string]string // bound to the fields with the meta tag, such as `meta:"trace_id"`
+}
+
+// Decision what to do with the message that cannot be decoded or is invalid
+type Decision int
+
+const (
+	// DeadLetter sends the message to the dead-letter sink, then skips it
+	DeadLetter Decision = iota
+	// Skip drops the message
+	Skip
+	// Fail returns the error, so that the message is not committed and will be redelivered
+	Fail
+)
+
+// Adapter the message decoder and validator
+type Adapter struct {
+	vd         *validator.Validator
+	metaTag    string
+	onError    func(msg *Message, err error)
+	decide     func(msg *Message, err error) Decision
+	deadLetter func(msg *Message, err error) error
+}
+
+// New creates an adapter that validates by vd.
+func New(vd *validator.Validator) *Adapter {
+	return &Adapter{vd: vd, metaTag: message.DefaultMetaTag}
+}
+
+// SetMetaTag sets the tag name of the header keys, the default is meta.
+func (a *Adapter) SetMetaTag(metaTag string) *Adapter {
+	a.metaTag = metaTag
+	return a
+}
+
+// OnError sets the callback of each message that cannot be decoded or is invalid, such as for the logging and metrics.
+func (a *Adapter) OnError(fn func(msg *Message, err error)) *Adapter {
+	a.onError = fn
+	return a
+}
+
+// SetDecider sets the decision of the message that cannot be decoded or is invalid.
+// NOTE:
+//  By default it is DeadLetter if the dead-letter sink is set, otherwise Skip,
+//  since the payload errors are not recovered by the redelivery.
+func (a *Adapter) SetDecider(decide func(msg *Message, err error) Decision) *Adapter {
+	a.decide = decide
+	return a
+}
+
+// SetDeadLetter sets the dead-letter sink, such as the producer of the dead-letter topic.
+func (a *Adapter) SetDeadLetter(send func(msg *Message, err error) error) *Adapter {
+	a.deadLetter = send
+	return a
+}
+
+// Decode decodes the payload and the headers of msg into structPtr, and validates it.
+// NOTE:
+//  It returns true if the message is valid and should be handled;
+//  It returns false and nil if the message is skipped or sent to the dead-letter sink, then it should be committed;
+//  The error is returned for the Fail decision, or if the dead-letter sink fails;
+//  The error of an unregistered content type wraps ErrUnknownCodec.
+func (a *Adapter) Decode(msg *Message, structPtr interface{}) (bool, error) {
+	err := a.decode(msg, structPtr)
+	if err == nil {
+		return true, nil
+	}
+	if a.onError != nil {
+		a.onError(msg, err)
+	}
+	switch a.decision(msg, err) {
+	case Fail:
+		return false, err
+	case DeadLetter:
+		if a.deadLetter == nil {
+			return false, fmt.Errorf("consumer: no dead-letter sink: %w", err)
+		}
+		if dlErr := a.deadLetter(msg, err); dlErr != nil {
+			return false, dlErr
+		}
+	}
+	return false, nil
+}
+
+func (a *Adapter) decode(msg *Message, structPtr interface{}) error {
+	codec, ok := getCodec(msg.ContentType)
+	if !ok {
+		return fmt.Errorf("consumer: %w: %q", ErrUnknownCodec, msg.ContentType)
+	}
+	binder := message.New(a.vd).SetMetaTag(a.metaTag).SetUnmarshal(message.UnmarshalFunc(codec))
+	return binder.Bind(msg.Value, msg.Headers, structPtr)
+}
+
+func (a *Adapter) decision(msg *Message, err error) Decision {
+	if a.decide != nil {
+		return a.decide(msg, err)
+	}
+	if a.deadLetter != nil {
+		return DeadLetter
+	}
+	return Skip
+}
@@ -0,0 +1,61 @@
+package consumer_test
+
+import (
+	"errors"
+	"fmt"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+	"github.com/bytedance/go-tagexpr/consumer"
+	"github.com/bytedance/go-tagexpr/validator"
+)
+
+func Example() {
+	type OrderCreated struct {
+		TraceID string  `meta:"trace_id" vd:"$!=''"`
+		OrderID string  `json:"order_id" vd:"len($)>0"`
+		Amount  float64 `json:"amount" vd:"{@:$>0}{msg:'amount must be positive'}"`
+	}
+	var deadLetters []string
+	adapter := consumer.New(validator.New("vd")).
+		OnError(func(msg *consumer.Message, err error) {
+			fmt.Printf("offset %d: %v\n", msg.Offset, err)
+		}).
+		SetDecider(func(msg *consumer.Message, err error) consumer.Decision {
+			if errors.Is(err, tagexpr.ErrValidation) {
+				return consumer.DeadLetter
+			}
+			return consumer.Skip
+		}).
+		SetDeadLetter(func(msg *consumer.Message, err error) error {
+			deadLetters = append(deadLetters, string(msg.Key))
+			return nil
+		})
+	headers := map[string]string{"trace_id": "t-1"}
+	messages := []*consumer.Message{
+		{Offset: 1, Key: []byte("o-1"), Value: []byte(`{"order_id":"o-1","amount":9.9}`), Headers: headers},
+		{Offset: 2, Key: []byte("o-2"), Value: []byte(`{"order_id":"o-2","amount":0}`), Headers: headers},
+		{Offset: 3, Key: []byte("o-3"), Value: []byte(`{"order_id":"o-3"`), Headers: headers},
+		{Offset: 4, Key: []byte("o-4"), Value: []byte{0x0a}, ContentType: "avro/binary", Headers: headers},
+		{Offset: 5, Key: []byte("o-5"), Value: []byte(`{"order_id":"o-5","amount":1}`), ContentType: "Application/JSON; charset=utf-8", Headers: headers},
+	}
+	for _, msg := range messages {
+		var evt OrderCreated
+		ok, err := adapter.Decode(msg, &evt)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if ok {
+			fmt.Printf("offset %d: handle %s %v %s\n", msg.Offset, evt.OrderID, evt.Amount, evt.TraceID)
+		}
+	}
+	fmt.Println(deadLetters)
+
+	// Output:
+	// offset 1: handle o-1 9.9 t-1
+	// offset 2: amount must be positive
+	// offset 3: unexpected end of JSON input
+	// offset 4: consumer: unknown codec: "avro/binary"
+	// offset 5: handle o-5 1 t-1
+	// [o-2]
+}
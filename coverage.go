@@ -0,0 +1,156 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// ExprCoverage the coverage of an expression
+type ExprCoverage struct {
+	Struct   string // struct type name
+	Selector string
+	Expr     string // simplified source form of the expression
+	Hits     uint64 // number of evaluations
+	Branches []BranchCoverage
+}
+
+// BranchCoverage the coverage of an && or || operator
+type BranchCoverage struct {
+	Expr  string // source form of the operation
+	Op    string // && or ||
+	Short uint64 // number of evaluations that skip the right operand
+	Full  uint64 // number of evaluations that evaluate the right operand
+}
+
+// Covered reports whether both the short-circuit and the full evaluation have happened.
+func (b BranchCoverage) Covered() bool {
+	return b.Short > 0 && b.Full > 0
+}
+
+// branchCounter the coverage counters of the && or || operator
+type branchCounter struct {
+	short, full uint64
+}
+
+func (b *branchCounter) record(tagExpr *TagExpr, short bool) {
+	if vm := tagExpr.getVM(); vm == nil || !vm.coverage {
+		return
+	}
+	if short {
+		atomic.AddUint64(&b.short, 1)
+	} else {
+		atomic.AddUint64(&b.full, 1)
+	}
+}
+
+// SetCoverage sets whether to record the evaluations of the expressions,
+// and the branches of && and || taken, for finding the dead or untested rules.
+// NOTE:
+//  It should be called before the vm is used, such as in TestMain;
+//  The recording costs a little, do not enable it in production.
+func (vm *VM) SetCoverage(enable bool) *VM {
+	vm.coverage = enable
+	return vm
+}
+
+// Coverage returns the coverage of the expressions of all the registered structs,
+// sorted by the struct name and in the registration order of the selectors.
+// NOTE:
+//  The expressions of the nested struct fields are reported by their own struct types.
+func (vm *VM) Coverage() []ExprCoverage {
+	if vm.parent != nil {
+		return vm.parent.Coverage()
+	}
+	vm.rw.RLock()
+	structs := make([]*Struct, 0, len(vm.structJar))
+	for _, s := range vm.structJar {
+		structs = append(structs, s)
+	}
+	vm.rw.RUnlock()
+	sort.Slice(structs, func(i, j int) bool { return structs[i].name < structs[j].name })
+	var r []ExprCoverage
+	for _, s := range structs {
+		for _, e := range s.exprList {
+			if strings.Contains(e.field, ".") {
+				continue
+			}
+			c := ExprCoverage{
+				Struct:   s.name,
+				Selector: e.selector,
+				Expr:     e.expr.String(),
+				Hits:     atomic.LoadUint64(&e.expr.hits),
+			}
+			walkBranches(e.expr.expr.RightOperand(), &c.Branches)
+			r = append(r, c)
+		}
+	}
+	return r
+}
+
+func walkBranches(e ExprNode, branches *[]BranchCoverage) {
+	if e == nil {
+		return
+	}
+	var counter *branchCounter
+	switch r := e.(type) {
+	case *andExprNode:
+		counter = &r.branchCounter
+	case *orExprNode:
+		counter = &r.branchCounter
+	}
+	if counter != nil {
+		*branches = append(*branches, BranchCoverage{
+			Expr:  nodeString(e),
+			Op:    operatorString(e),
+			Short: atomic.LoadUint64(&counter.short),
+			Full:  atomic.LoadUint64(&counter.full),
+		})
+	}
+	for _, sub := range subNodes(e) {
+		walkBranches(sub, branches)
+	}
+}
+
+// WriteCoverage writes the coverage report, one expression per line, then the uncovered branches,
+// and the summary at last.
+func (vm *VM) WriteCoverage(w io.Writer) error {
+	var exprs, hitExprs, branches, coveredBranches int
+	for _, c := range vm.Coverage() {
+		exprs++
+		if c.Hits > 0 {
+			hitExprs++
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", c.Struct, c.Selector, c.Hits, c.Expr); err != nil {
+			return err
+		}
+		for _, b := range c.Branches {
+			branches++
+			if b.Covered() {
+				coveredBranches++
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "\t%s short=%d full=%d\t%s\n", b.Op, b.Short, b.Full, b.Expr); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "expressions: %d/%d evaluated, branches: %d/%d covered\n", hitExprs, exprs, coveredBranches, branches)
+	return err
+}
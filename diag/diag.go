@@ -0,0 +1,422 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diag returns the structured diagnostics of the struct tag expressions,
+// so that the editor plugins can underline the broken expressions inside the go string literals.
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+)
+
+// Severity the severity of the diagnostic, the values are the same as LSP
+type Severity int
+
+// severities
+const (
+	SeverityError Severity = iota + 1
+	SeverityWarning
+	SeverityInformation
+	SeverityHint
+)
+
+var severityNames = [...]string{"", "error", "warning", "information", "hint"}
+
+// String returns the lower case name of the severity.
+func (s Severity) String() string {
+	if s > 0 && int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return "severity(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Range the byte offsets [Start, End) in the checked text
+type Range struct {
+	Start, End int
+}
+
+// Fix the suggested fix that replaces the text of the range with NewText
+type Fix struct {
+	Message string
+	Range   Range
+	NewText string
+}
+
+// Diagnostic a problem of the struct tag expression
+type Diagnostic struct {
+	Range    Range
+	Severity Severity
+	Message  string
+	Fix      *Fix
+}
+
+// Checker diagnostics checker
+type Checker struct {
+	tagName string
+	vm      *tagexpr.VM
+	// fields is used to look up the fields, its tag name matches nothing
+	fields *tagexpr.VM
+}
+
+// New creates a diagnostics checker that uses @tagName as the tag name.
+func New(tagName string) *Checker {
+	return &Checker{
+		tagName: tagName,
+		vm:      tagexpr.New(tagName),
+		fields:  tagexpr.New(" "),
+	}
+}
+
+// Check returns the diagnostics of the tag value, such as {@:$>0}{msg:'invalid'},
+// structType is the struct that the tagged field belongs to, nil skips the field checks.
+// NOTE:
+//  The ranges are the byte offsets in the tag value.
+func (c *Checker) Check(structType reflect.Type, tag string) []Diagnostic {
+	var s *tagexpr.Struct
+	if structType != nil {
+		s, _ = c.fields.Struct(structType)
+	}
+	var diags []Diagnostic
+	for _, seg := range splitTag(tag, &diags) {
+		diags = append(diags, c.checkExpr(s, tag[seg.Start:seg.End], seg.Start)...)
+	}
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].Range.Start < diags[j].Range.Start })
+	return diags
+}
+
+// CheckStructTag returns the diagnostics of the @tagName value of the struct tag, such as `json:"a" vd:"$>0"`.
+// NOTE:
+//  The ranges are the byte offsets in the struct tag, that is the content of the raw string literal.
+func (c *Checker) CheckStructTag(structType reflect.Type, structTag string) []Diagnostic {
+	start, end, ok := lookupQuoted(structTag, c.tagName)
+	if !ok {
+		return nil
+	}
+	quoted := structTag[start:end]
+	value, offsets, err := unquote(quoted)
+	if err != nil {
+		return []Diagnostic{{
+			Range:    Range{start, end},
+			Severity: SeverityError,
+			Message:  "malformed quoted value: " + err.Error(),
+		}}
+	}
+	diags := c.Check(structType, value)
+	for i := range diags {
+		d := &diags[i]
+		d.Range = Range{start + offsets[d.Range.Start], start + offsets[d.Range.End]}
+		if d.Fix != nil {
+			d.Fix.Range = Range{start + offsets[d.Fix.Range.Start], start + offsets[d.Fix.Range.End]}
+			d.Fix.NewText = strings.Trim(strconv.Quote(d.Fix.NewText), `"`)
+		}
+	}
+	return diags
+}
+
+// splitTag returns the ranges of the expressions in the tag, and reports the structure problems.
+func splitTag(tag string, diags *[]Diagnostic) []Range {
+	i := skipSpace(tag, 0)
+	end := len(strings.TrimRight(tag, " \t\r\n"))
+	if i >= end {
+		return nil
+	}
+	if tag[i] != '{' {
+		return []Range{{i, end}}
+	}
+	var exprs []Range
+	names := make(map[string]bool)
+	for i < end {
+		if tag[i] != '{' {
+			*diags = append(*diags, Diagnostic{
+				Range:    Range{i, end},
+				Severity: SeverityError,
+				Message:  "expect {name:expression}",
+			})
+			break
+		}
+		closing := pairedBrace(tag, i)
+		if closing < 0 {
+			*diags = append(*diags, Diagnostic{
+				Range:    Range{i, end},
+				Severity: SeverityError,
+				Message:  "unclosed {",
+				Fix:      &Fix{Message: "add }", Range: Range{end, end}, NewText: "}"},
+			})
+			break
+		}
+		colon := strings.IndexByte(tag[i+1:closing], ':')
+		name := ""
+		if colon >= 0 {
+			name = strings.TrimSpace(tag[i+1 : i+1+colon])
+		}
+		switch {
+		case colon < 0 || name == "":
+			*diags = append(*diags, Diagnostic{
+				Range:    Range{i, closing + 1},
+				Severity: SeverityError,
+				Message:  "missing expression name, expect {name:expression}",
+			})
+		case names[name]:
+			*diags = append(*diags, Diagnostic{
+				Range:    Range{i + 1, i + 1 + colon},
+				Severity: SeverityError,
+				Message:  "duplicate expression name: " + name,
+			})
+		default:
+			names[name] = true
+			start := skipSpace(tag, i+2+colon)
+			stop := start + len(strings.TrimRight(tag[start:closing], " \t\r\n"))
+			if start >= stop {
+				*diags = append(*diags, Diagnostic{
+					Range:    Range{i, closing + 1},
+					Severity: SeverityError,
+					Message:  "empty expression: " + name,
+				})
+			} else {
+				exprs = append(exprs, Range{start, stop})
+			}
+		}
+		i = skipSpace(tag, closing+1)
+	}
+	return exprs
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// pairedBrace returns the index of the } that closes the { at i, -1 if not found,
+// the same as the tag parser, the braces escaped by the backslash are skipped.
+func pairedBrace(s string, i int) int {
+	level := 0
+	for j := i + 1; j < len(s); j++ {
+		escaped := s[j-1] == '\\' && (j < 2 || s[j-2] != '\\')
+		switch {
+		case escaped:
+		case s[j] == '{':
+			level++
+		case s[j] == '}':
+			if level == 0 {
+				return j
+			}
+			level--
+		}
+	}
+	return -1
+}
+
+// checkExpr returns the diagnostics of the expression, offset is its position in the tag.
+func (c *Checker) checkExpr(s *tagexpr.Struct, expr string, offset int) []Diagnostic {
+	e, err := c.vm.ParseExpr(expr)
+	if err != nil {
+		d := Diagnostic{
+			Range:    Range{offset, offset + len(expr)},
+			Severity: SeverityError,
+			Message:  "syntax incorrect",
+		}
+		if serr, ok := err.(*tagexpr.SyntaxError); ok {
+			d.Range.Start += serr.Offset
+			d.Message = fmt.Sprintf("syntax incorrect at %q", expr[serr.Offset:])
+		} else if errors.Is(err, tagexpr.ErrTypeMismatch) {
+			d.Message = err.Error()
+			return []Diagnostic{d}
+		}
+		d.Fix = balanceFix(expr, offset)
+		return []Diagnostic{d}
+	}
+	if s == nil {
+		return nil
+	}
+	var diags []Diagnostic
+	walk(e.AST(), func(n *tagexpr.Node) {
+		if n.Kind != tagexpr.SelectorNode || n.Name == "" {
+			return
+		}
+		if _, ok := s.StructField(n.Name); ok {
+			return
+		}
+		i := strings.Index(expr, "("+n.Name+")")
+		if i < 0 {
+			return
+		}
+		r := Range{offset + i + 1, offset + i + 1 + len(n.Name)}
+		d := Diagnostic{
+			Range:    r,
+			Severity: SeverityWarning,
+			Message:  "unknown field: " + n.Name,
+		}
+		if name := closestField(s, n.Name); name != "" {
+			d.Fix = &Fix{Message: "change to " + name, Range: r, NewText: name}
+		}
+		diags = append(diags, d)
+	})
+	return diags
+}
+
+func walk(n *tagexpr.Node, fn func(*tagexpr.Node)) {
+	if n == nil {
+		return
+	}
+	fn(n)
+	for _, arg := range n.Args {
+		walk(arg, fn)
+	}
+}
+
+// balanceFix returns the fix that closes the unbalanced quote or parentheses, nil if they are balanced.
+func balanceFix(expr string, offset int) *Fix {
+	var quoted bool
+	var parens int
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			parens++
+		case c == ')':
+			parens--
+		}
+	}
+	end := Range{offset + len(expr), offset + len(expr)}
+	switch {
+	case quoted:
+		return &Fix{Message: "add '", Range: end, NewText: "'"}
+	case parens > 0:
+		return &Fix{Message: "add )", Range: end, NewText: strings.Repeat(")", parens)}
+	}
+	return nil
+}
+
+// closestField returns the field name that is most similar to name, "" if none is similar enough.
+func closestField(s *tagexpr.Struct, name string) string {
+	var best string
+	bestDist := len(name)/3 + 2 // the distance must be less than it
+	if bestDist > 4 {
+		bestDist = 4
+	}
+	for _, field := range s.FieldNames() {
+		if d := distance(strings.ToLower(field), strings.ToLower(name)); d < bestDist {
+			best, bestDist = field, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance of the strings.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// lookupQuoted returns the range of the quoted value of the key in the struct tag, like reflect.StructTag.Lookup.
+func lookupQuoted(tag, key string) (start, end int, ok bool) {
+	i := 0
+	for i < len(tag) {
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		j := i
+		for j < len(tag) && tag[j] > ' ' && tag[j] != ':' && tag[j] != '"' && tag[j] != 0x7f {
+			j++
+		}
+		if j == i || j+1 >= len(tag) || tag[j] != ':' || tag[j+1] != '"' {
+			return 0, 0, false
+		}
+		name := tag[i:j]
+		start = j + 1
+		j = start + 1
+		for j < len(tag) && tag[j] != '"' {
+			if tag[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if j >= len(tag) {
+			return 0, 0, false
+		}
+		if name == key {
+			return start, j + 1, true
+		}
+		i = j + 1
+	}
+	return 0, 0, false
+}
+
+// unquote unquotes the go string literal,
+// offsets[i] is the offset in the literal of the byte i of the value, offsets[len(value)] is the closing quote.
+func unquote(quoted string) (value string, offsets []int, err error) {
+	if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+		return "", nil, strconv.ErrSyntax
+	}
+	s := quoted[1 : len(quoted)-1]
+	var b []byte
+	pos := 1
+	for len(s) > 0 {
+		r, multibyte, tail, err := strconv.UnquoteChar(s, '"')
+		if err != nil {
+			return "", nil, err
+		}
+		n := len(b)
+		if multibyte || r >= 0x80 {
+			b = append(b, string(r)...)
+		} else {
+			b = append(b, byte(r))
+		}
+		for ; n < len(b); n++ {
+			offsets = append(offsets, pos)
+		}
+		pos += len(s) - len(tail)
+		s = tail
+	}
+	offsets = append(offsets, pos)
+	return string(b), offsets, nil
+}
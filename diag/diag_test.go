@@ -0,0 +1,67 @@
+package diag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	type Addr struct {
+		City string
+	}
+	type T struct {
+		Name string
+		Addr Addr
+	}
+	typ := reflect.TypeOf(T{})
+	cases := []struct {
+		tag   string
+		diags []Diagnostic
+	}{
+		{"$>0", nil},
+		{"{@:len($)>0}{msg:'bad'}", nil},
+		{"{@:bool: $>0}", nil},
+		{"{@:bool: (Nmae)$>0}", []Diagnostic{{Range{10, 14}, SeverityWarning, "unknown field: Nmae", &Fix{"change to Name", Range{10, 14}, "Name"}}}},
+		{"{@:bool: $>>1}", []Diagnostic{{Range{11, 13}, SeverityError, `syntax incorrect at ">1"`, nil}}},
+		{"{@:bool: 'a'}", []Diagnostic{{Range{3, 12}, SeverityError, `"bool: 'a'": the result is string, declared bool`, nil}}},
+		{"{@:$>>1}", []Diagnostic{{Range{5, 7}, SeverityError, `syntax incorrect at ">1"`, nil}}},
+		{"regexp('a)", []Diagnostic{{Range{6, 10}, SeverityError, `syntax incorrect at "('a)"`, &Fix{"add '", Range{10, 10}, "'"}}}},
+		{"($>1", []Diagnostic{{Range{0, 4}, SeverityError, `syntax incorrect at "($>1"`, &Fix{"add )", Range{4, 4}, ")"}}}},
+		{"{@:$>0", []Diagnostic{{Range{0, 6}, SeverityError, "unclosed {", &Fix{"add }", Range{6, 6}, "}"}}}},
+		{"{$>0}", []Diagnostic{{Range{0, 5}, SeverityError, "missing expression name, expect {name:expression}", nil}}},
+		{"{@:$>0}{@:$<9}", []Diagnostic{{Range{8, 9}, SeverityError, "duplicate expression name: @", nil}}},
+		{"{@:$>0}{msg: }", []Diagnostic{{Range{7, 14}, SeverityError, "empty expression: msg", nil}}},
+		{"{@:$>0} x", []Diagnostic{{Range{8, 9}, SeverityError, "expect {name:expression}", nil}}},
+		{"(Nmae)$!=''&&(Addr.Cty)$!=''&&(Foo)$==nil", []Diagnostic{
+			{Range{1, 5}, SeverityWarning, "unknown field: Nmae", &Fix{"change to Name", Range{1, 5}, "Name"}},
+			{Range{14, 22}, SeverityWarning, "unknown field: Addr.Cty", &Fix{"change to Addr.City", Range{14, 22}, "Addr.City"}},
+			{Range{31, 34}, SeverityWarning, "unknown field: Foo", nil},
+		}},
+	}
+	c := New("vd")
+	for _, cc := range cases {
+		diags := c.Check(typ, cc.tag)
+		if !reflect.DeepEqual(diags, cc.diags) {
+			t.Fatalf("%s: got %+v, want %+v", cc.tag, diags, cc.diags)
+		}
+	}
+	if diags := c.Check(nil, "(Nmae)$!=''"); diags != nil {
+		t.Fatalf("got %+v, want nil", diags)
+	}
+}
+
+func TestCheckStructTag(t *testing.T) {
+	c := New("vd")
+	tag := `json:"a" vd:"regexp('\\d+)"`
+	diags := c.CheckStructTag(nil, tag)
+	want := []Diagnostic{{Range{19, 26}, SeverityError, `syntax incorrect at "('\\d+)"`, &Fix{"add '", Range{26, 26}, "'"}}}
+	if !reflect.DeepEqual(diags, want) {
+		t.Fatalf("got %+v, want %+v", diags, want)
+	}
+	if diags = c.CheckStructTag(nil, `json:"a"`); diags != nil {
+		t.Fatalf("got %+v, want nil", diags)
+	}
+	if SeverityWarning.String() != "warning" || Severity(9).String() != "severity(9)" {
+		t.Fatal("unexpected severity names")
+	}
+}
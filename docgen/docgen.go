@@ -0,0 +1,152 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package docgen generates the documentation of the struct tag expression rules,
+// for publishing the validation contracts to the API consumers.
+package docgen
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"reflect"
+	"strings"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+)
+
+const errMsgExprName = "msg"
+
+// Generator rule documentation generator
+type Generator struct {
+	vm *tagexpr.VM
+}
+
+// New creates a rule documentation generator that uses @tagName as the tag name.
+func New(tagName string) *Generator {
+	return &Generator{
+		vm: tagexpr.New(tagName),
+	}
+}
+
+// Row a rule of the struct field
+type Row struct {
+	Field      string // field selector, such as A.B
+	Type       string // go type of the field
+	Rule       string // expression name, "@" is the default one
+	Expression string // simplified source form of the expression
+	Message    string // source form of the error message expression
+}
+
+// Table the rules of the struct type
+type Table struct {
+	Struct string
+	Rows   []Row
+}
+
+// Tables returns the rule tables of the structs.
+func (g *Generator) Tables(structOrStructPtr ...interface{}) ([]Table, error) {
+	tables := make([]Table, 0, len(structOrStructPtr))
+	for _, v := range structOrStructPtr {
+		if v == nil {
+			return nil, errors.New("cannot generate nil interface")
+		}
+		s, err := g.vm.Struct(reflect.TypeOf(v))
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, newTable(s))
+	}
+	return tables, nil
+}
+
+func newTable(s *tagexpr.Struct) Table {
+	exprs := make(map[string]*tagexpr.Expr)
+	s.Range(func(selector string, expr *tagexpr.Expr) bool {
+		exprs[selector] = expr
+		return true
+	})
+	t := Table{Struct: s.Name()}
+	s.Range(func(selector string, expr *tagexpr.Expr) bool {
+		idx := strings.LastIndex(selector, "@")
+		field, rule := selector[:idx], selector[idx:]
+		if rule == "@"+errMsgExprName {
+			return true
+		}
+		row := Row{
+			Field:      field,
+			Rule:       rule,
+			Expression: expr.String(),
+		}
+		if sf, ok := s.StructField(field); ok {
+			row.Type = sf.Type.String()
+		}
+		if rule == "@" {
+			if msg, ok := exprs[selector+errMsgExprName]; ok {
+				row.Message = msg.String()
+			}
+		}
+		t.Rows = append(t.Rows, row)
+		return true
+	})
+	return t
+}
+
+// Markdown writes the rule tables of the structs in Markdown.
+func (g *Generator) Markdown(w io.Writer, structOrStructPtr ...interface{}) error {
+	tables, err := g.Tables(structOrStructPtr...)
+	if err != nil {
+		return err
+	}
+	escape := strings.NewReplacer("|", `\|`, "\n", " ").Replace
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "### %s\n\n", t.Struct)
+		fmt.Fprintln(w, "|Field|Type|Rule|Expression|Message|")
+		fmt.Fprintln(w, "|-----|----|----|----------|-------|")
+		for _, r := range t.Rows {
+			_, err = fmt.Fprintf(w, "|%s|%s|%s|`%s`|%s|\n",
+				escape(r.Field), escape(r.Type), escape(r.Rule), escape(r.Expression), escape(r.Message))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// HTML writes the rule tables of the structs in HTML.
+func (g *Generator) HTML(w io.Writer, structOrStructPtr ...interface{}) error {
+	tables, err := g.Tables(structOrStructPtr...)
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		fmt.Fprintf(w, "<h3>%s</h3>\n<table>\n", html.EscapeString(t.Struct))
+		fmt.Fprintln(w, "<tr><th>Field</th><th>Type</th><th>Rule</th><th>Expression</th><th>Message</th></tr>")
+		for _, r := range t.Rows {
+			fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td><td><code>%s</code></td><td>%s</td></tr>\n",
+				html.EscapeString(r.Field), html.EscapeString(r.Type), html.EscapeString(r.Rule),
+				html.EscapeString(r.Expression), html.EscapeString(r.Message))
+		}
+		_, err = fmt.Fprintln(w, "</table>")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
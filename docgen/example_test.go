@@ -0,0 +1,36 @@
+package docgen_test
+
+import (
+	"os"
+
+	"github.com/bytedance/go-tagexpr/docgen"
+)
+
+func Example() {
+	type Addr struct {
+		Zip string `vd:"len($)==5"`
+	}
+	type User struct {
+		Name string `vd:"{@:$!=''}{msg:'name required'}"`
+		Age  int    `vd:"{@:$>=18}{max:$<=60*2}"`
+		Addr *Addr
+	}
+	g := docgen.New("vd")
+	g.Markdown(os.Stdout, new(User))
+	g.HTML(os.Stdout, Addr{})
+
+	// Output:
+	// ### docgen_test.User
+	//
+	// |Field|Type|Rule|Expression|Message|
+	// |-----|----|----|----------|-------|
+	// |Name|string|@|`$ != ''`|'name required'|
+	// |Age|int|@|`$ >= 18`||
+	// |Age|int|@max|`$ <= 120`||
+	// |Addr.Zip|string|@|`len($) == 5`||
+	// <h3>docgen_test.Addr</h3>
+	// <table>
+	// <tr><th>Field</th><th>Type</th><th>Rule</th><th>Expression</th><th>Message</th></tr>
+	// <tr><td>Zip</td><td>string</td><td>@</td><td><code>len($) == 5</code></td><td></td></tr>
+	// </table>
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"reflect"
+	"sync"
+)
+
+const cmpExprName = "cmp"
+
+// equalVMs the vms of the package-level Equal by the tag name
+var equalVMs sync.Map
+
+// Equal reports whether the two struct pointers of the same type are equal by the fields marked in the tagName tag,
+// such as the idempotency key fields of a request, see VM.Equal.
+func Equal(a, b interface{}, tagName string) (bool, error) {
+	vm, ok := equalVMs.Load(tagName)
+	if !ok {
+		vm, _ = equalVMs.LoadOrStore(tagName, New(tagName))
+	}
+	return vm.(*VM).Equal(a, b)
+}
+
+// Equal reports whether the two struct pointers of the same type are equal by the marked fields,
+// for the domain-aware deduplication and idempotency checks.
+// NOTE:
+//  The field is marked if its cmp expression, such as {cmp:true}, is true for either struct;
+//  If the struct has no cmp expression, all the fields with tag expressions are compared;
+//  The values are compared deeply, the numbers of different types are equal if their values are, like the expressions.
+func (vm *VM) Equal(a, b interface{}) (bool, error) {
+	if ta, tb := reflect.TypeOf(a), reflect.TypeOf(b); ta != tb {
+		return false, newKindError(ErrTypeMismatch, "cannot compare different types: %v and %v", ta, tb)
+	}
+	ta, err := vm.Run(a)
+	if err != nil {
+		return false, err
+	}
+	tb, err := vm.Run(b)
+	if err != nil {
+		return false, err
+	}
+	fields, err := ta.compareFields(tb)
+	if err != nil {
+		return false, err
+	}
+	for _, field := range fields {
+		ha, _ := ta.Field(field)
+		hb, _ := tb.Field(field)
+		if !reflect.DeepEqual(snapshotValue(ha.Value(), 0), snapshotValue(hb.Value(), 0)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// compareFields returns the fields marked by the cmp expressions of t or other,
+// or all the fields with tag expressions if there is no cmp expression.
+func (t *TagExpr) compareFields(other *TagExpr) ([]string, error) {
+	var fields []string
+	seen := make(map[string]bool)
+	if len(t.s.compares) == 0 {
+		for _, e := range t.s.exprList {
+			if !seen[e.field] {
+				seen[e.field] = true
+				fields = append(fields, e.field)
+			}
+		}
+		return fields, nil
+	}
+	for _, e := range t.s.compares {
+		for _, te := range [2]*TagExpr{t, other} {
+			v, err := te.safeRun(e)
+			if err != nil {
+				return nil, err
+			}
+			if v == true && !seen[e.field] {
+				seen[e.field] = true
+				fields = append(fields, e.field)
+			}
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The sentinel errors, the returned errors can be checked by errors.Is, such as:
+//  if errors.Is(err, tagexpr.ErrSyntax) { ... }
+var (
+	// ErrSyntax the syntax of the expression or the struct tag is incorrect, see *SyntaxError
+	ErrSyntax = errors.New("syntax incorrect")
+	// ErrUnknownSelector the selector of the expression does not exist
+	ErrUnknownSelector = errors.New("unknown selector")
+	// ErrNilDeref the nil value is run or dereferenced
+	ErrNilDeref = errors.New("nil dereference")
+	// ErrTypeMismatch the value is not of the expected type, such as not a structure pointer
+	ErrTypeMismatch = errors.New("type mismatch")
+	// ErrValidation the value does not satisfy the rules, such as *validator.Error
+	ErrValidation = errors.New("validation failed")
+	// ErrUnreachable the operands of the expression cannot be reached, such as through the nil pointer
+	ErrUnreachable = errors.New("unreachable")
+	// ErrLimitExceeded the struct exceeds the registration limits, see RegisterLimits
+	ErrLimitExceeded = errors.New("limit exceeded")
+)
+
+// kindError the error of the sentinel kind, keeps its own message.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func newKindError(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// Error implements error.
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the sentinel error.
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
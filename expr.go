@@ -16,38 +16,130 @@ package tagexpr
 
 import (
 	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unsafe"
 )
 
 // Expr expression
 type Expr struct {
+	hits uint64 // number of evaluations, recorded if the coverage is enabled, first for the 64-bit alignment
 	expr ExprNode
+	vm   *VM
+	ps   *parseState // only used when parsing
+
+	src        string // the source, the key of the rule cache
+	resultType string // the declared result type, such as bool in {@:bool: $>0}
 }
 
 // parseExpr parses the expression.
 func parseExpr(expr string) (*Expr, error) {
+	return parseVMExpr(nil, expr)
+}
+
+// parseVMExpr parses the expression,
+// the regular expressions are shared by all the expressions of the vm.
+func parseVMExpr(vm *VM, expr string) (*Expr, error) {
+	if p, ok := vm.loadCachedExpr(expr); ok {
+		return p, nil
+	}
 	e := newGroupExprNode()
 	p := &Expr{
 		expr: e,
+		vm:   vm,
+		ps:   &parseState{limits: vm.getParseLimits(), source: expr},
+		src:  expr,
+	}
+	if max := p.ps.limits.MaxLength; max >= 0 && len(expr) > max {
+		return nil, &SyntaxError{Expr: expr, Offset: max, Reason: fmt.Sprintf("expression too long, the limit is %d bytes", max)}
 	}
 	s := expr
 	_, err := p.parseExprNode(&s, e)
+	if p.ps.err != nil {
+		return nil, p.ps.err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("%q (syntax incorrect): %s", expr, err.Error())
+		return nil, &SyntaxError{Expr: expr, Offset: offsetOf(expr, string(err.(parsingPosError)))}
 	}
+	p.ps = nil
 	sortPriority(e.RightOperand())
 	err = p.checkSyntax()
 	if err != nil {
 		return nil, err
 	}
+	foldConst(e)
+	if vm != nil && vm.costOrder {
+		reorderByCost(e)
+	}
 	return p, nil
 }
 
+// SyntaxError the syntax error of the expression
+type SyntaxError struct {
+	Expr   string // source of the expression
+	Offset int    // byte offset of the part that cannot be parsed
+	Reason string // the exceeded parse limit, empty for the malformed syntax
+}
+
+// Error implements error.
+func (e *SyntaxError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%.64q (syntax incorrect): %s", e.Expr, e.Reason)
+	}
+	return fmt.Sprintf("%q (syntax incorrect): parsing pos: %q", e.Expr, e.Expr[e.Offset:])
+}
+
+// Is reports whether the target is ErrSyntax.
+func (e *SyntaxError) Is(target error) bool {
+	return target == ErrSyntax
+}
+
+// parsingPosError the rest of the expression that cannot be parsed
+type parsingPosError string
+
+func (e parsingPosError) Error() string {
+	return fmt.Sprintf("parsing pos: %q", string(e))
+}
+
+// offsetOf returns the byte offset of sub in s.
+// NOTE:
+//  sub is expected to be sliced from s, otherwise it is regarded as the suffix of s.
+func offsetOf(s, sub string) int {
+	base := (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+	p := (*reflect.StringHeader)(unsafe.Pointer(&sub)).Data
+	if p < base || p+uintptr(len(sub)) > base+uintptr(len(s)) {
+		return len(s) - len(sub)
+	}
+	return int(p - base)
+}
+
+// Cost returns the estimated cost of evaluating the expression,
+// the literals and the field values are cheap, while the regexps and the functions are expensive.
+func (p *Expr) Cost() int {
+	return nodeCost(p.expr)
+}
+
+// String returns the simplified form of the expression.
+func (p *Expr) String() string {
+	return nodeString(p.expr.RightOperand())
+}
+
 // run calculates the value of expression.
 func (p *Expr) run(field string, tagExpr *TagExpr) interface{} {
+	if vm := tagExpr.getVM(); vm != nil && vm.coverage {
+		atomic.AddUint64(&p.hits, 1)
+	}
 	return p.expr.Run(field, tagExpr)
 }
 
 func (p *Expr) parseOperand(expr *string) (e ExprNode) {
+	if e = p.readPluginExprNode(expr); e != nil {
+		return e
+	}
 	if e = p.readLenFnExprNode(expr); e != nil {
 		return e
 	}
@@ -57,6 +149,24 @@ func (p *Expr) parseOperand(expr *string) (e ExprNode) {
 	if e = p.readSprintfFnExprNode(expr); e != nil {
 		return e
 	}
+	if e = p.readDiveFnExprNode(expr); e != nil {
+		return e
+	}
+	if e = p.readExistsFnExprNode(expr); e != nil {
+		return e
+	}
+	if e = p.readFuncExprNode(expr); e != nil {
+		return e
+	}
+	if e = readDiveExprNode(expr); e != nil {
+		return e
+	}
+	if e = readRuleExprNode(expr); e != nil {
+		return e
+	}
+	if e = readParamExprNode(expr); e != nil {
+		return e
+	}
 	if e = readStringExprNode(expr); e != nil {
 		return e
 	}
@@ -128,6 +238,9 @@ func (*Expr) parseOperator(expr *string) (e ExprNode) {
 }
 
 func (p *Expr) parseExprNode(expr *string, e ExprNode) (ExprNode, error) {
+	if err := p.ps.countToken(*expr); err != nil {
+		return nil, err
+	}
 	trimLeftSpace(expr)
 	if *expr == "" {
 		return nil, nil
@@ -137,7 +250,7 @@ func (p *Expr) parseExprNode(expr *string, e ExprNode) (ExprNode, error) {
 		var subExprNode *string
 		operand, subExprNode = readGroupExprNode(expr)
 		if operand != nil {
-			_, err := p.parseExprNode(subExprNode, operand)
+			_, err := p.parseSubExprNode(subExprNode, operand)
 			if err != nil {
 				return nil, err
 			}
@@ -146,7 +259,7 @@ func (p *Expr) parseExprNode(expr *string, e ExprNode) (ExprNode, error) {
 		}
 	}
 	if operand == nil {
-		return nil, fmt.Errorf("parsing pos: %q", *expr)
+		return nil, parsingPosError(*expr)
 	}
 
 	trimLeftSpace(expr)
@@ -156,6 +269,11 @@ func (p *Expr) parseExprNode(expr *string, e ExprNode) (ExprNode, error) {
 		operand.SetParent(e)
 		return operand, nil
 	}
+	trimLeftSpace(expr)
+	if *expr == "" {
+		// the binary operator misses its right operand
+		return nil, parsingPosError(*expr)
+	}
 	if _, ok := e.(*groupExprNode); ok {
 		operator.SetLeftOperand(operand)
 		operand.SetParent(operator)
@@ -248,6 +366,400 @@ func leftOperandToParent(e ExprNode) {
 	e.SetParent(le)
 }
 
+// foldConst replaces the constant sub-expressions with the literals,
+// such as 60*60, len('abc') or sprintf('%d',1), returns whether e is constant.
+// NOTE:
+//  The functions registered by RegFunc and the VM-dependent built-in functions are never folded.
+func foldConst(e ExprNode) bool {
+	var isConst bool
+	switch r := e.(type) {
+	case nil:
+		return false
+	case *boolExprNode, *stringExprNode, *digitalExprNode, *nilExprNode:
+		return true
+	case *diveExprNode, *ruleExprNode, *paramExprNode:
+		return false
+	case *selectorExprNode:
+		foldArgs(r.subExprs)
+		return false
+	case *diveFnExprNode:
+		foldConst(r.collection)
+		foldConst(r.predicate)
+		return false
+	case *existsFnExprNode:
+		return false
+	case *sprintfFnExprNode:
+		isConst = foldArgs(r.args)
+	case *funcExprNode:
+		isConst = foldArgs(r.args) && pureFuncs[r.name]
+	case *pluginExprNode:
+		foldArgs(r.args)
+		return false
+	case *groupExprNode, *lenFnExprNode, *regexpFnExprNode:
+		isConst = foldChild(e, e.RightOperand(), e.SetRightOperand)
+	case *andExprNode, *orExprNode:
+		leftConst := foldChild(e, e.LeftOperand(), e.SetLeftOperand)
+		rightConst := foldChild(e, e.RightOperand(), e.SetRightOperand)
+		isConst = leftConst && rightConst
+		if leftConst && !isConst {
+			// short-circuit: false&&x is false, true||x is true
+			_, isOr := e.(*orExprNode)
+			isConst = isTruthy(e.LeftOperand().Run("", nil)) == isOr
+		}
+	default:
+		if operatorString(e) == "" {
+			return false
+		}
+		leftConst := foldChild(e, e.LeftOperand(), e.SetLeftOperand)
+		rightConst := foldChild(e, e.RightOperand(), e.SetRightOperand)
+		isConst = leftConst && rightConst
+	}
+	return isConst
+}
+
+// foldChild folds the child node of e, returns whether it is constant.
+func foldChild(e, child ExprNode, set func(ExprNode)) bool {
+	if !foldConst(child) {
+		return false
+	}
+	if isLiteral(child) {
+		return true
+	}
+	if lit := newLiteralExprNode(child.Run("", nil)); lit != nil {
+		lit.SetParent(e)
+		set(lit)
+	}
+	return true
+}
+
+// foldArgs folds the arguments of the function, returns whether all of them are constant.
+func foldArgs(args []ExprNode) bool {
+	allConst := true
+	for i, arg := range args {
+		if !foldConst(arg) {
+			allConst = false
+			continue
+		}
+		if isLiteral(arg) {
+			continue
+		}
+		if lit := newLiteralExprNode(arg.Run("", nil)); lit != nil {
+			args[i] = lit
+		}
+	}
+	return allConst
+}
+
+// isLiteral reports whether e is the literal node, which is kept as it is,
+// such as the number literal with its exact value.
+func isLiteral(e ExprNode) bool {
+	switch e.(type) {
+	case *boolExprNode, *stringExprNode, *digitalExprNode, *nilExprNode:
+		return true
+	}
+	return false
+}
+
+// newLiteralExprNode returns the literal node of v, nil if v has no literal form.
+func newLiteralExprNode(v interface{}) ExprNode {
+	switch r := v.(type) {
+	case nil:
+		return &nilExprNode{}
+	case bool:
+		return &boolExprNode{val: r}
+	case string:
+		return &stringExprNode{val: r}
+	case float64:
+		if math.IsNaN(r) || math.IsInf(r, 0) {
+			return nil
+		}
+		return &digitalExprNode{val: r}
+	}
+	return nil
+}
+
+// subNodes returns the child nodes of e.
+func subNodes(e ExprNode) []ExprNode {
+	switch r := e.(type) {
+	case *selectorExprNode:
+		return r.subExprs
+	case *sprintfFnExprNode:
+		return r.args
+	case *funcExprNode:
+		return r.args
+	case *pluginExprNode:
+		return r.args
+	case *diveFnExprNode:
+		if r.predicate == nil {
+			return []ExprNode{r.collection}
+		}
+		return []ExprNode{r.collection, r.predicate}
+	}
+	var a []ExprNode
+	if left := e.LeftOperand(); left != nil {
+		a = append(a, left)
+	}
+	if right := e.RightOperand(); right != nil {
+		a = append(a, right)
+	}
+	return a
+}
+
+// nodeCost returns the estimated cost of evaluating the expression node.
+func nodeCost(e ExprNode) int {
+	var cost int
+	switch r := e.(type) {
+	case nil:
+		return 0
+	case *boolExprNode, *stringExprNode, *digitalExprNode, *nilExprNode, *diveExprNode, *paramExprNode:
+		return 0
+	case *selectorExprNode:
+		cost = 1
+	case *groupExprNode:
+		cost = 0
+	case *lenFnExprNode, *existsFnExprNode:
+		cost = 2
+	case *sprintfFnExprNode:
+		cost = 5
+	case *regexpFnExprNode:
+		cost = 10
+	case *funcExprNode:
+		cost = 20
+		if pureFuncs[r.name] {
+			cost = 5
+		}
+	case *pluginExprNode:
+		cost = 20
+	case *diveFnExprNode:
+		// the predicate is run for each element
+		return 20 + nodeCost(r.collection) + 4*nodeCost(r.predicate)
+	default:
+		if operatorString(e) == "" {
+			return 20
+		}
+		cost = 1
+	}
+	for _, sub := range subNodes(e) {
+		cost += nodeCost(sub)
+	}
+	return cost
+}
+
+// reorderByCost reorders the operands of the && and || chains from cheap to expensive,
+// so that the short circuit skips the expensive ones as much as possible.
+// NOTE:
+//  The operands that may have side effects, see hasSideEffects, stay in place,
+//  only the operands between them are reordered, so whether they are evaluated does not change;
+//  The result of && and || is bool, the reordering does not change the value,
+//  but the operand that panics or is unreachable may be evaluated or skipped differently.
+func reorderByCost(e ExprNode) {
+	if e == nil {
+		return
+	}
+	switch e.(type) {
+	case *andExprNode, *orExprNode:
+	default:
+		for _, sub := range subNodes(e) {
+			reorderByCost(sub)
+		}
+		return
+	}
+	type slot struct {
+		node ExprNode
+		left bool
+	}
+	var slots []slot
+	var operands []ExprNode
+	var walk func(ExprNode)
+	walk = func(n ExprNode) {
+		for _, left := range [2]bool{true, false} {
+			sub := n.RightOperand()
+			if left {
+				sub = n.LeftOperand()
+			}
+			if reflect.TypeOf(sub) == reflect.TypeOf(e) {
+				walk(sub)
+				continue
+			}
+			reorderByCost(sub)
+			slots = append(slots, slot{node: n, left: left})
+			operands = append(operands, sub)
+		}
+	}
+	walk(e)
+	for i := 0; i < len(operands); {
+		if hasSideEffects(operands[i]) {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(operands) && !hasSideEffects(operands[j]) {
+			j++
+		}
+		run := operands[i:j]
+		sort.SliceStable(run, func(i, j int) bool {
+			return nodeCost(run[i]) < nodeCost(run[j])
+		})
+		i = j
+	}
+	for i, s := range slots {
+		if s.left {
+			s.node.SetLeftOperand(operands[i])
+		} else {
+			s.node.SetRightOperand(operands[i])
+		}
+		operands[i].SetParent(s.node)
+	}
+}
+
+// hasSideEffects reports whether evaluating the expression node may have side effects,
+// such as the functions registered by RegFunc, the plugins and the references to the other expressions.
+// NOTE:
+//  The referenced expression is not known when parsing, it may call the functions registered by RegFunc.
+func hasSideEffects(e ExprNode) bool {
+	switch r := e.(type) {
+	case nil:
+		return false
+	case *funcExprNode:
+		if customFuncs[r.name] {
+			return true
+		}
+	case *pluginExprNode:
+		return true
+	case *ruleExprNode:
+		return true
+	}
+	for _, sub := range subNodes(e) {
+		if hasSideEffects(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeString returns the source form of the expression node.
+func nodeString(e ExprNode) string {
+	switch r := e.(type) {
+	case nil:
+		return ""
+	case *boolExprNode:
+		return strconv.FormatBool(r.val)
+	case *stringExprNode:
+		return quoteString(r.val)
+	case *digitalExprNode:
+		return strconv.FormatFloat(r.val, 'f', -1, 64)
+	case *nilExprNode:
+		return "nil"
+	case *diveExprNode:
+		s := "#v"
+		if r.key {
+			s = "#k"
+		}
+		return boolPrefixString(r.boolPrefix) + s
+	case *groupExprNode:
+		return boolPrefixString(r.boolPrefix) + "(" + nodeString(r.rightOperand) + ")"
+	case *selectorExprNode:
+		s := boolPrefixString(r.boolPrefix)
+		if r.field != "" {
+			s += "(" + r.field + ")"
+		}
+		s += r.name
+		for i, sub := range r.subExprs {
+			if i < len(r.safe) && r.safe[i] {
+				s += "?"
+			}
+			s += "[" + nodeString(unwrapGroup(sub)) + "]"
+		}
+		return s
+	case *lenFnExprNode:
+		return "len(" + nodeString(unwrapGroup(r.rightOperand)) + ")"
+	case *regexpFnExprNode:
+		return "regexp('" + r.re.String() + "'," + nodeString(unwrapGroup(r.rightOperand)) + ")"
+	case *sprintfFnExprNode:
+		return "sprintf(" + strings.Join(append([]string{quoteString(r.format)}, argStrings(r.args)...), ",") + ")"
+	case *funcExprNode:
+		return boolPrefixString(r.boolPrefix) + r.name + "(" + strings.Join(argStrings(r.args), ",") + ")"
+	case *pluginExprNode:
+		return boolPrefixString(r.boolPrefix) + r.src
+	case *diveFnExprNode:
+		args := []ExprNode{r.collection}
+		if r.predicate != nil {
+			args = append(args, r.predicate)
+		}
+		return boolPrefixString(r.boolPrefix) + r.name + "(" + strings.Join(argStrings(args), ",") + ")"
+	case *existsFnExprNode:
+		return boolPrefixString(r.boolPrefix) + r.name + "(" + nodeString(unwrapGroup(r.rightOperand)) + ")"
+	case *ruleExprNode:
+		return boolPrefixString(r.boolPrefix) + "@" + strings.TrimSuffix(r.selector, "@")
+	case *paramExprNode:
+		return boolPrefixString(r.boolPrefix) + "%" + r.name + "%"
+	}
+	op := operatorString(e)
+	if op == "" {
+		return fmt.Sprintf("%T", e)
+	}
+	return nodeString(e.LeftOperand()) + " " + op + " " + nodeString(e.RightOperand())
+}
+
+func operatorString(e ExprNode) string {
+	switch e.(type) {
+	case *additionExprNode:
+		return "+"
+	case *subtractionExprNode:
+		return "-"
+	case *multiplicationExprNode:
+		return "*"
+	case *divisionExprNode:
+		return "/"
+	case *remainderExprNode:
+		return "%"
+	case *equalExprNode:
+		return "=="
+	case *notEqualExprNode:
+		return "!="
+	case *greaterExprNode:
+		return ">"
+	case *greaterEqualExprNode:
+		return ">="
+	case *lessExprNode:
+		return "<"
+	case *lessEqualExprNode:
+		return "<="
+	case *andExprNode:
+		return "&&"
+	case *orExprNode:
+		return "||"
+	}
+	return ""
+}
+
+func argStrings(args []ExprNode) []string {
+	a := make([]string, len(args))
+	for i, arg := range args {
+		a[i] = nodeString(unwrapGroup(arg))
+	}
+	return a
+}
+
+// unwrapGroup returns the content of the group node that wraps the argument.
+func unwrapGroup(e ExprNode) ExprNode {
+	if grp, ok := e.(*groupExprNode); ok && grp.boolPrefix == nil {
+		return grp.rightOperand
+	}
+	return e
+}
+
+func boolPrefixString(boolPrefix *bool) string {
+	switch {
+	case boolPrefix == nil:
+		return ""
+	case *boolPrefix:
+		return "!!"
+	default:
+		return "!"
+	}
+}
+
 // ExprNode expression interface
 type ExprNode interface {
 	SetParent(ExprNode)
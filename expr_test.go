@@ -15,8 +15,11 @@
 package tagexpr
 
 import (
+	"errors"
+	"fmt"
 	"math"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -57,6 +60,7 @@ func TestExpr(t *testing.T) {
 		{expr: "20%2", val: 0.0},
 		{expr: "6 % 5", val: 1.0},
 		{expr: "20%7 %5", val: 1.0},
+		{expr: "7%0.5", val: math.NaN()},
 		{expr: "1*2+7+2.2", val: 11.2},
 		{expr: "-20/2+1+2", val: -7.0},
 		{expr: "20/2+1-2-1", val: 8.0},
@@ -178,6 +182,202 @@ func TestBuiltInFunc(t *testing.T) {
 		{expr: "sprintf('test string: %s,%v','a',1)", val: "test string: a,1"},
 		{expr: "sprintf('')+'a'", val: "a"},
 		{expr: "sprintf('%v',10+2*2)", val: "14"},
+
+		{expr: "iban('GB82 WEST 1234 5698 7654 32')", val: true},
+		{expr: "iban('DE89370400440532013000')", val: true},
+		{expr: "iban('GB82WEST12345698765433')", val: false},
+		{expr: "iban('XX82WEST12345698765432')", val: false},
+		{expr: "!iban('DE8937040044053201300')", val: true},
+		{expr: "iban(1)", val: nil},
+		{expr: "bic('DEUTDEFF')", val: true},
+		{expr: "bic('DEUTDEFF500')", val: true},
+		{expr: "bic('DEUT')", val: false},
+		{expr: "ccbrand('4111 1111 1111 1111')=='visa'", val: true},
+		{expr: "ccbrand('5555555555554444')", val: "mastercard"},
+		{expr: "ccbrand('378282246310005')", val: "amex"},
+		{expr: "money('10.00','USD')>=money('10','USD')", val: true},
+		{expr: "money('10.01','USD')>money('10.00','USD')", val: true},
+		{expr: "money(0.3,'USD')==money('0.30','USD')", val: true},
+		{expr: "money('9007199254740993','JPY')>9007199254740992", val: true},
+		{expr: "money('10.001','USD')", val: nil},
+		{expr: "money('10.5','JPY')", val: nil},
+		{expr: "money('1.234','KWD')==1.234", val: true},
+		{expr: "money('1e3','USD')", val: nil},
+		{expr: "money('10','XXX')", val: nil},
+		{expr: "money('10')", val: nil},
+		{expr: "ccbrand('6011111111111117')", val: "discover"},
+		{expr: "ccbrand('3530111333300000')", val: "jcb"},
+		{expr: "ccbrand('30569309025904')", val: "dinersclub"},
+		{expr: "ccbrand('6200000000000005')", val: "unionpay"},
+		{expr: "ccbrand('6759649826438453')", val: "maestro"},
+		{expr: "ccbrand('4111111111111112')", val: ""},
+
+		{expr: "phone('+1 (212) 555-0123')", val: true},
+		{expr: "phone('+8613800138000')", val: true},
+		{expr: "phone('13800138000')", val: false},
+		{expr: "phone('+999123')", val: false},
+		{expr: "phone('(212) 555-0123','US')", val: true},
+		{expr: "phone('1-212-555-0123','us')", val: true},
+		{expr: "phone('012-555-0123','US')", val: false},
+		{expr: "phone('+44 7911 123456','US')", val: false},
+		{expr: "phone('07911 123456','GB')", val: true},
+		{expr: "phone('+44 7911 123456','GB')", val: true},
+		{expr: "phone('0044 7911 123456','GB')", val: true},
+		{expr: "phone('07911 123456','XX')", val: false},
+		{expr: "phone('0791a123456','GB')", val: false},
+		{expr: "phone(13800138000)", val: nil},
+
+		{expr: "iso3166('CN')", val: true},
+		{expr: "iso3166('cn')", val: false},
+		{expr: "iso3166('XX')", val: false},
+		{expr: "iso4217('USD')", val: true},
+		{expr: "iso4217('XYZ')", val: false},
+		{expr: "bcp47('en')", val: true},
+		{expr: "bcp47('en-US')", val: true},
+		{expr: "bcp47('zh-Hans-CN')", val: true},
+		{expr: "bcp47('es-419')", val: true},
+		{expr: "bcp47('de-DE-1996')", val: true},
+		{expr: "bcp47('en-US-u-ca-gregory')", val: true},
+		{expr: "bcp47('en-US-x-twain')", val: true},
+		{expr: "bcp47('qq-US')", val: false},
+		{expr: "bcp47('en-US-')", val: false},
+		{expr: "bcp47('en_US')", val: false},
+		{expr: "tz('Asia/Shanghai')", val: true},
+		{expr: "tz('UTC')", val: true},
+		{expr: "tz('Mars/Olympus')", val: false},
+		{expr: "tz(8)", val: nil},
+
+		{expr: "latlng(39.9042, 116.4074)", val: true},
+		{expr: "latlng(-90, -180)", val: true},
+		{expr: "latlng(90.5, 0)", val: false},
+		{expr: "latlng(0, 181)", val: false},
+		{expr: "latlng('0', 0)", val: nil},
+		{expr: "within_bbox(40, 116, 39, 115, 41, 117)", val: true},
+		{expr: "within_bbox(42, 116, 39, 115, 41, 117)", val: false},
+		{expr: "within_bbox(-17, 179.5, -20, 177, -15, -178)", val: true},
+		{expr: "within_bbox(-17, 170, -20, 177, -15, -178)", val: false},
+		{expr: "within_bbox(40, 116, 39, 115)", val: nil},
+		{expr: "distance(48.8566, 2.3522, 51.5074, -0.1278)>340 && distance(48.8566, 2.3522, 51.5074, -0.1278)<347", val: true},
+		{expr: "distance(10, 20, 10, 20)", val: 0.0},
+		{expr: "distance(91, 0, 0, 0)", val: nil},
+		{expr: "safepath('uploads/2019/a.png')", val: true},
+		{expr: "safepath('a/../../etc/passwd')", val: false},
+		{expr: "safepath('..\\\\windows')", val: false},
+		{expr: "safepath('/etc/passwd')", val: false},
+		{expr: "safepath('C:/boot.ini')", val: false},
+		{expr: "safepath('a..b/c')", val: true},
+		{expr: "safefilename('report 2019.pdf')", val: true},
+		{expr: "safefilename('a/b.pdf')", val: false},
+		{expr: "safefilename('CON.txt')", val: false},
+		{expr: "safefilename('a.pdf.')", val: false},
+		{expr: "safefilename('..')", val: false},
+		{expr: "ext('a/Report.PDF')", val: "pdf"},
+		{expr: "ext('archive.tar.gz')", val: "gz"},
+		{expr: "ext('a.d/README')", val: ""},
+		{expr: "ext('a.JPG', 'png', '.jpg')", val: true},
+		{expr: "ext('a.exe', 'png', 'jpg')", val: false},
+		{expr: "ext(1)", val: nil},
+		{expr: "mime('Text/HTML; charset=utf-8')", val: "text/html"},
+		{expr: "mime('text')", val: ""},
+		{expr: "mime('image/png', 'image/png', 'image/jpeg')", val: true},
+		{expr: "mime('image/webp', 'image/*')", val: true},
+		{expr: "mime('application/pdf', 'image/*', 'text/plain')", val: false},
+		{expr: "mime('bad', '*/*')", val: false},
+		{expr: "mime('image/png', 1)", val: nil},
+		{expr: "datauri('data:image/png;base64,iVBORw0KGgo=')", val: true},
+		{expr: "datauri('data:,Hello%2C%20World')", val: true},
+		{expr: "datauri('data:text/plain;base64,!!')", val: false},
+		{expr: "datauri('http://a.com/a.png')", val: false},
+		{expr: "datauri('data:image/png;base64,iVBORw0KGgo=', 'image/png', 'image/jpeg')", val: true},
+		{expr: "datauri('data:;charset=utf-8,a', 'image/*')", val: false},
+		{expr: "hexcolor('#fff')", val: true},
+		{expr: "hexcolor('#FF8800cc')", val: true},
+		{expr: "hexcolor('#ff88')", val: true},
+		{expr: "hexcolor('ff8800')", val: false},
+		{expr: "hexcolor('#ff880')", val: false},
+		{expr: "hexcolor('#gg8800')", val: false},
+		{expr: "rgb('rgb(255, 0, 0)')", val: true},
+		{expr: "rgb('RGB(100%,0%,50.5%)')", val: true},
+		{expr: "rgb('rgb(256, 0, 0)')", val: false},
+		{expr: "rgb('rgb(255, 0%, 0)')", val: false},
+		{expr: "rgb('rgb(255, 0)')", val: false},
+		{expr: "rgba('rgba(255, 0, 0, 0.5)')", val: true},
+		{expr: "rgba('rgba(0%, 0%, 0%, 50%)')", val: true},
+		{expr: "rgba('rgba(255, 0, 0, 1.5)')", val: false},
+		{expr: "rgba('rgb(255, 0, 0)')", val: false},
+		{expr: "rgb(1)", val: nil},
+		{expr: "hostname('localhost')", val: true},
+		{expr: "hostname('api-1.example.com.')", val: true},
+		{expr: "hostname('-api.example.com')", val: false},
+		{expr: "hostname('api_1.example.com')", val: false},
+		{expr: "hostname('a..com')", val: false},
+		{expr: "fqdn('api.example.com')", val: true},
+		{expr: "fqdn('example.com.')", val: true},
+		{expr: "fqdn('localhost')", val: false},
+		{expr: "fqdn('10.0.0.1')", val: false},
+		{expr: "port(8080)", val: true},
+		{expr: "port('443')", val: true},
+		{expr: "port(0)", val: false},
+		{expr: "port(65536)", val: false},
+		{expr: "port(80.5)", val: false},
+		{expr: "port('+80')", val: false},
+		{expr: "port(nil)", val: nil},
+		{expr: "semver('1.2.3')", val: true},
+		{expr: "semver('v1.2.3-rc.1+build.5')", val: true},
+		{expr: "semver('1.2')", val: false},
+		{expr: "semver('01.2.3')", val: false},
+		{expr: "semver('1.2.3-01')", val: false},
+		{expr: "semver_gte('1.2.0','1.2.0')", val: true},
+		{expr: "semver_gte('1.10.0','1.9.9')", val: true},
+		{expr: "semver_gte('1.2.0-rc.1','1.2.0')", val: false},
+		{expr: "semver_gte('1.0.0-alpha.beta','1.0.0-alpha.1')", val: true},
+		{expr: "semver_gte('1.0.0-alpha','1.0.0-alpha.1')", val: false},
+		{expr: "semver_gte('1.0.0-rc.11','1.0.0-rc.2')", val: true},
+		{expr: "semver_gte('x','1.0.0')", val: nil},
+
+		{expr: "isbase64('aGVsbG8=')", val: true},
+		{expr: "isbase64('aGVsbG8')", val: true},
+		{expr: "isbase64('-_-_')", val: true},
+		{expr: "isbase64('a*b')", val: false},
+		{expr: "isbase64('')", val: false},
+		{expr: "ishex('0aFF')", val: true},
+		{expr: "ishex('0aF')", val: false},
+		{expr: "ishex('xy')", val: false},
+		{expr: "isjson('{\"a\":[1,2]}')", val: true},
+		{expr: "isjson('{a:1}')", val: false},
+		{expr: "isjson(1)", val: nil},
+
+		{expr: "pwstrength('abc')", val: 0.0},
+		{expr: "pwstrength('Password1')", val: 0.0},
+		{expr: "pwstrength('Sunrise42')", val: 2.0},
+		{expr: "pwstrength('letmein1')", val: 1.0},
+		{expr: "pwstrength('P@ssw0rd')", val: 0.0},
+		{expr: "pwstrength('Tr0ub4dor&3x')", val: 4.0},
+		{expr: "pwstrength('correct horse battery')>=2", val: true},
+
+		{expr: "split('a,b,c',',')", val: []interface{}{"a", "b", "c"}},
+		{expr: "len(split('a,b,c',','))", val: 3.0},
+		{expr: "split('',',')", val: []interface{}{}},
+		{expr: "split(1,',')", val: nil},
+		{expr: "join(split('a,b,c',','),'-')", val: "a-b-c"},
+		{expr: "join('abc','-')", val: nil},
+		{expr: "replace('a1b22','\\d+','#')", val: "a#b#"},
+		{expr: "replace(' A ','^\\s+|\\s+$','')=='A'", val: true},
+		{expr: "replace('a','a**','')", val: nil},
+
+		{expr: "jsonpath('{\"user\":{\"age\":18}}','$.user.age')>=18", val: true},
+		{expr: "jsonpath('{\"items\":[{\"first name\":\"a\"},{}]}','$.items[0][\"first name\"]')", val: "a"},
+		{expr: "jsonpath('[1,2,3]','$[-1]')", val: 3.0},
+		{expr: "jsonpath('[1,2,3]','$[3]')", val: nil},
+		{expr: "len(jsonpath('{\"a\":[1,2]}','$.a'))", val: 2.0},
+		{expr: "jsonpath('{\"a\":1}','$.b.c')", val: nil},
+		{expr: "jsonpath('{\"a\":1}','a')", val: nil},
+		{expr: "jsonpath('{a}','$.a')", val: nil},
+
+		{expr: "md5('abc')", val: "900150983cd24fb0d6963f7d28e17f72"},
+		{expr: "sha1('abc')", val: "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{expr: "sha256('abc')=='ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad'", val: true},
+		{expr: "sha256(1)", val: nil},
 	}
 	for _, c := range cases {
 		t.Log(c.expr)
@@ -195,6 +395,237 @@ func TestBuiltInFunc(t *testing.T) {
 	}
 }
 
+func TestRegFunc(t *testing.T) {
+	add1 := func(args ...interface{}) interface{} {
+		f, _ := args[0].(float64)
+		return f + 1
+	}
+	if err := RegFunc("add1", add1); err != nil {
+		t.Fatal(err)
+	}
+	if RegFunc("add1", add1) == nil {
+		t.Fatal("want duplicate registration error")
+	}
+	if RegFunc("add-1", add1) == nil {
+		t.Fatal("want invalid name error")
+	}
+	if RegFunc("add1", nil, true) == nil {
+		t.Fatal("want nil function error")
+	}
+	vm, err := parseExpr("add1(1)*2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val := vm.run("", nil); val != 4.0 {
+		t.Fatalf("got: %v, want: %v", val, 4.0)
+	}
+}
+
+type zonePlugin map[string][4]float64
+
+func (z zonePlugin) Parse(src string) (int, []string, interface{}, error) {
+	if !strings.HasPrefix(src, "within(") {
+		return 0, nil, nil, nil
+	}
+	end := strings.Index(src, "')")
+	if end < 0 {
+		return 0, nil, nil, errors.New("missing the zone")
+	}
+	a := strings.SplitN(src[len("within("):end], " in '", 2)
+	box, ok := z[a[len(a)-1]]
+	if len(a) != 2 || !ok {
+		return 0, nil, nil, fmt.Errorf("unknown zone: %s", a[len(a)-1])
+	}
+	args := strings.SplitN(a[0], ",", 2)
+	if len(args) != 2 {
+		return 0, nil, nil, errors.New("want the latitude and the longitude")
+	}
+	return end + 2, args, box, nil
+}
+
+func (z zonePlugin) Eval(data interface{}, args []interface{}) interface{} {
+	box := data.([4]float64)
+	lat, _ := args[0].(float64)
+	lng, _ := args[1].(float64)
+	return lat >= box[0] && lat <= box[1] && lng >= box[2] && lng <= box[3]
+}
+
+func TestRegPlugin(t *testing.T) {
+	zones := zonePlugin{"zone-a": {0, 1, 0, 2}}
+	if err := RegPlugin("within", zones); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { plugins = nil }()
+	if RegPlugin("within", zones) == nil {
+		t.Fatal("want duplicate registration error")
+	}
+	var cases = []struct {
+		expr string
+		val  interface{}
+	}{
+		{expr: "within(0.5, 1+1 in 'zone-a')", val: true},
+		{expr: "within(0.5, 3 in 'zone-a') || len('ab')==2", val: true},
+		{expr: "!within(2-0.5,1 in 'zone-a') && 1<2", val: true},
+		{expr: "within(1.5, 1 in 'zone-a')", val: false},
+	}
+	for _, c := range cases {
+		vm, err := parseExpr(c.expr)
+		if err != nil {
+			t.Fatal(c.expr, err)
+		}
+		if val := vm.run("", nil); val != c.val {
+			t.Fatalf("%s: got: %v, want: %v", c.expr, val, c.val)
+		}
+	}
+	vm, _ := parseExpr("within(0.5, 1+1 in 'zone-a')")
+	if s := vm.String(); s != "within(0.5, 1+1 in 'zone-a')" {
+		t.Fatalf("got: %s", s)
+	}
+	if n := vm.AST(); n.Kind != FuncNode || n.Name != "within" || len(n.Args) != 2 {
+		t.Fatalf("got: %+v", n)
+	}
+	for _, expr := range []string{"within(1, 1 in 'zone-b')", "within(1 in 'zone-a')", "within(1, 1) in 'zone-a')"} {
+		if _, err := parseExpr(expr); !errors.Is(err, ErrSyntax) {
+			t.Fatalf("%s: want syntax error, got %v", expr, err)
+		}
+	}
+}
+
+func TestConstFold(t *testing.T) {
+	var cases = []struct {
+		expr   string
+		folded string
+		val    interface{}
+	}{
+		{expr: "60*60", folded: "3600", val: 3600.0},
+		{expr: "$>10*60", folded: "$ > 600"},
+		{expr: "($+1)*(2+3)", folded: "($ + 1) * 5"},
+		{expr: "len('abc')<=$", folded: "3 <= $"},
+		{expr: "sprintf('%v-%v',1,2)==$", folded: "'1-2' == $"},
+		{expr: "iban('DE89 3704 0044 0532 0130 00')&&$", folded: "true && $"},
+		{expr: "false&&$", folded: "false", val: false},
+		{expr: "true||$", folded: "true", val: true},
+		{expr: "true&&$", folded: "true && $"},
+		{expr: "$[1+1]==nil", folded: "$[2] == nil"},
+		{expr: "count($,#v>1+1)", folded: "count($,#v > 2)"},
+		{expr: "pwstrength('abc')==0", folded: "pwstrength('abc') == 0"},
+		{expr: "1/0", folded: "1 / 0"},
+	}
+	for _, c := range cases {
+		p, err := parseExpr(c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.String(); got != c.folded {
+			t.Fatalf("expr: %q, got: %q, want: %q", c.expr, got, c.folded)
+		}
+		if c.val != nil {
+			if val := p.run("", nil); !reflect.DeepEqual(c.val, val) {
+				t.Fatalf("expr: %q, got: %v, want: %v", c.expr, val, c.val)
+			}
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	var cases = []struct {
+		expr   string
+		simple string
+		val    interface{}
+	}{
+		{expr: `'it\'s'`, simple: `'it\'s'`, val: "it's"},
+		{expr: `'C:\\'`, simple: `'C:\\'`, val: `C:\`},
+		{expr: `'\d+'`, simple: `'\\d+'`, val: `\d+`},
+		{expr: `'a\\\'b'`, simple: `'a\\\'b'`, val: `a\'b`},
+		{expr: `sprintf('%v\'s','it')`, simple: `'it\'s'`, val: "it's"},
+		{expr: `sprintf('%v',$)=='\'\\'`, simple: `sprintf('%v',$) == '\'\\'`},
+	}
+	for _, c := range cases {
+		p, err := parseExpr(c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := p.String()
+		if s != c.simple {
+			t.Fatalf("expr: %q, got: %q, want: %q", c.expr, s, c.simple)
+		}
+		p2, err := parseExpr(s)
+		if err != nil {
+			t.Fatalf("expr: %q, reparse %q: %v", c.expr, s, err)
+		}
+		if s2 := p2.String(); s2 != s {
+			t.Fatalf("expr: %q, reparsed: %q, want: %q", c.expr, s2, s)
+		}
+		if c.val != nil {
+			if val, val2 := p.run("", nil), p2.run("", nil); val != c.val || val2 != c.val {
+				t.Fatalf("expr: %q, got: %q and %q reparsed, want: %q", c.expr, val, val2, c.val)
+			}
+		}
+	}
+}
+
+func TestCostOrder(t *testing.T) {
+	var cases = []struct {
+		expr      string
+		reordered string
+	}{
+		{expr: "regexp('a')&&$>0&&iban($)", reordered: "$ > 0 && iban($) && regexp('a',$)"},
+		{expr: "len($)>1||$==''", reordered: "$ == '' || len($) > 1"},
+		{expr: "(regexp('a')||$)&&$", reordered: "$ && ($ || regexp('a',$))"},
+		{expr: "regexp('a')&&$||$", reordered: "$ || $ && regexp('a',$)"},
+		{expr: "regexp('a')&&@X@calc&&iban($)&&$>0", reordered: "regexp('a',$) && @X@calc && $ > 0 && iban($)"},
+		{expr: "regexp('a')&&$>0&&touch($)&&len($)>1&&$", reordered: "$ > 0 && regexp('a',$) && touch($) && $ && len($) > 1"},
+		{expr: "regexp('a')||!touch($)||$", reordered: "regexp('a',$) || !touch($) || $"},
+		{expr: "regexp('a')&&@Y&&iban($)&&$>0", reordered: "regexp('a',$) && @Y && $ > 0 && iban($)"},
+	}
+	err := RegFunc("touch", func(args ...interface{}) interface{} { return true }, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := New("").SetCostOrder(true)
+	for _, c := range cases {
+		p, err := parseVMExpr(vm, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.String(); got != c.reordered {
+			t.Fatalf("expr: %q, got: %q, want: %q", c.expr, got, c.reordered)
+		}
+	}
+	p, err := parseExpr("regexp('a')&&$>0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.String(); got != "regexp('a',$) && $ > 0" {
+		t.Fatalf("got: %q", got)
+	}
+	if p.Cost() != 14 {
+		t.Fatalf("got: %d, want: 14", p.Cost())
+	}
+}
+
+func TestAST(t *testing.T) {
+	p, err := parseExpr("!(A)$['x']&&regexp('^a',$)||len(sprintf('%d',1+2))>1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	or := p.AST()
+	if or.Kind != OperatorNode || or.Op != "||" {
+		t.Fatalf("got: %+v", or)
+	}
+	and, gt := or.Args[0], or.Args[1]
+	if and.Op != "&&" || gt.Kind != LiteralNode || gt.Value != true {
+		t.Fatalf("got: %+v, %+v", and, gt)
+	}
+	sel, re := and.Args[0], and.Args[1]
+	if sel.Kind != SelectorNode || sel.Name != "A" || sel.Prefix != "!" || len(sel.Args) != 1 || sel.Args[0].Value != "x" {
+		t.Fatalf("got: %+v", sel)
+	}
+	if re.Kind != FuncNode || re.Name != "regexp" || re.Args[0].Value != "^a" || re.Args[1].Kind != SelectorNode {
+		t.Fatalf("got: %+v", re)
+	}
+}
+
 func TestSyntaxIncorrect(t *testing.T) {
 	var cases = []struct {
 		incorrectExpr string
@@ -208,6 +639,11 @@ func TestSyntaxIncorrect(t *testing.T) {
 		{incorrectExpr: "sprintf()"},
 		{incorrectExpr: "sprintf(0)"},
 		{incorrectExpr: "sprintf('a'+'b')"},
+		{incorrectExpr: "iban("},
+		{incorrectExpr: "iban('a' 'b')"},
+		{incorrectExpr: "unknownfn('a')"},
+		{incorrectExpr: "count()"},
+		{incorrectExpr: "any($,#v,1)"},
 	}
 	for _, c := range cases {
 		_, err := parseExpr(c.incorrectExpr)
@@ -218,3 +654,60 @@ func TestSyntaxIncorrect(t *testing.T) {
 		}
 	}
 }
+
+func TestParseLimits(t *testing.T) {
+	deep := strings.Repeat("(", 40) + "1" + strings.Repeat(")", 40)
+	long := strings.Repeat("1+", 3000) + "1"
+	var cases = []struct {
+		limits ParseLimits
+		expr   string
+		reason string
+	}{
+		{ParseLimits{}, deep, "nested too deep, the limit is 32"},
+		{ParseLimits{MaxDepth: -1}, deep, ""},
+		{ParseLimits{}, long, "expression too long, the limit is 4096 bytes"},
+		{ParseLimits{MaxLength: -1}, long, "too many tokens, the limit is 1024"},
+		{ParseLimits{MaxLength: -1, MaxTokens: 4000}, long, ""},
+		{ParseLimits{MaxDepth: 2}, "len(len(len($)))", "nested too deep, the limit is 2"},
+		{ParseLimits{MaxDepth: 2}, "(A)$[(B)$[(C)$]]", ""},
+		{ParseLimits{MaxTokens: 3}, "1+2+3+4", "too many tokens, the limit is 3"},
+	}
+	for _, c := range cases {
+		_, err := New("").SetParseLimits(c.limits).ParseExpr(c.expr)
+		if c.reason == "" {
+			if err != nil {
+				t.Fatalf("%.20s: %v", c.expr, err)
+			}
+			continue
+		}
+		serr, ok := err.(*SyntaxError)
+		if !ok || serr.Reason != c.reason {
+			t.Fatalf("%.20s: got %v, want %s", c.expr, err, c.reason)
+		}
+	}
+}
+
+func TestSyntaxErrorOffset(t *testing.T) {
+	var cases = []struct {
+		expr   string
+		offset int
+	}{
+		{"1 + + 'a'", 4},
+		{"$>0 && ($ >> 1)", 11},
+		{"len", 0},
+		{"iban(", 0},
+		{"1&&", 3},
+		{"$ > 0 ||  ", 10},
+		{"bool: $ >> 1", 9},
+	}
+	for _, c := range cases {
+		_, err := New("").ParseExpr(c.expr)
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Fatalf("%s: want *SyntaxError, got: %v", c.expr, err)
+		}
+		if serr.Offset != c.offset {
+			t.Fatalf("%s: offset: got %d, want %d", c.expr, serr.Offset, c.offset)
+		}
+	}
+}
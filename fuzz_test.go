@@ -0,0 +1,80 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package tagexpr
+
+import (
+	"testing"
+)
+
+// FuzzParseExpr parses and evaluates the arbitrary expressions, run it by:
+//  go test -run XXX -fuzz FuzzParseExpr
+func FuzzParseExpr(f *testing.F) {
+	for _, seed := range []string{
+		"$>0&&len((B)$)<=8",
+		"!($=='')||regexp('^\\\\w+$',(B)$)",
+		"sprintf('%d-%v',(A)$,(C)$[0])",
+		"any((C)$,#v>1)&&count((D)$)==2",
+		"mod((A)$,3)+1.5*-2",
+		"(D)$['k']!=nil",
+		"in((B)$,'a','b')",
+	} {
+		f.Add(seed)
+	}
+	type T struct {
+		A int
+		B string
+		C []int
+		D map[string]int
+	}
+	vm := New("fuzz")
+	f.Fuzz(func(t *testing.T, expr string) {
+		te, err := vm.Run(&T{A: 1, B: "b", C: []int{1, 2}, D: map[string]int{"k": 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := vm.ParseExpr(expr)
+		if err != nil {
+			if _, ok := err.(*SyntaxError); !ok {
+				t.Fatalf("%q: want *SyntaxError, got %T", expr, err)
+			}
+			return
+		}
+		_ = e.String()
+		e.AST()
+		e.Cost()
+		e.run("A", te)
+	})
+}
+
+// FuzzStructTag registers the arbitrary struct tags, run it by:
+//  go test -run XXX -fuzz FuzzStructTag
+func FuzzStructTag(f *testing.F) {
+	for _, seed := range []string{
+		"$>0",
+		"{@:$>0}{msg:sprintf('%v',$)}",
+		"{@:len($)>1}{priority:10}",
+		"{x:(",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, tag string) {
+		field := &Field{host: New("fuzz").newStruct()}
+		field.Name = "A"
+		field.parseExprs(tag)
+	})
+}
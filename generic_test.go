@@ -0,0 +1,100 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package tagexpr
+
+import (
+	"reflect"
+	"testing"
+)
+
+type genericUser struct {
+	Name string `tagexpr:"len($)>0"`
+}
+
+type genericOrder struct {
+	ID int `tagexpr:"$>0"`
+}
+
+type genericPage[T any] struct {
+	Items []T `tagexpr:"len($)<=2"`
+	Item  T
+	Next  *genericPage[T]
+}
+
+func TestGenericStruct(t *testing.T) {
+	vm := New("tagexpr")
+	users := &genericPage[genericUser]{
+		Items: []genericUser{{"a"}},
+		Item:  genericUser{""},
+		Next:  &genericPage[genericUser]{Items: []genericUser{{"b"}, {"c"}, {"d"}}},
+	}
+	orders := &genericPage[genericOrder]{Item: genericOrder{ID: 1}}
+	te, err := vm.Run(users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"Items@":      true,
+		"Item.Name@":  false,
+		"Next.Items@": false,
+		"Item.ID@":    nil,
+	}
+	for selector, r := range want {
+		if got := te.Eval(selector); got != r {
+			t.Fatalf("%T: %s: got %v, want %v", users, selector, got, r)
+		}
+	}
+	te, err = vm.Run(orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if te.Eval("Item.ID@") != true || te.Eval("Item.Name@") != nil {
+		t.Fatalf("%T: got %v", orders, te.EvalGlob("*"))
+	}
+	s1, _ := vm.Struct(reflect.TypeOf(users))
+	s2, _ := vm.Struct(reflect.TypeOf(orders))
+	if s1 == s2 || s1.Name() == s2.Name() {
+		t.Fatalf("got the same struct: %s, %s", s1.Name(), s2.Name())
+	}
+}
+
+func TestLocalTypesOfSameName(t *testing.T) {
+	vm := New("tagexpr")
+	run := func(v interface{}) interface{} {
+		te, err := vm.Run(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return te.Eval("A@")
+	}
+	a := func() interface{} {
+		type T struct {
+			A int `tagexpr:"$>0"`
+		}
+		return &T{A: 1}
+	}()
+	b := func() interface{} {
+		type T struct {
+			A string `tagexpr:"$=='b'"`
+		}
+		return &T{A: "b"}
+	}()
+	if run(a) != true || run(b) != true {
+		t.Fatalf("got %v, %v", run(a), run(b))
+	}
+}
@@ -0,0 +1,50 @@
+package graphql_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+	"github.com/bytedance/go-tagexpr/graphql"
+	"github.com/bytedance/go-tagexpr/validator"
+)
+
+func Example() {
+	// generated by gqlgen, with the vd tags added by the model hook
+	type AddressInput struct {
+		City string `json:"city" vd:"{@:len($)>0}{msg:'city is required'}{code:'CITY_REQUIRED'}"`
+	}
+	type Profile struct {
+		Bio string `json:"bio" vd:"{@:len($)<=8}{msg:'bio is too long'}{severity:'warning'}"`
+	}
+	type NewUser struct {
+		Profile
+		Name    string        `json:"name" vd:"{@:len($)>=2}{msg:'name is too short'}"`
+		Email   string        `json:"email" vd:"regexp('^\\S+@\\S+$')"`
+		Address *AddressInput `json:"address"`
+	}
+	gv := graphql.New(validator.New("vd")).SetAllErrors(true)
+	path := []interface{}{"createUser"}
+
+	errs, _ := gv.Validate(path, "input", &NewUser{Profile: Profile{Bio: "gopher since 2012"}, Name: "a", Email: "a@b.com", Address: &AddressInput{}})
+	for _, e := range errs {
+		b, _ := json.Marshal(e)
+		var verr *validator.Error
+		errors.As(e, &verr)
+		fmt.Println(string(b), verr.FieldSelector)
+	}
+	fmt.Println(errors.Is(errs[0], tagexpr.ErrValidation))
+
+	errs, _ = gv.Validate(path, "input", &NewUser{Name: "ab", Email: "a@b.com", Address: &AddressInput{City: "x"}})
+	fmt.Println(len(errs))
+	fmt.Println(gv.FieldPath(reflect.TypeOf(&NewUser{}), "Profile.Bio"))
+
+	// Output:
+	// {"message":"name is too short","path":["createUser"],"extensions":{"code":"BAD_USER_INPUT","field":"input.name"}} Name
+	// {"message":"city is required","path":["createUser"],"extensions":{"code":"BAD_USER_INPUT","field":"input.address.city","rule":"CITY_REQUIRED"}} Address.City
+	// true
+	// 0
+	// bio
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package graphql validates the input structs of the gqlgen-style resolvers by the struct tag expressions,
+// and maps the failures to the GraphQL errors with the input field paths in the extensions,
+// such as {"message":"...","path":["createUser"],"extensions":{"code":"BAD_USER_INPUT","field":"input.address.city"}}.
+package graphql
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/bytedance/go-tagexpr/validator"
+)
+
+// DefaultCode the code in the extensions of the validation errors
+const DefaultCode = "BAD_USER_INPUT"
+
+// Error the GraphQL error of the spec, which converts to *gqlerror.Error field by field
+type Error struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"` // the response path of the resolver, such as ["createUser"]
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	Err        error                  `json:"-"` // the validation error
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the validation error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Validator the input validator
+type Validator struct {
+	vd        *validator.Validator
+	code      string
+	allErrors bool
+}
+
+// New creates an input validator that validates by vd.
+func New(vd *validator.Validator) *Validator {
+	return &Validator{vd: vd, code: DefaultCode}
+}
+
+// SetCode sets the code in the extensions, the default is BAD_USER_INPUT.
+func (v *Validator) SetCode(code string) *Validator {
+	v.code = code
+	return v
+}
+
+// SetAllErrors sets whether to return the errors of all the failed rules, the default is false, only the first one.
+// NOTE:
+//  The other failed rules are found by validator.Report, whose messages ignore the error factory;
+//  The failed warning rules, such as {severity:'warning'}, are not returned.
+func (v *Validator) SetAllErrors(all bool) *Validator {
+	v.allErrors = all
+	return v
+}
+
+// Validate validates the input struct pointer of the resolver argument argName, such as input,
+// and returns the GraphQL errors of the failed rules, nil if it is valid.
+// NOTE:
+//  path is the response path of the resolver, such as graphql.GetFieldContext(ctx).Path() of gqlgen;
+//  The extensions have the code, the input field path such as input.address.city by the json tags,
+//  and the rule code of {code:'...'} as rule if it is set;
+//  The error is returned as it is if the validation fails not for a rule, such as the evaluation panic.
+func (v *Validator) Validate(path []interface{}, argName string, input interface{}) ([]*Error, error) {
+	err := v.vd.Validate(input)
+	if err == nil {
+		return nil, nil
+	}
+	var verr *validator.Error
+	if !errors.As(err, &verr) {
+		return nil, err
+	}
+	if !v.allErrors {
+		return []*Error{v.newError(path, argName, input, verr.FieldSelector, verr.Code, err)}, nil
+	}
+	results, rerr := v.vd.Report(input, validator.Options{})
+	if rerr != nil {
+		return nil, rerr
+	}
+	var errs []*Error
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			return nil, r.Err
+		case r.Pass || r.Severity == validator.SeverityWarning:
+			continue
+		}
+		rerr := &validator.Error{FieldSelector: r.FieldSelector, Msg: r.Msg, Code: r.Code, Rule: r.Rule, Value: r.Value}
+		errs = append(errs, v.newError(path, argName, input, r.FieldSelector, r.Code, rerr))
+	}
+	return errs, nil
+}
+
+func (v *Validator) newError(path []interface{}, argName string, input interface{}, fieldSelector, code string, err error) *Error {
+	field := v.FieldPath(reflect.TypeOf(input), fieldSelector)
+	if argName != "" {
+		field = argName + "." + field
+	}
+	ext := map[string]interface{}{"code": v.code, "field": field}
+	if code != "" {
+		ext["rule"] = code
+	}
+	return &Error{Message: err.Error(), Path: path, Extensions: ext, Err: err}
+}
+
+// FieldPath returns the GraphQL input field path of the field selector by the json tags, such as Address.City to address.city.
+// NOTE:
+//  The path is the json names path of tagexpr.Struct.FieldPath, like the JSON encoder;
+//  The field selector is returned as it is if the field is not found or not encoded by the JSON encoder.
+func (v *Validator) FieldPath(structType reflect.Type, fieldSelector string) string {
+	s, err := v.vd.VM().Struct(structType)
+	if err != nil {
+		return fieldSelector
+	}
+	if path, ok := s.FieldPath(fieldSelector, "json"); ok && path != "" {
+		return path
+	}
+	return fieldSelector
+}
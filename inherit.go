@@ -0,0 +1,97 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"strings"
+)
+
+const inheritExprName = "inherit"
+
+// overrideEmbedded removes the expressions of the promoted fields of the embedded structs,
+// which are re-declared by the fields of the same names of the struct.
+// NOTE:
+//  The child wins by the expression name, such as Name@ of the child overrides Base.Name@,
+//  while Base.Name@msg is kept if the child has no Name@msg;
+//  With {inherit:false}, none of the expressions of the promoted field is kept.
+func (s *Struct) overrideEmbedded() {
+	var removed map[*exprEntry]bool
+	for _, e := range s.exprList {
+		name, ok := s.promotedName(e.field)
+		if !ok {
+			continue
+		}
+		if _, ok = s.exprs[name+e.selector[len(e.field):]]; !ok && !s.disinherits(name) {
+			continue
+		}
+		if removed == nil {
+			removed = make(map[*exprEntry]bool)
+		}
+		removed[e] = true
+		delete(s.exprs, e.selector)
+	}
+	if removed == nil {
+		return
+	}
+	filter := func(list []*exprEntry) []*exprEntry {
+		kept := list[:0]
+		for _, e := range list {
+			if !removed[e] {
+				kept = append(kept, e)
+			}
+		}
+		return kept
+	}
+	s.exprList = filter(s.exprList)
+	s.sanitizers = filter(s.sanitizers)
+	s.calcs = filter(s.calcs)
+	s.redactions = filter(s.redactions)
+	s.compares = filter(s.compares)
+}
+
+// promotedName returns the name of the promoted field, such as Name for Base.Name,
+// if all the fields of the path are embedded and the struct declares the field of the name.
+func (s *Struct) promotedName(field string) (string, bool) {
+	i := strings.LastIndexByte(field, '.')
+	if i < 0 {
+		return "", false
+	}
+	for j := 0; j < i; j++ {
+		if field[j] != '.' {
+			continue
+		}
+		if f, ok := s.fields[field[:j]]; !ok || !f.Anonymous {
+			return "", false
+		}
+	}
+	if f, ok := s.fields[field[:i]]; !ok || !f.Anonymous {
+		return "", false
+	}
+	name := field[i+1:]
+	if _, ok := s.fields[name]; !ok {
+		return "", false
+	}
+	return name, true
+}
+
+// disinherits reports whether the field is tagged {inherit:false}.
+func (s *Struct) disinherits(field string) bool {
+	e, ok := s.exprs[field+"@"+inheritExprName]
+	if !ok {
+		return false
+	}
+	node := e.expr.AST()
+	return node != nil && node.Kind == LiteralNode && node.Value == false
+}
@@ -0,0 +1,59 @@
+package kubebuilder_test
+
+import (
+	"fmt"
+
+	"github.com/bytedance/go-tagexpr/kubebuilder"
+)
+
+func Example() {
+	type Spec struct {
+		Replicas int32             `json:"replicas" vd:"$>=1&&$<=10"`
+		Image    string            `json:"image" vd:"$!=''&&regexp('^[a-z0-9./-]+(:[\\w.-]+)?$')"`
+		Policy   string            `json:"policy" vd:"$=='Always'||$=='Never'"`
+		Ports    []int32           `json:"ports" vd:"len($)<=8"`
+		Labels   map[string]string `json:"labels" vd:"len($)<=16"`
+	}
+	g := kubebuilder.New("vd")
+	markers, err := g.Markers(new(Spec))
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range []string{"Replicas", "Image", "Policy", "Ports", "Labels"} {
+		fmt.Println(f, markers[f])
+	}
+
+	src := `package v1
+
+type Spec struct {
+	// Replicas is the number of the pods.
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 ` + "`" + `json:"replicas" vd:"$>=1&&$<=10"` + "`" + `
+	// +optional
+	Ports []int32 ` + "`" + `json:"ports,omitempty" vd:"len($)<=8"` + "`" + `
+}
+`
+	out, err := g.Rewrite([]byte(src))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(string(out))
+
+	// Output:
+	// Replicas [+kubebuilder:validation:Minimum=1 +kubebuilder:validation:Maximum=10]
+	// Image [+kubebuilder:validation:Required +kubebuilder:validation:Pattern=`^[a-z0-9./-]+(:[\w.-]+)?$`]
+	// Policy [+kubebuilder:validation:Enum=Always;Never]
+	// Ports [+kubebuilder:validation:MaxItems=8]
+	// Labels [+kubebuilder:validation:MaxProperties=16]
+	// package v1
+	//
+	// type Spec struct {
+	// 	// Replicas is the number of the pods.
+	// 	// +kubebuilder:validation:Minimum=1
+	// 	// +kubebuilder:validation:Maximum=10
+	// 	Replicas int32 `json:"replicas" vd:"$>=1&&$<=10"`
+	// 	// +optional
+	// 	// +kubebuilder:validation:MaxItems=8
+	// 	Ports []int32 `json:"ports,omitempty" vd:"len($)<=8"`
+	// }
+}
@@ -0,0 +1,285 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kubebuilder generates the controller-gen/kubebuilder validation markers from the struct tag expressions,
+// so that the CRD schema and the runtime validation of the spec structs are kept aligned.
+package kubebuilder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/go-tagexpr/constraint"
+)
+
+// MarkerPrefix the prefix of the validation markers
+const MarkerPrefix = "+kubebuilder:validation:"
+
+// Generator validation marker generator
+type Generator struct {
+	tagName   string
+	extractor *constraint.Extractor
+}
+
+// New creates a validation marker generator that uses @tagName as the tag name.
+func New(tagName string) *Generator {
+	return &Generator{
+		tagName:   tagName,
+		extractor: constraint.New(tagName),
+	}
+}
+
+// Markers returns the validation markers of the struct fields,
+// the key is the field selector, such as A.B for the field B of the nested struct field A.
+func (g *Generator) Markers(structOrStructPtr interface{}) (map[string][]string, error) {
+	if structOrStructPtr == nil {
+		return nil, errors.New("cannot generate nil interface")
+	}
+	schema, err := g.extractor.Extract(structOrStructPtr)
+	if err != nil {
+		return nil, err
+	}
+	markers := make(map[string][]string)
+	addMarkers(markers, "", schema, map[reflect.Type]bool{})
+	return markers, nil
+}
+
+func addMarkers(markers map[string][]string, nameSpace string, schema *constraint.Schema, visited map[reflect.Type]bool) {
+	if visited[schema.Type] {
+		return
+	}
+	visited[schema.Type] = true
+	defer delete(visited, schema.Type)
+	for _, f := range schema.Fields {
+		selector := nameSpace + f.Name
+		if a := newMarkers(f.Type.Kind(), &f.Constraints); len(a) > 0 {
+			markers[selector] = a
+		}
+		if f.Elem != nil && derefKind(f.Type) == reflect.Struct {
+			addMarkers(markers, selector+".", f.Elem, visited)
+		}
+	}
+}
+
+func derefKind(t reflect.Type) reflect.Kind {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind()
+}
+
+// newMarkers returns the markers of the constraints, kind is the kind of the field type.
+func newMarkers(kind reflect.Kind, c *constraint.Constraints) []string {
+	var a []string
+	add := func(name string, value interface{}) {
+		if value == nil {
+			a = append(a, MarkerPrefix+name)
+		} else {
+			a = append(a, fmt.Sprintf("%s%s=%v", MarkerPrefix, name, value))
+		}
+	}
+	if c.Required {
+		add("Required", nil)
+	}
+	minLen, maxLen := "MinLength", "MaxLength"
+	switch kind {
+	case reflect.Slice, reflect.Array:
+		minLen, maxLen = "MinItems", "MaxItems"
+	case reflect.Map:
+		minLen, maxLen = "MinProperties", "MaxProperties"
+	}
+	if c.MinLength != nil {
+		add(minLen, *c.MinLength)
+	}
+	if c.MaxLength != nil {
+		add(maxLen, *c.MaxLength)
+	}
+	if c.Minimum != nil {
+		add("Minimum", number(*c.Minimum))
+		if c.ExclusiveMinimum {
+			add("ExclusiveMinimum", true)
+		}
+	}
+	if c.Maximum != nil {
+		add("Maximum", number(*c.Maximum))
+		if c.ExclusiveMaximum {
+			add("ExclusiveMaximum", true)
+		}
+	}
+	if c.Pattern != "" {
+		if strings.Contains(c.Pattern, "`") {
+			add("Pattern", strconv.Quote(c.Pattern))
+		} else {
+			add("Pattern", "`"+c.Pattern+"`")
+		}
+	}
+	if len(c.Enum) > 0 {
+		values := make([]string, len(c.Enum))
+		for i, v := range c.Enum {
+			if f, ok := v.(float64); ok {
+				values[i] = number(f)
+			} else {
+				values[i] = fmt.Sprint(v)
+			}
+		}
+		add("Enum", strings.Join(values, ";"))
+	}
+	return a
+}
+
+func number(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Rewrite adds the validation markers above the fields that have the @tagName tag in the go source file.
+// NOTE:
+//  The existing validation markers of these fields are replaced,
+//  so that the source can be rewritten again after the tag expressions are changed.
+func (g *Generator) Rewrite(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var edits []edit
+	ast.Inspect(file, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		st, ok := n.(*ast.StructType)
+		if !ok {
+			return true
+		}
+		for _, field := range st.Fields.List {
+			var e *edit
+			if e, err = g.rewriteField(fset, src, field); err != nil {
+				err = fmt.Errorf("%s: %s", fset.Position(field.Pos()), err.Error())
+				return false
+			}
+			if e != nil {
+				edits = append(edits, *e)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, e := range edits {
+		src = append(src[:e.start:e.start], append([]byte(e.text), src[e.end:]...)...)
+	}
+	return format.Source(src)
+}
+
+// edit replaces src[start:end] with text
+type edit struct {
+	start, end int
+	text       string
+}
+
+func (g *Generator) rewriteField(fset *token.FileSet, src []byte, field *ast.Field) (*edit, error) {
+	if field.Tag == nil {
+		return nil, nil
+	}
+	raw, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return nil, err
+	}
+	tag, ok := reflect.StructTag(raw).Lookup(g.tagName)
+	if !ok {
+		return nil, nil
+	}
+	c, err := g.extractor.ExtractTag(tag, exprType(field.Type))
+	if err != nil {
+		return nil, err
+	}
+	lineStart := func(pos token.Pos) int {
+		offset := fset.Position(pos).Offset
+		return bytes.LastIndexByte(src[:offset], '\n') + 1
+	}
+	e := &edit{start: lineStart(field.Pos())}
+	e.end = e.start
+	indent := src[e.start:fset.Position(field.Pos()).Offset]
+	var lines []string
+	if field.Doc != nil {
+		e.start = lineStart(field.Doc.Pos())
+		for _, comment := range field.Doc.List {
+			if !strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(comment.Text, "//")), MarkerPrefix) {
+				lines = append(lines, comment.Text)
+			}
+		}
+	}
+	for _, m := range newMarkers(exprKind(field.Type), &c) {
+		lines = append(lines, "// "+m)
+	}
+	for _, line := range lines {
+		e.text += string(indent) + line + "\n"
+	}
+	return e, nil
+}
+
+// basicTypes the predeclared types
+var basicTypes = map[string]reflect.Type{
+	"bool": reflect.TypeOf(false), "string": reflect.TypeOf(""),
+	"int": reflect.TypeOf(0), "int8": reflect.TypeOf(int8(0)), "int16": reflect.TypeOf(int16(0)),
+	"int32": reflect.TypeOf(int32(0)), "int64": reflect.TypeOf(int64(0)),
+	"uint": reflect.TypeOf(uint(0)), "uint8": reflect.TypeOf(uint8(0)), "uint16": reflect.TypeOf(uint16(0)),
+	"uint32": reflect.TypeOf(uint32(0)), "uint64": reflect.TypeOf(uint64(0)),
+	"byte": reflect.TypeOf(byte(0)), "rune": reflect.TypeOf(rune(0)),
+	"float32": reflect.TypeOf(float32(0)), "float64": reflect.TypeOf(float64(0)),
+}
+
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// exprType returns a type that has the same shape as the field type expression.
+// NOTE:
+//  The named types are unknown in the syntax tree, use interface{} instead.
+func exprType(expr ast.Expr) reflect.Type {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if typ, ok := basicTypes[t.Name]; ok {
+			return typ
+		}
+	case *ast.StarExpr:
+		return reflect.PtrTo(exprType(t.X))
+	case *ast.ArrayType:
+		return reflect.SliceOf(exprType(t.Elt))
+	case *ast.MapType:
+		return reflect.MapOf(exprType(t.Key), exprType(t.Value))
+	}
+	return interfaceType
+}
+
+// exprKind returns the kind of the field type expression, the pointer is dereferenced.
+func exprKind(expr ast.Expr) reflect.Kind {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return exprKind(t.X)
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return reflect.Array
+		}
+	}
+	return exprType(expr).Kind()
+}
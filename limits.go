@@ -0,0 +1,147 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"fmt"
+)
+
+// ParseLimits the hard limits of parsing an expression,
+// so that the adversarial tags cannot blow the stack or spin the parser.
+// NOTE:
+//  Zero means the default limit, negative means unlimited.
+type ParseLimits struct {
+	MaxLength int // max bytes of the expression
+	MaxDepth  int // max nesting depth of the groups, function calls and subscripts
+	MaxTokens int // max number of the parsed operands, including the ones re-parsed by backtracking
+}
+
+// DefaultParseLimits the default limits, far beyond the handwritten expressions
+var DefaultParseLimits = ParseLimits{
+	MaxLength: 4096,
+	MaxDepth:  32,
+	MaxTokens: 1024,
+}
+
+// SetParseLimits sets the hard limits of parsing the expressions.
+// NOTE:
+//  It should be called before the vm is used.
+func (vm *VM) SetParseLimits(limits ParseLimits) *VM {
+	vm.parseLimits = limits
+	return vm
+}
+
+// getParseLimits returns the limits with the defaults filled, the default ones if vm is nil.
+func (vm *VM) getParseLimits() ParseLimits {
+	var limits ParseLimits
+	if vm != nil {
+		limits = vm.parseLimits
+	}
+	if limits.MaxLength == 0 {
+		limits.MaxLength = DefaultParseLimits.MaxLength
+	}
+	if limits.MaxDepth == 0 {
+		limits.MaxDepth = DefaultParseLimits.MaxDepth
+	}
+	if limits.MaxTokens == 0 {
+		limits.MaxTokens = DefaultParseLimits.MaxTokens
+	}
+	return limits
+}
+
+// parseState the state of parsing an expression
+type parseState struct {
+	limits ParseLimits
+	source string
+	depth  int
+	tokens int
+	err    *SyntaxError // the exceeded limit, stops all the parsing once set
+}
+
+// fail records the exceeded limit at the rest of the expression.
+func (ps *parseState) fail(rest, reason string) error {
+	if ps.err == nil {
+		ps.err = &SyntaxError{Expr: ps.source, Offset: offsetOf(ps.source, rest), Reason: reason}
+	}
+	return ps.err
+}
+
+func (ps *parseState) countToken(rest string) error {
+	if ps.err != nil {
+		return ps.err
+	}
+	ps.tokens++
+	if max := ps.limits.MaxTokens; max >= 0 && ps.tokens > max {
+		return ps.fail(rest, fmt.Sprintf("too many tokens, the limit is %d", max))
+	}
+	return nil
+}
+
+// parseSubExprNode parses the nested expression, such as the group, the function argument and the subscript.
+func (p *Expr) parseSubExprNode(expr *string, e ExprNode) (ExprNode, error) {
+	ps := p.ps
+	ps.depth++
+	defer func() { ps.depth-- }()
+	if max := ps.limits.MaxDepth; max >= 0 && ps.depth > max {
+		return nil, ps.fail(*expr, fmt.Sprintf("nested too deep, the limit is %d", max))
+	}
+	return p.parseExprNode(expr, e)
+}
+
+// RegisterLimits the hard limits of registering a struct type,
+// so that the giant graphs of types, such as the ORM entities, cannot consume unbounded memory.
+// NOTE:
+//  Zero means the default limit, negative means unlimited;
+//  The struct type that exceeds the limits is not registered, vm.Run returns ErrLimitExceeded.
+type RegisterLimits struct {
+	MaxDepth  int // max nesting depth of the struct fields, 1 for the struct without struct field
+	MaxFields int // max number of the fields, including the ones of the nested structs
+}
+
+// DefaultRegisterLimits the default limits, far beyond the handwritten structs
+var DefaultRegisterLimits = RegisterLimits{
+	MaxDepth:  32,
+	MaxFields: 10000,
+}
+
+// SetRegisterLimits sets the hard limits of registering the struct types.
+// NOTE:
+//  It should be called before the vm is used.
+func (vm *VM) SetRegisterLimits(limits RegisterLimits) *VM {
+	vm.registerLimits = limits
+	return vm
+}
+
+// getRegisterLimits returns the limits with the defaults filled.
+func (vm *VM) getRegisterLimits() RegisterLimits {
+	limits := vm.registerLimits
+	if limits.MaxDepth == 0 {
+		limits.MaxDepth = DefaultRegisterLimits.MaxDepth
+	}
+	if limits.MaxFields == 0 {
+		limits.MaxFields = DefaultRegisterLimits.MaxFields
+	}
+	return limits
+}
+
+func (limits RegisterLimits) check(s *Struct) error {
+	if max := limits.MaxDepth; max >= 0 && s.depth > max {
+		return newKindError(ErrLimitExceeded, "%s: nested too deep, the limit is %d", s.name, max)
+	}
+	if max := limits.MaxFields; max >= 0 && len(s.fields) > max {
+		return newKindError(ErrLimitExceeded, "%s: too many fields, the limit is %d", s.name, max)
+	}
+	return nil
+}
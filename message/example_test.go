@@ -0,0 +1,38 @@
+package message_test
+
+import (
+	"errors"
+	"fmt"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+	"github.com/bytedance/go-tagexpr/message"
+	"github.com/bytedance/go-tagexpr/validator"
+)
+
+func Example() {
+	type ChatMessage struct {
+		ConnID string `meta:"conn_id" vd:"$!=''"`
+		UserID int64  `meta:"user_id" vd:"$>0"`
+		Room   string `json:"room" vd:"regexp('^[a-z0-9-]{1,32}$')"`
+		Text   string `json:"text" vd:"{@:len($)>0&&len($)<=500}{msg:'text must be 1 to 500 characters'}"`
+	}
+	binder := message.New(validator.New("vd"))
+	meta := map[string]string{"conn_id": "c-1", "user_id": "42"}
+
+	var m ChatMessage
+	err := binder.Bind([]byte(`{"room":"general","text":"hi","UserID":7}`), meta, &m)
+	fmt.Println(err, m.UserID)
+
+	err = binder.Bind([]byte(`{"room":"general","text":""}`), meta, new(ChatMessage))
+	fmt.Println(err)
+	fmt.Println(errors.Is(err, tagexpr.ErrValidation))
+
+	err = binder.Bind([]byte(`{"room":"general","text":"hi"}`), map[string]string{"user_id": "x"}, new(ChatMessage))
+	fmt.Println(errors.Is(err, tagexpr.ErrTypeMismatch))
+
+	// Output:
+	// <nil> 42
+	// text must be 1 to 500 characters
+	// true
+	// true
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package message binds the structs from the message frames, such as the websocket JSON frames,
+// and the metadata of the message, then validates them by the struct tag expressions,
+// so that the realtime services share the same declarative validation as the REST handlers.
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+	"github.com/bytedance/go-tagexpr/validator"
+)
+
+// DefaultMetaTag the tag name of the metadata keys
+const DefaultMetaTag = "meta"
+
+// UnmarshalFunc unmarshals the message payload into the struct pointer, such as json.Unmarshal.
+type UnmarshalFunc func(data []byte, structPtr interface{}) error
+
+// Binder message binder
+type Binder struct {
+	vd        *validator.Validator
+	metaTag   string
+	unmarshal UnmarshalFunc
+}
+
+// New creates a message binder that unmarshals the payload by json.Unmarshal and validates by vd.
+func New(vd *validator.Validator) *Binder {
+	return &Binder{vd: vd, metaTag: DefaultMetaTag, unmarshal: json.Unmarshal}
+}
+
+// SetMetaTag sets the tag name of the metadata keys, the default is meta.
+func (b *Binder) SetMetaTag(metaTag string) *Binder {
+	b.metaTag = metaTag
+	return b
+}
+
+// SetUnmarshal sets the unmarshal function of the payload, such as the one of msgpack, the default is json.Unmarshal.
+func (b *Binder) SetUnmarshal(unmarshal UnmarshalFunc) *Binder {
+	b.unmarshal = unmarshal
+	return b
+}
+
+// Bind unmarshals the payload data into structPtr, sets the fields tagged by the meta tag from meta, then validates it.
+// NOTE:
+//  The meta fields are set after the payload, so that the metadata such as the connection ID cannot be forged by the payload;
+//  The meta fields are the top-level ones of the kinds string, bool, int, uint and float, or the pointers to them;
+//  The field is not changed if its key is absent from meta;
+//  The error of a meta value that cannot be converted wraps tagexpr.ErrTypeMismatch.
+func (b *Binder) Bind(data []byte, meta map[string]string, structPtr interface{}) error {
+	if len(data) > 0 {
+		if err := b.unmarshal(data, structPtr); err != nil {
+			return err
+		}
+	}
+	if err := b.BindMeta(meta, structPtr); err != nil {
+		return err
+	}
+	return b.vd.Validate(structPtr)
+}
+
+// BindMeta sets the fields tagged by the meta tag from meta, without validation.
+func (b *Binder) BindMeta(meta map[string]string, structPtr interface{}) error {
+	v := reflect.ValueOf(structPtr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("message: not structure pointer: %T: %w", structPtr, tagexpr.ErrTypeMismatch)
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		key, ok := sf.Tag.Lookup(b.metaTag)
+		if !ok || key == "" || key == "-" || sf.PkgPath != "" {
+			continue
+		}
+		s, ok := meta[key]
+		if !ok {
+			continue
+		}
+		if err := setValue(v.Field(i), s); err != nil {
+			return fmt.Errorf("message: meta %s: %v: %w", key, err, tagexpr.ErrTypeMismatch)
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		r, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(r)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		r, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(r)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		r, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(r)
+	case reflect.Float32, reflect.Float64:
+		r, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(r)
+	default:
+		return fmt.Errorf("unsupported kind %s", v.Kind())
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"strings"
+)
+
+const metaExprName = "meta"
+
+// Meta the metadata of the rules of a field, such as {meta:'pii,severity=high'},
+// the key without value, such as pii, maps to "".
+type Meta map[string]string
+
+// ParseMeta parses the comma-separated key or key=value pairs.
+func ParseMeta(s string) Meta {
+	m := make(Meta)
+	for _, kv := range strings.Split(s, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		var v string
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			kv, v = strings.TrimSpace(kv[:i]), strings.TrimSpace(kv[i+1:])
+		}
+		m[kv] = v
+	}
+	return m
+}
+
+// Has reports whether the key is present.
+func (m Meta) Has(key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+// Meta returns the metadata of the field by the name, nil if there is none.
+// NOTE:
+//  format: fieldName, fieldName1.fieldName2;
+//  The meta expression must be a constant string, otherwise it is ignored.
+func (s *Struct) Meta(field string) Meta {
+	e, ok := s.exprs[field+"@"+metaExprName]
+	if !ok {
+		return nil
+	}
+	return exprMeta(e.expr)
+}
+
+// RangeMeta loop through the metadata of each field in the registration order,
+// such as to locate the fields of PII or high severity.
+func (s *Struct) RangeMeta(fn func(field string, meta Meta) bool) {
+	const suffix = "@" + metaExprName
+	for _, e := range s.exprList {
+		if !strings.HasSuffix(e.selector, suffix) {
+			continue
+		}
+		meta := exprMeta(e.expr)
+		if meta == nil {
+			continue
+		}
+		if !fn(e.field, meta) {
+			return
+		}
+	}
+}
+
+func exprMeta(expr *Expr) Meta {
+	node := expr.AST()
+	if node == nil || node.Kind != LiteralNode {
+		return nil
+	}
+	s, ok := node.Value.(string)
+	if !ok {
+		return nil
+	}
+	return ParseMeta(s)
+}
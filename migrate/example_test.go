@@ -0,0 +1,102 @@
+package migrate_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bytedance/go-tagexpr/migrate"
+)
+
+func Example() {
+	type User struct {
+		Name  string   `json:"name" validate:"required,min=3,max=32"`
+		Age   int      `validate:"omitempty,gte=18,lte=130"`
+		Role  string   `validate:"oneof=admin user"`
+		Tags  []string `validate:"max=3,unique"`
+		Email *string  `validate:"omitempty,email"`
+	}
+	rules, err := migrate.RuleMap(new(User))
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range []string{"Name", "Age", "Role", "Tags"} {
+		fmt.Println(f, rules[f])
+	}
+
+	src := `package model
+
+import "time"
+
+type Status string
+
+type User struct {
+	Name     string    ` + "`" + `json:"name" validate:"required,max=32"` + "`" + `
+	Age      int       ` + "`" + `validate:"gte=18"` + "`" + `
+	Status   Status    ` + "`" + `validate:"required,min=3"` + "`" + `
+	Birthday time.Time ` + "`" + `validate:"required"` + "`" + `
+}
+`
+	out, err := migrate.Rewrite([]byte(src), "vd")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(string(out))
+
+	_, err = migrate.Rewrite([]byte("package model\n\ntype T struct {\n\tF func() `validate:\"required\"`\n}\n"), "vd")
+	fmt.Println()
+	fmt.Println(err)
+
+	// Output:
+	// Name !($=='')&&len($)>=3&&len($)<=32
+	// Age $==0||($>=18&&$<=130)
+	// Role $=='admin'||$=='user'
+	// Tags len($)<=3&&unique($)
+	// package model
+	//
+	// import "time"
+	//
+	// type Status string
+	//
+	// type User struct {
+	// 	Name     string    `json:"name" vd:"!($=='')&&len($)<=32"`
+	// 	Age      int       `vd:"$>=18"`
+	// 	Status   Status    `vd:"!($=='')&&len($)>=3"`
+	// 	Birthday time.Time `vd:"true"`
+	// }
+	//
+	// 4:2: unsupported field type func()
+}
+
+func ExampleRewriteDir() {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, src := range map[string]string{
+		"status.go": "package model\n\ntype Status string\n",
+		"user.go":   "package model\n\ntype User struct {\n\tStatus Status `validate:\"required\"`\n}\n",
+	} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			panic(err)
+		}
+	}
+	files, err := migrate.RewriteDir(dir, "vd")
+	if err != nil {
+		panic(err)
+	}
+	for name, src := range files {
+		fmt.Println(filepath.Base(name))
+		fmt.Print(string(src))
+	}
+
+	// Output:
+	// user.go
+	// package model
+	//
+	// type User struct {
+	// 	Status Status `vd:"!($=='')"`
+	// }
+}
@@ -0,0 +1,331 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migrate translates the go-playground/validator tags into the struct tag expressions,
+// such as validate:"required,min=3,max=32,email" into vd:"!($=='')&&len($)>=3&&len($)<=32&&regexp('...')".
+package migrate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+)
+
+// ValidateTagName the tag name of go-playground/validator
+const ValidateTagName = "validate"
+
+// RuleMap returns the expressions translated from the validate tags of the struct fields,
+// the key is the field selector, such as A.B for the field B of the nested struct field A.
+func RuleMap(structOrStructPtr interface{}) (map[string]string, error) {
+	if structOrStructPtr == nil {
+		return nil, errors.New("cannot translate nil interface")
+	}
+	t := reflect.TypeOf(structOrStructPtr)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("not structure pointer or structure: %s", t.String())
+	}
+	rules := make(map[string]string)
+	err := addRules(rules, "", t, map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func addRules(rules map[string]string, nameSpace string, t reflect.Type, visited map[reflect.Type]bool) error {
+	if visited[t] {
+		return nil
+	}
+	visited[t] = true
+	defer delete(visited, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		selector := nameSpace + field.Name
+		if tag, ok := field.Tag.Lookup(ValidateTagName); ok {
+			expr, err := tagexpr.TranslateValidateTag(tag, ft.Kind(), ft != field.Type)
+			if err != nil {
+				return fmt.Errorf("%s: %s", selector, err.Error())
+			}
+			if expr == "-" {
+				continue
+			}
+			if expr != "" {
+				rules[selector] = expr
+			}
+		}
+		if ft.Kind() == reflect.Struct {
+			if err := addRules(rules, selector+".", ft, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Rewrite rewrites the validate tags of the go source file into the @tagName tags,
+// the other tags are kept as they are.
+// NOTE:
+//  The field types are resolved by type-checking the file, such as the underlying string of type Status string,
+//  the imported packages are loaded from the source, use RewriteDir for the types declared in the other files of the package;
+//  Return error if the type of a field with the validate tag cannot be resolved or is not supported, such as func.
+func Rewrite(src []byte, tagName string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	info := check(fset, []*ast.File{file})
+	if _, err = rewriteFile(fset, file, tagName, info); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RewriteDir rewrites the validate tags of the go files of the package in the directory, like Rewrite,
+// and returns the sources of the changed files by their paths.
+// NOTE:
+//  The files of the package are type-checked together, so the field types declared in any of them are resolved;
+//  The test files are rewritten, the files excluded by the build constraints are not.
+func RewriteDir(dir string, tagName string) (map[string][]byte, error) {
+	pkg, err := build.ImportDir(dir, build.ImportComment)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	r := make(map[string][]byte)
+	// the external test package is checked apart from the package it tests
+	for _, names := range [][]string{
+		append(append(append([]string{}, pkg.GoFiles...), pkg.CgoFiles...), pkg.TestGoFiles...),
+		pkg.XTestGoFiles,
+	} {
+		files := make([]*ast.File, 0, len(names))
+		for _, name := range names {
+			file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+		if len(files) == 0 {
+			continue
+		}
+		info := check(fset, files)
+		for _, file := range files {
+			changed, err := rewriteFile(fset, file, tagName, info)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				continue
+			}
+			var buf bytes.Buffer
+			if err = format.Node(&buf, fset, file); err != nil {
+				return nil, err
+			}
+			r[fset.Position(file.Pos()).Filename] = buf.Bytes()
+		}
+	}
+	return r, nil
+}
+
+// check type-checks the files of one package, the imported packages are loaded from the source.
+func check(fset *token.FileSet, files []*ast.File) *types.Info {
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		// the types that cannot be resolved are reported by the fields using them
+		Error: func(error) {},
+	}
+	conf.Check(files[0].Name.Name, fset, files, info)
+	return info
+}
+
+// rewriteFile rewrites the validate tags of the file, reports whether any tag is changed.
+func rewriteFile(fset *token.FileSet, file *ast.File, tagName string, info *types.Info) (changed bool, err error) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		st, ok := n.(*ast.StructType)
+		if !ok {
+			return true
+		}
+		for _, field := range st.Fields.List {
+			if field.Tag == nil {
+				continue
+			}
+			var ok bool
+			if ok, err = rewriteTag(field, tagName, info); err != nil {
+				err = fmt.Errorf("%s: %s", fset.Position(field.Pos()), err.Error())
+				return false
+			}
+			changed = changed || ok
+		}
+		return true
+	})
+	return changed, err
+}
+
+// rewriteTag rewrites the validate tag of the field, reports whether the tag is changed.
+func rewriteTag(field *ast.Field, tagName string, info *types.Info) (bool, error) {
+	raw, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return false, err
+	}
+	pairs, err := parseTag(raw)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	for i, p := range pairs {
+		if p[0] != ValidateTagName {
+			continue
+		}
+		kind, nilable, err := fieldKind(field.Type, info)
+		if err != nil {
+			return false, err
+		}
+		expr, err := tagexpr.TranslateValidateTag(p[1], kind, nilable)
+		if err != nil {
+			return false, err
+		}
+		pairs[i] = [2]string{tagName, expr}
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+	a := make([]string, 0, len(pairs))
+	for _, p := range pairs {
+		if p[1] == "" && p[0] == tagName {
+			continue
+		}
+		a = append(a, p[0]+":"+strconv.Quote(p[1]))
+	}
+	tag := strings.Join(a, " ")
+	if strings.Contains(tag, "`") {
+		field.Tag.Value = strconv.Quote(tag)
+	} else {
+		field.Tag.Value = "`" + tag + "`"
+	}
+	return true, nil
+}
+
+// parseTag parses the key:"value" pairs of the struct tag, like reflect.StructTag.Lookup.
+func parseTag(tag string) ([][2]string, error) {
+	var pairs [][2]string
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return nil, fmt.Errorf("malformed struct tag: %q", tag)
+		}
+		name := tag[:i]
+		tag = tag[i+1:]
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return nil, fmt.Errorf("malformed struct tag: %q", tag)
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			return nil, err
+		}
+		tag = tag[i+1:]
+		pairs = append(pairs, [2]string{name, value})
+	}
+	return pairs, nil
+}
+
+// basicKinds the kinds of the basic types
+var basicKinds = map[types.BasicKind]reflect.Kind{
+	types.Bool: reflect.Bool, types.String: reflect.String,
+	types.Int: reflect.Int, types.Int8: reflect.Int8, types.Int16: reflect.Int16, types.Int32: reflect.Int32, types.Int64: reflect.Int64,
+	types.Uint: reflect.Uint, types.Uint8: reflect.Uint8, types.Uint16: reflect.Uint16, types.Uint32: reflect.Uint32, types.Uint64: reflect.Uint64,
+	types.Uintptr: reflect.Uintptr, types.Float32: reflect.Float32, types.Float64: reflect.Float64,
+}
+
+// fieldKind returns the kind of the underlying type of the field, the pointers are dereferenced,
+// nilable is whether the field is a pointer.
+func fieldKind(expr ast.Expr, info *types.Info) (kind reflect.Kind, nilable bool, err error) {
+	tv, ok := info.Types[expr]
+	if !ok || tv.Type == nil || tv.Type == types.Typ[types.Invalid] {
+		return reflect.Invalid, false, fmt.Errorf("cannot resolve the field type %s", types.ExprString(expr))
+	}
+	t := tv.Type.Underlying()
+	for {
+		ptr, ok := t.(*types.Pointer)
+		if !ok {
+			break
+		}
+		t, nilable = ptr.Elem().Underlying(), true
+	}
+	switch u := t.(type) {
+	case *types.Basic:
+		kind = basicKinds[u.Kind()]
+	case *types.Struct:
+		kind = reflect.Struct
+	case *types.Slice:
+		kind = reflect.Slice
+	case *types.Array:
+		kind = reflect.Array
+	case *types.Map:
+		kind = reflect.Map
+	case *types.Interface:
+		kind = reflect.Interface
+	}
+	if kind == reflect.Invalid {
+		return kind, false, fmt.Errorf("unsupported field type %s", types.ExprString(expr))
+	}
+	return kind, nilable, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+var numberAdapters = make(map[reflect.Type]func(ptr interface{}) (*big.Rat, bool))
+
+func init() {
+	RegNumberAdapter(reflect.TypeOf(big.Int{}), func(ptr interface{}) (*big.Rat, bool) {
+		return new(big.Rat).SetInt(ptr.(*big.Int)), true
+	})
+	RegNumberAdapter(reflect.TypeOf(big.Float{}), func(ptr interface{}) (*big.Rat, bool) {
+		r, _ := ptr.(*big.Float).Rat(nil)
+		return r, r != nil
+	})
+	RegNumberAdapter(reflect.TypeOf(big.Rat{}), func(ptr interface{}) (*big.Rat, bool) {
+		return new(big.Rat).Set(ptr.(*big.Rat)), true
+	})
+}
+
+// RegNumberAdapter registers the adapter of the arbitrary-precision numeric type,
+// whose field value is *big.Rat in the expressions, compared exactly by ==, !=, <, <=, >, >=.
+// NOTE:
+//  big.Int, big.Float and big.Rat are registered by default;
+//  typ is the value type, such as reflect.TypeOf(decimal.Decimal{}), its pointer is passed to toRat;
+//  The number literals are compared by their decimal form, such as 0.1 is exactly 1/10;
+//  If @force=true, allow to cover the existed adapter of the same @typ;
+//  It is not concurrency safe, should be called during initialization, before the structs are registered.
+func RegNumberAdapter(typ reflect.Type, toRat func(ptr interface{}) (*big.Rat, bool), force ...bool) error {
+	if typ == nil || typ.Kind() == reflect.Ptr {
+		return fmt.Errorf("invalid number adapter type: %v", typ)
+	}
+	if len(force) == 0 || !force[0] {
+		if _, ok := numberAdapters[typ]; ok {
+			return fmt.Errorf("duplicate registration number adapter: %s", typ.String())
+		}
+	}
+	numberAdapters[typ] = toRat
+	return nil
+}
+
+func (f *Field) setRatGetter(t reflect.Type, toRat func(interface{}) (*big.Rat, bool), ptrDeep int) {
+	f.valueGetter = func(ptr uintptr) interface{} {
+		if ptr = derefPtr(ptr+f.Offset, ptrDeep); ptr == 0 {
+			return nil
+		}
+		if r, ok := toRat(reflect.NewAt(t, unsafe.Pointer(ptr)).Interface()); ok {
+			return r
+		}
+		return nil
+	}
+}
+
+// adaptNumber returns the *big.Rat value of vv if its type has the number adapter.
+func adaptNumber(vv reflect.Value) (interface{}, bool) {
+	toRat, ok := numberAdapters[vv.Type()]
+	if !ok || !vv.CanInterface() {
+		return nil, ok
+	}
+	if !vv.CanAddr() {
+		p := reflect.New(vv.Type())
+		p.Elem().Set(vv)
+		vv = p.Elem()
+	}
+	if r, ok := toRat(vv.Addr().Interface()); ok {
+		return r, true
+	}
+	return nil, true
+}
+
+// compareRat compares the operand values exactly if either of them is *big.Rat,
+// the other one must be *big.Rat or the finite float64.
+func compareRat(e ExprNode, v0, v1 interface{}) (c int, ok bool) {
+	r0, ok0 := v0.(*big.Rat)
+	r1, ok1 := v1.(*big.Rat)
+	if !ok0 && !ok1 {
+		return 0, false
+	}
+	if !ok0 {
+		if r0, ok = operandRat(e.LeftOperand(), v0); !ok {
+			return 0, false
+		}
+	}
+	if !ok1 {
+		if r1, ok = operandRat(e.RightOperand(), v1); !ok {
+			return 0, false
+		}
+	}
+	return r0.Cmp(r1), true
+}
+
+// operandRat converts the float64 value of the operand to *big.Rat,
+// the number literal is converted by its source text, such as 9007199254740993.
+func operandRat(operand ExprNode, v interface{}) (*big.Rat, bool) {
+	if d, ok := unwrapGroup(operand).(*digitalExprNode); ok && d.exact != nil {
+		return d.exact, true
+	}
+	return floatToRat(v)
+}
+
+// floatToRat converts the finite float64 to *big.Rat by its shortest decimal form.
+func floatToRat(v interface{}) (*big.Rat, bool) {
+	f, ok := v.(float64)
+	if !ok || math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"reflect"
+	"regexp"
+)
+
+// Params the named parameters of the expressions, such as %max_items% in len($)<=%max_items%
+type Params map[string]interface{}
+
+// SetParams sets the named parameters of the vm, which are used by all the structs.
+// NOTE:
+//  The number types are converted to float64, the string types to string;
+//  The parameter that is not set is nil;
+//  It should be called before the vm is used.
+func (vm *VM) SetParams(params Params) *VM {
+	vm.params = params
+	return vm
+}
+
+// SetParams sets the named parameters of the call, such as the limits of the tenant,
+// which take precedence over the ones of the vm.
+func (t *TagExpr) SetParams(params Params) *TagExpr {
+	t.params = params
+	return t
+}
+
+// getParam returns the value of the named parameter, nil if it is not set.
+func (t *TagExpr) getParam(name string) interface{} {
+	if t == nil || t.s == nil {
+		return nil
+	}
+	v, ok := t.params[name]
+	if !ok {
+		if v, ok = t.getVM().params[name]; !ok {
+			return nil
+		}
+	}
+	switch v.(type) {
+	case nil, float64, string, bool:
+		return v
+	}
+	return toExprValue(reflect.ValueOf(v))
+}
+
+type paramExprNode struct {
+	exprBackground
+	name       string
+	boolPrefix *bool
+}
+
+var paramRegexp = regexp.MustCompile(`^(\!*)%([A-Za-z_][A-Za-z0-9_]*)%`)
+
+func readParamExprNode(expr *string) ExprNode {
+	a := paramRegexp.FindStringSubmatch(*expr)
+	if a == nil {
+		return nil
+	}
+	*expr = (*expr)[len(a[0]):]
+	e := &paramExprNode{name: a[2]}
+	if boolNum := len(a[1]); boolNum > 0 {
+		bol := true
+		for i := boolNum; i > 0; i-- {
+			bol = !bol
+		}
+		e.boolPrefix = &bol
+	}
+	return e
+}
+
+func (pe *paramExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
+	return applyBoolPrefix(tagExpr.getParam(pe.name), pe.boolPrefix)
+}
@@ -0,0 +1,274 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SetCompatTag sets the tag name of the go-playground/validator syntax, such as validate,
+// which is interpreted when the field has no tag of the vm, so that both dialects can coexist.
+// NOTE:
+//  The translated expression is the default one, that is the selector is fieldName@;
+//  The field tagged with -, such as validate:"-", is excluded from the registration like `te:"-"`;
+//  The rule that cannot be translated, such as dive, eqfield and a|b, is left out by the unsupported policy, see SetUnsupportedPolicy;
+//  It should be called before the vm is used.
+func (vm *VM) SetCompatTag(tagName string) *VM {
+	vm.compatTagName = tagName
+	return vm
+}
+
+// translateCompatTag translates the compatible tag of the field of s into the expression.
+func (vm *VM) translateCompatTag(s *Struct, structField reflect.StructField) (string, error) {
+	tag, ok := structField.Tag.Lookup(vm.compatTagName)
+	if !ok {
+		return "", nil
+	}
+	t := structField.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	expr, err := translateValidateTag(tag, t.Kind(), t != structField.Type, func(rule string, err error) error {
+		return vm.checkUnsupportedRule(s, structField, rule, err)
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", structField.Name, err.Error())
+	}
+	return expr, nil
+}
+
+// playgroundPatterns the regexps of the go-playground/validator format keywords
+var playgroundPatterns = map[string]string{
+	"alpha":        `^[a-zA-Z]+$`,
+	"alphanum":     `^[a-zA-Z0-9]+$`,
+	"numeric":      `^[-+]?[0-9]+(\.[0-9]+)?$`,
+	"number":       `^[0-9]+$`,
+	"lowercase":    `^[^A-Z]*$`,
+	"uppercase":    `^[^a-z]*$`,
+	"email":        `^[^@\s]+@[^@\s]+\.[^@\s]+$`,
+	"url":          `^[a-zA-Z][a-zA-Z0-9+.-]*://[^\s/?#]+[^\s]*$`,
+	"uri":          `^[a-zA-Z][a-zA-Z0-9+.-]*:[^\s]+$`,
+	"uuid":         `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`,
+	"ipv4":         `^((25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])\.){3}(25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])$`,
+	"hexcolor":     `^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`,
+	"printascii":   `^[\x20-\x7e]*$`,
+	"alphaunicode": `^[\p{L}]+$`,
+}
+
+// playgroundFuncs the built-in functions of the go-playground/validator format keywords
+var playgroundFuncs = map[string]string{
+	"hexadecimal":        "ishex($)",
+	"base64":             "isbase64($)",
+	"json":               "isjson($)",
+	"e164":               "phone($)",
+	"bic":                "bic($)",
+	"iso3166_1_alpha2":   "iso3166($)",
+	"iso4217":            "iso4217($)",
+	"bcp47_language_tag": "bcp47($)",
+	"timezone":           "tz($)",
+	"semver":             "semver($)",
+	"credit_card":        "ccbrand($)!=''",
+	"unique":             "unique($)",
+}
+
+// TranslateValidateTag translates the go-playground/validator tag into the equivalent expression,
+// such as required,min=3,max=32,email.
+// NOTE:
+//  kind is the kind of the field, the pointer should be dereferenced;
+//  nilable is whether the field is a pointer, then required and omitempty check for nil;
+//  The length is compared for the string, slice, map and array, otherwise the value;
+//  The struct value such as time.Time is always present for required and omitempty;
+//  The tag - is translated into -, which excludes the field from the registration, like go-playground/validator;
+//  Return error if any keyword is not supported, such as dive and the cross field ones.
+func TranslateValidateTag(tag string, kind reflect.Kind, nilable bool) (string, error) {
+	return translateValidateTag(tag, kind, nilable, nil)
+}
+
+// translateValidateTag translates the tag like TranslateValidateTag, the rule that cannot be translated
+// is left out if skip returns nil for it, otherwise the error is returned.
+func translateValidateTag(tag string, kind reflect.Kind, nilable bool, skip func(rule string, err error) error) (string, error) {
+	if strings.TrimSpace(tag) == skipTag {
+		return skipTag, nil
+	}
+	var exprs []string
+	var omitempty bool
+	for _, rule := range strings.Split(tag, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		if rule == "omitempty" {
+			omitempty = true
+			continue
+		}
+		key, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			key, param = rule[:i], rule[i+1:]
+		}
+		expr, err := translatePlaygroundRule(key, param, kind, nilable)
+		if err != nil && skip != nil {
+			if err = skip(rule, err); err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			return "", fmt.Errorf("validate tag %q: %s", tag, err.Error())
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 0 {
+		return "", nil
+	}
+	if len(exprs) > 1 {
+		for i, expr := range exprs {
+			if strings.Contains(expr, "||") {
+				exprs[i] = "(" + expr + ")"
+			}
+		}
+	}
+	expr := strings.Join(exprs, "&&")
+	if omitempty {
+		if len(exprs) > 1 {
+			expr = "(" + expr + ")"
+		}
+		expr = zeroExpr(kind, nilable) + "||" + expr
+	}
+	return expr, nil
+}
+
+func translatePlaygroundRule(key, param string, kind reflect.Kind, nilable bool) (string, error) {
+	if pattern, ok := playgroundPatterns[key]; ok {
+		return "regexp('" + pattern + "')", nil
+	}
+	if fn, ok := playgroundFuncs[key]; ok {
+		return fn, nil
+	}
+	operand := "$"
+	if hasLength(kind) {
+		operand = "len($)"
+	}
+	switch key {
+	case "required":
+		if kind == reflect.Struct && !nilable {
+			// the struct value such as time.Time is always present, like go-playground/validator
+			return "true", nil
+		}
+		return "!(" + zeroExpr(kind, nilable) + ")", nil
+	case "len", "eq", "ne", "min", "max", "gt", "gte", "lt", "lte":
+		if param == "" {
+			return "", fmt.Errorf("missing parameter of %s", key)
+		}
+		op := map[string]string{
+			"len": "==", "eq": "==", "ne": "!=", "min": ">=", "max": "<=",
+			"gt": ">", "gte": ">=", "lt": "<", "lte": "<=",
+		}[key]
+		if (key == "eq" || key == "ne") && kind == reflect.String {
+			if err := checkLiteral(param); err != nil {
+				return "", err
+			}
+			return "$" + op + "'" + param + "'", nil
+		}
+		if _, err := strconv.ParseFloat(param, 64); err != nil {
+			return "", fmt.Errorf("invalid number parameter of %s: %q", key, param)
+		}
+		return operand + op + param, nil
+	case "oneof":
+		a := strings.Fields(param)
+		if len(a) == 0 {
+			return "", fmt.Errorf("missing parameter of %s", key)
+		}
+		for i, s := range a {
+			if kind == reflect.String {
+				if err := checkLiteral(s); err != nil {
+					return "", err
+				}
+				a[i] = "$=='" + s + "'"
+			} else {
+				if _, err := strconv.ParseFloat(s, 64); err != nil {
+					return "", fmt.Errorf("invalid number parameter of %s: %q", key, s)
+				}
+				a[i] = "$==" + s
+			}
+		}
+		return strings.Join(a, "||"), nil
+	case "contains", "startswith", "endswith", "excludes":
+		if kind != reflect.String {
+			return "", fmt.Errorf("%s is only supported for string", key)
+		}
+		pattern := map[string]string{
+			"contains": "", "startswith": "^", "endswith": "", "excludes": "",
+		}[key] + regexpQuote(param)
+		if key == "endswith" {
+			pattern += "$"
+		}
+		if key == "excludes" {
+			return "!regexp('" + pattern + "')", nil
+		}
+		return "regexp('" + pattern + "')", nil
+	}
+	return "", fmt.Errorf("unsupported keyword: %s", key)
+}
+
+// zeroExpr returns the expression checking whether the field is zero value, or nil if it is nilable,
+// the struct value is never regarded as empty since its value is always nil in the expressions.
+func zeroExpr(kind reflect.Kind, nilable bool) string {
+	switch {
+	case nilable:
+		return "$==nil"
+	case kind == reflect.Struct:
+		return "false"
+	case kind == reflect.String:
+		return "$==''"
+	case kind == reflect.Bool:
+		return "$==false"
+	case hasLength(kind):
+		return "len($)==0"
+	case kind >= reflect.Int && kind <= reflect.Float64:
+		return "$==0"
+	default:
+		return "$==nil"
+	}
+}
+
+func hasLength(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// checkLiteral checks whether s can be written as the string literal of the expression.
+func checkLiteral(s string) error {
+	if strings.ContainsAny(s, `'\()`) {
+		return fmt.Errorf("unsupported character in string parameter: %q", s)
+	}
+	return nil
+}
+
+func regexpQuote(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`\.+*?()|[]{}^$'`, r) {
+			b.WriteString(fmt.Sprintf(`\x%02x`, r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
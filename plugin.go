@@ -0,0 +1,128 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Plugin the custom node type of the expression language, such as geo_within((Lat)$,(Lng)$ in 'zone-a'),
+// which is parsed and evaluated by the downstream code without forking the parser.
+type Plugin interface {
+	// Parse reads the node at the beginning of src, returns the number of the bytes it takes,
+	// the sources of the sub-expressions whose values are the arguments of Eval, and the data for Eval;
+	// n is 0 if src does not begin with the node, err is reported as the syntax error.
+	Parse(src string) (n int, args []string, data interface{}, err error)
+	// Eval returns the value of the node by the data returned by Parse and the values of the arguments.
+	Eval(data interface{}, args []interface{}) interface{}
+}
+
+type namedPlugin struct {
+	name   string
+	plugin Plugin
+}
+
+// plugins the registered plugins in the order of the names
+var plugins []namedPlugin
+
+// RegPlugin registers the plugin by the name.
+// NOTE:
+//  The plugins are tried in the order of the names, after the selectors and the groups, before the built-in operands;
+//  The name is the FuncNode name in the syntax tree, and the node is regarded as an expensive function by the cost order;
+//  The source taken by the plugin is kept as it is in the simplified form of the expression;
+//  The '!' prefix is taken off before Parse, and applied to the result of Eval like the functions;
+//  It is not concurrency safe, should be called during initialization.
+func RegPlugin(name string, plugin Plugin, force ...bool) error {
+	if !funcNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid expression plugin name: %q", name)
+	}
+	i := sort.Search(len(plugins), func(i int) bool { return plugins[i].name >= name })
+	if i < len(plugins) && plugins[i].name == name {
+		if len(force) == 0 || !force[0] {
+			return fmt.Errorf("duplicate registration expression plugin: %s", name)
+		}
+		plugins[i].plugin = plugin
+		return nil
+	}
+	plugins = append(plugins, namedPlugin{})
+	copy(plugins[i+1:], plugins[i:])
+	plugins[i] = namedPlugin{name: name, plugin: plugin}
+	return nil
+}
+
+type pluginExprNode struct {
+	exprBackground
+	name       string
+	plugin     Plugin
+	src        string
+	data       interface{}
+	args       []ExprNode
+	boolPrefix *bool
+}
+
+func (p *Expr) readPluginExprNode(expr *string) ExprNode {
+	if len(plugins) == 0 {
+		return nil
+	}
+	src := strings.TrimLeft(*expr, "!")
+	var boolPrefix *bool
+	if boolNum := len(*expr) - len(src); boolNum > 0 {
+		bol := boolNum%2 == 0
+		boolPrefix = &bol
+	}
+	for _, np := range plugins {
+		n, argSrcs, data, err := np.plugin.Parse(src)
+		if err != nil {
+			p.ps.fail(*expr, np.name+": "+err.Error())
+			return nil
+		}
+		if n <= 0 || n > len(src) {
+			continue
+		}
+		args := make([]ExprNode, len(argSrcs))
+		for i, s := range argSrcs {
+			operand := newGroupExprNode()
+			_, err = p.parseSubExprNode(trimLeftSpace(&s), operand)
+			if trimLeftSpace(&s); err != nil || s != "" || operand.RightOperand() == nil {
+				p.ps.fail(*expr, fmt.Sprintf("%s: invalid argument %d", np.name, i))
+				return nil
+			}
+			sortPriority(operand.RightOperand())
+			args[i] = operand
+		}
+		e := &pluginExprNode{
+			name:       np.name,
+			plugin:     np.plugin,
+			src:        src[:n],
+			data:       data,
+			args:       args,
+			boolPrefix: boolPrefix,
+		}
+		*expr = src[n:]
+		return e
+	}
+	return nil
+}
+
+func (pe *pluginExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
+	// the plugin may keep the args, which are not allocated from the arena
+	args := make([]interface{}, len(pe.args))
+	for i, e := range pe.args {
+		args[i] = e.Run(currField, tagExpr)
+	}
+	return applyBoolPrefix(pe.plugin.Eval(pe.data, args), pe.boolPrefix)
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// EvalError the panic recovered when evaluating an expression,
+// such as the bad dereference of a field or the failed reflect conversion.
+type EvalError struct {
+	Selector string      // selector of the expression
+	Panic    interface{} // the recovered value
+	Stack    []byte      // stack of the goroutine when panicking
+}
+
+// Error implements error.
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("panic when evaluating %q: %v", e.Selector, e.Panic)
+}
+
+// Unwrap returns the recovered error, nil if the panic is not an error,
+// so that the functions can panic with the error of the sentinel, such as newKindError(ErrTypeMismatch, ...).
+func (e *EvalError) Unwrap() error {
+	err, _ := e.Panic.(error)
+	return err
+}
+
+// Is reports whether the target is ErrNilDeref for the nil pointer dereference,
+// or ErrTypeMismatch for the failed type assertion and reflect operation.
+// NOTE:
+//  The other panics of the error are matched by errors.Is through Unwrap.
+func (e *EvalError) Is(target error) bool {
+	var (
+		assertErr *runtime.TypeAssertionError
+		valueErr  *reflect.ValueError
+		rtErr     runtime.Error
+	)
+	switch err := e.Unwrap(); {
+	case errors.As(err, &assertErr), errors.As(err, &valueErr):
+		return target == ErrTypeMismatch
+	case errors.As(err, &rtErr):
+		// the runtime has no type of the nil pointer dereference
+		return target == ErrNilDeref && strings.Contains(rtErr.Error(), "nil pointer dereference")
+	}
+	return false
+}
+
+// SetRecover sets whether to recover the panics when evaluating the expressions,
+// the default is true.
+// NOTE:
+//  The recovered panic makes the value nil, EvalWithError returns it as *EvalError;
+//  Disable it in tests to get the original panic and stack.
+func (vm *VM) SetRecover(enable bool) *VM {
+	vm.noRecover = !enable
+	return vm
+}
+
+// EvalWithError evaluate the value of the struct tag expression by the selector expression,
+// returns *EvalError if the evaluation panics.
+// NOTE:
+//  The error is ErrUnknownSelector if the selector does not exist;
+//  The error is ErrTypeMismatch if the result does not match the declared result type, such as bool in {@:bool: $>0}.
+func (t *TagExpr) EvalWithError(selector string) (interface{}, error) {
+	e, ok := t.s.getExpr(selector)
+	if !ok {
+		return nil, newKindError(ErrUnknownSelector, "unknown selector: %s", selector)
+	}
+	v, err := t.safeRun(e)
+	if err == nil {
+		err = e.expr.checkResultType(selector, v)
+	}
+	return v, err
+}
+
+// NotBoolError the result of the expression is not bool, such as nil or string
+type NotBoolError struct {
+	Selector string      // selector of the expression
+	Value    interface{} // the result
+}
+
+// Error implements error.
+func (e *NotBoolError) Error() string {
+	return fmt.Sprintf("%s: the result %v is not bool", e.Selector, e.Value)
+}
+
+// Is reports whether the target is ErrTypeMismatch.
+func (e *NotBoolError) Is(target error) bool {
+	return target == ErrTypeMismatch
+}
+
+// EvalBoolStrict evaluate the bool value of the struct tag expression by the selector expression,
+// distinguishes the false result from the one that cannot be evaluated, unlike EvalBool.
+// NOTE:
+//  The error is *NotBoolError if the result is not bool, such as nil;
+//  The other errors are the same as EvalWithError.
+func (t *TagExpr) EvalBoolStrict(selector string) (bool, error) {
+	v, err := t.EvalWithError(selector)
+	if err != nil {
+		return false, err
+	}
+	r, ok := v.(bool)
+	if !ok {
+		return false, &NotBoolError{Selector: selector, Value: v}
+	}
+	return r, nil
+}
+
+// safeRun runs the expression, recovers the panic if the vm enables it.
+func (t *TagExpr) safeRun(e *exprEntry) (v interface{}, err error) {
+	if t.getVM().noRecover {
+		return e.expr.run(e.field, t), nil
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			v, err = nil, &EvalError{Selector: e.selector, Panic: p, Stack: debug.Stack()}
+		}
+	}()
+	return e.expr.run(e.field, t), nil
+}
+
+// evalFrame the state of one evaluation, which is kept off the shared handler
+// so that the concurrent evaluations on the same handler do not race.
+type evalFrame struct {
+	dive      diveFrame // the element being iterated by the innermost count, any or all
+	diving    bool      // whether dive is set
+	unreached *bool     // set if the evaluation passed through an unreachable path, nil if not asked
+}
+
+// withFrame returns the copy of the handler that carries the frame, from the arena if any.
+// NOTE:
+//  It is only made when the state is needed, such as by count, any, all and EvalWithUnreached.
+func (t *TagExpr) withFrame(frame *evalFrame) *TagExpr {
+	var f *TagExpr
+	if t.arena != nil {
+		f = t.arena.newTagExpr()
+	} else {
+		f = new(TagExpr)
+	}
+	*f = *t
+	f.frame = frame
+	return f
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+const redactExprName = "redact"
+
+// DefaultRedactMask the mask of the redacted string fields
+const DefaultRedactMask = "***"
+
+// Redact returns a copy of the struct pointed by structPtr, with the fields masked
+// whose redact expressions, such as {redact:true}, are true or string.
+// NOTE:
+//  The string field is set to the string value of the expression, such as {redact:'[hidden]'},
+//  or DefaultRedactMask if the value is true; the fields of the other types are set to zero;
+//  The redact expressions are evaluated against the original struct;
+//  The structs on the pointer path to the redacted fields are copied, the original is never changed;
+//  The result is the pointer of the same type as structPtr, for logging the request bodies safely.
+func (vm *VM) Redact(structPtr interface{}) (interface{}, error) {
+	te, err := vm.Run(structPtr)
+	if err != nil {
+		return nil, err
+	}
+	src := reflect.ValueOf(structPtr)
+	dst := reflect.New(src.Type().Elem())
+	dst.Elem().Set(src.Elem())
+	for _, e := range te.s.redactions {
+		v, err := te.safeRun(e)
+		if err != nil {
+			return nil, err
+		}
+		mask, ok := v.(string)
+		if !ok {
+			if b, _ := v.(bool); !b {
+				continue
+			}
+			mask = DefaultRedactMask
+		}
+		if field, ok := cloneFieldPath(dst.Elem(), e.field); ok {
+			maskValue(field, mask)
+		}
+	}
+	return dst.Interface(), nil
+}
+
+// cloneFieldPath returns the settable field of the path such as A.B.C,
+// the structs pointed on the path are copied; false if any pointer is nil.
+func cloneFieldPath(v reflect.Value, path string) (reflect.Value, bool) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		v = v.FieldByName(name)
+		if !v.IsValid() {
+			return v, false
+		}
+		v = reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+		if i == len(names)-1 {
+			break
+		}
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return v, false
+			}
+			p := reflect.New(v.Type().Elem())
+			p.Elem().Set(v.Elem())
+			v.Set(p)
+			v = p.Elem()
+		}
+	}
+	return v, true
+}
+
+func maskValue(v reflect.Value, mask string) {
+	if v.Kind() == reflect.String {
+		v.SetString(mask)
+		return
+	}
+	v.Set(reflect.Zero(v.Type()))
+}
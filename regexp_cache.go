@@ -0,0 +1,113 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"regexp"
+	"sync"
+	"sync/atomic"
+)
+
+// RegexpCacheStats statistics of the compiled regular expressions shared in the vm
+type RegexpCacheStats struct {
+	// Patterns the number of the distinct patterns compiled
+	Patterns int
+	// Hits the number of the lookups that reused a compiled pattern
+	Hits uint64
+	// Misses the number of the lookups that compiled a new pattern
+	Misses uint64
+}
+
+// maxRegexpCachePatterns the max number of the patterns cached in a vm,
+// which bounds the memory of the patterns built at runtime, such as the ones of replace((A)$,(P)$,'')
+const maxRegexpCachePatterns = 4096
+
+type regexpCache struct {
+	hits, misses uint64 // first for the 64-bit alignment of the atomic operations on the 32-bit platforms
+	rw           sync.RWMutex
+	m            map[string]*regexp.Regexp
+}
+
+func newRegexpCache() *regexpCache {
+	return &regexpCache{m: make(map[string]*regexp.Regexp, 64)}
+}
+
+// defaultRegexpCache is used when the expression is not bound to any vm.
+// NOTE:
+//  It is a variable rather than a pointer to the composite literal, which is not 64-bit aligned on the 32-bit platforms;
+//  Its map is made by compile.
+var defaultRegexpCache regexpCache
+
+// compile returns the compiled regular expression of the pattern,
+// the same pattern is compiled only once.
+// NOTE:
+//  The pattern is compiled without caching if maxRegexpCachePatterns are cached.
+func (c *regexpCache) compile(pattern string) (*regexp.Regexp, error) {
+	c.rw.RLock()
+	re, ok := c.m[pattern]
+	c.rw.RUnlock()
+	if ok {
+		atomic.AddUint64(&c.hits, 1)
+		return re, nil
+	}
+	c.rw.Lock()
+	defer c.rw.Unlock()
+	if re, ok = c.m[pattern]; ok {
+		atomic.AddUint64(&c.hits, 1)
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&c.misses, 1)
+	if c.m == nil {
+		c.m = make(map[string]*regexp.Regexp, 64)
+	}
+	if len(c.m) < maxRegexpCachePatterns {
+		c.m[pattern] = re
+	}
+	return re, nil
+}
+
+func (c *regexpCache) stats() RegexpCacheStats {
+	c.rw.RLock()
+	n := len(c.m)
+	c.rw.RUnlock()
+	return RegexpCacheStats{
+		Patterns: n,
+		Hits:     atomic.LoadUint64(&c.hits),
+		Misses:   atomic.LoadUint64(&c.misses),
+	}
+}
+
+// RegexpCacheStats returns the statistics of the regular expressions shared
+// by all the structs registered in the vm.
+func (vm *VM) RegexpCacheStats() RegexpCacheStats {
+	return vm.regexpCache().stats()
+}
+
+// compileRegexp compiles the regular expression by the cache of the vm,
+// uses the default cache if vm is nil.
+func (vm *VM) compileRegexp(pattern string) (*regexp.Regexp, error) {
+	return vm.regexpCache().compile(pattern)
+}
+
+func (vm *VM) regexpCache() *regexpCache {
+	if vm == nil {
+		return &defaultRegexpCache
+	}
+	return vm.regexps
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+)
+
+// ruleCacheRegistry the rule caches registered by the generated files, tag name -> caches
+var ruleCacheRegistry = make(map[string][]string)
+
+// RegisterRuleCache registers the rule cache of the tag name, which is called by the file generated by WriteRegistry,
+// the vms of the tag name build the expressions from it instead of parsing them.
+// NOTE:
+//  The cache is loaded when the vm parses the first expression, and ignored if it mismatches the vm, see LoadRuleCache;
+//  It is not concurrency safe, should be called during initialization.
+func RegisterRuleCache(tagName string, data string) {
+	ruleCacheRegistry[tagName] = append(ruleCacheRegistry[tagName], data)
+}
+
+// WriteRegistry writes the Go file of the package, which registers the rule cache of the registered structs at init,
+// such as by go:generate with a program that warms up the vm by the structs of the service.
+// NOTE:
+//  The struct layouts are still registered by reflection at the first use, only the parsing is skipped.
+func (vm *VM) WriteRegistry(w io.Writer, pkgName string) error {
+	var data bytes.Buffer
+	if err := vm.SaveRuleCache(&data); err != nil {
+		return err
+	}
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by tagexpr.WriteRegistry. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", pkgName)
+	fmt.Fprintf(&src, "import tagexpr %q\n\n", "github.com/bytedance/go-tagexpr")
+	fmt.Fprintf(&src, "func init() {\n\ttagexpr.RegisterRuleCache(%q, %s)\n}\n", vm.tagName, strconv.Quote(data.String()))
+	b, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// LoadRegistry loads the rule caches registered by RegisterRuleCache for the tag name of the vm, once,
+// and returns the error of the first cache that is rejected, such as to fail fast at startup.
+// NOTE:
+//  It is called when the vm parses the first expression, the error is kept and returned by every call;
+//  The rejected cache is skipped, its expressions are parsed.
+func (vm *VM) LoadRegistry() error {
+	if vm.parent != nil {
+		vm = vm.parent
+	}
+	vm.registryOnce.Do(func() {
+		for i, data := range ruleCacheRegistry[vm.tagName] {
+			if err := vm.LoadRuleCache(bytes.NewReader([]byte(data))); err != nil && vm.registryErr == nil {
+				vm.registryErr = fmt.Errorf("tagexpr: rule cache %d of %q: %w", i, vm.tagName, err)
+			}
+		}
+	})
+	return vm.registryErr
+}
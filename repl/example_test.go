@@ -0,0 +1,50 @@
+package repl_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bytedance/go-tagexpr/repl"
+)
+
+func Example() {
+	type User struct {
+		Name string
+		Age  int
+	}
+	s := repl.New()
+	if err := s.Load(&User{Name: "Alice", Age: 17}); err != nil {
+		panic(err)
+	}
+	s.SetField("Age")
+	r, err := s.Eval("$>=18||(Name)$=='Alice'")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(r.Value)
+	for _, step := range r.Trace {
+		fmt.Printf("%s%s => %v\n", strings.Repeat("  ", step.Depth), step.Expr, step.Value)
+	}
+
+	s.LoadJSON([]byte(`{"user":{"name":"Bob","tags":["a","b"]},"user-id":7}`))
+	s.Run(strings.NewReader(":fields\nlen((User.Tags)$)==2&&(User_id)$>5\n:field User_id\n:trace\n$%2==1\n:quit\n"), os.Stdout)
+
+	// Output:
+	// true
+	// $ >= 18 || (Name)$ == 'Alice' => true
+	//   $ >= 18 => false
+	//     $ => 17
+	//   (Name)$ == 'Alice' => true
+	//     (Name)$ => Alice
+	// > User struct { Name string "json:\"name\""; Tags []interface {} "json:\"tags\"" }
+	// User.Name string
+	// User.Tags []interface {}
+	// User_id float64
+	// > true
+	// > > trace: true
+	// > true
+	//   $ % 2 => 1
+	//     $ => 7
+	// >
+}
@@ -0,0 +1,322 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package repl evaluates the ad-hoc struct tag expressions against a loaded struct value or JSON document,
+// for prototyping the complex rules interactively.
+package repl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+)
+
+// the tag name of the ad-hoc expression, not used by the loaded struct
+const tagName = "repl"
+
+// exprFieldName the name of the field that holds the expression if no current field is set
+const exprFieldName = "REPL_"
+
+// Result the evaluation result
+type Result struct {
+	Value interface{}
+	Trace []tagexpr.TraceStep
+}
+
+// Session the REPL session
+type Session struct {
+	value reflect.Value // struct value
+	field string
+}
+
+// New creates a REPL session.
+func New() *Session {
+	return &Session{}
+}
+
+// Load loads the struct value, the expressions select its fields by the go field names.
+func (s *Session) Load(structOrStructPtr interface{}) error {
+	v := reflect.ValueOf(structOrStructPtr)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("not structure pointer or structure: %s", v.Type())
+	}
+	s.value, s.field = v, ""
+	return nil
+}
+
+// LoadJSON loads the JSON object.
+// NOTE:
+//  The keys are selected by the exported names, such as (Name)$ for the key name and (User_id)$ for user-id;
+//  The nested objects are nested structs, such as (User.Name)$.
+func (s *Session) LoadJSON(data []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return errors.New("not JSON object")
+	}
+	s.value, s.field = jsonValue(doc), ""
+	return nil
+}
+
+// SetField sets the top-level field that $ refers to, "" clears it,
+// the nested fields are selected explicitly, such as (A.B)$.
+func (s *Session) SetField(name string) error {
+	if name != "" {
+		if strings.Contains(name, ".") {
+			return fmt.Errorf("not top-level field: %s", name)
+		}
+		if _, err := fieldByName(s.value, name); err != nil {
+			return err
+		}
+	}
+	s.field = name
+	return nil
+}
+
+// Fields returns the exported field names of the loaded value, the nested fields are included.
+func (s *Session) Fields() []string {
+	if !s.value.IsValid() {
+		return nil
+	}
+	var names []string
+	var walk func(t reflect.Type, nameSpace string, depth int)
+	walk = func(t reflect.Type, nameSpace string, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			names = append(names, nameSpace+f.Name)
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && depth < 8 {
+				walk(ft, nameSpace+f.Name+".", depth+1)
+			}
+		}
+	}
+	walk(s.value.Type(), "", 0)
+	return names
+}
+
+// Eval evaluates the expression against the loaded value.
+func (s *Session) Eval(expr string) (r Result, err error) {
+	if !s.value.IsValid() {
+		return r, errors.New("nothing loaded")
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+	ptr, selector := s.bind(expr)
+	te, err := tagexpr.New(tagName).Run(ptr.Interface())
+	if err != nil {
+		return r, err
+	}
+	r.Value = te.Eval(selector)
+	r.Trace, _ = te.Trace(selector)
+	return r, nil
+}
+
+// bind returns the pointer to the copy of the loaded value that holds the expression, and its selector.
+// NOTE:
+//  The copy has the exported fields, the expression is tagged on the current field.
+func (s *Session) bind(expr string) (reflect.Value, string) {
+	t := s.value.Type()
+	var fields []reflect.StructField
+	var index []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		f.Tag, f.Anonymous, f.Index, f.Offset = "", false, nil, 0
+		if f.Name == s.field {
+			f.Tag = reflect.StructTag(tagName + ":" + strconv.Quote(expr))
+		}
+		fields = append(fields, f)
+		index = append(index, i)
+	}
+	selector := s.field
+	if selector == "" {
+		fields = append(fields, reflect.StructField{
+			Name: exprFieldName,
+			Type: reflect.TypeOf((*interface{})(nil)).Elem(),
+			Tag:  reflect.StructTag(tagName + ":" + strconv.Quote(expr)),
+		})
+		selector = exprFieldName
+	}
+	ptr := reflect.New(reflect.StructOf(fields))
+	for i, j := range index {
+		ptr.Elem().Field(i).Set(s.value.Field(j))
+	}
+	return ptr, selector + "@"
+}
+
+func fieldByName(v reflect.Value, name string) (reflect.StructField, error) {
+	if !v.IsValid() {
+		return reflect.StructField{}, errors.New("nothing loaded")
+	}
+	t := v.Type()
+	var f reflect.StructField
+	for _, s := range strings.Split(name, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		var ok bool
+		if t.Kind() == reflect.Struct {
+			f, ok = t.FieldByName(s)
+		}
+		if !ok || f.PkgPath != "" {
+			return f, fmt.Errorf("field not found: %s", name)
+		}
+		t = f.Type
+	}
+	return f, nil
+}
+
+// jsonValue returns the value of the JSON document, the objects are converted into structs.
+func jsonValue(doc interface{}) reflect.Value {
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		if doc == nil {
+			return reflect.Zero(reflect.TypeOf((*interface{})(nil)).Elem())
+		}
+		return reflect.ValueOf(doc)
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]reflect.StructField, 0, len(keys))
+	values := make([]reflect.Value, 0, len(keys))
+	used := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		name := exportName(k)
+		for i := 2; used[name]; i++ {
+			name = exportName(k) + strconv.Itoa(i)
+		}
+		used[name] = true
+		v := jsonValue(obj[k])
+		fields = append(fields, reflect.StructField{
+			Name: name,
+			Type: v.Type(),
+			Tag:  reflect.StructTag(`json:` + strconv.Quote(k)),
+		})
+		values = append(values, v)
+	}
+	v := reflect.New(reflect.StructOf(fields)).Elem()
+	for i, fv := range values {
+		v.Field(i).Set(fv)
+	}
+	return v
+}
+
+// exportName returns the exported go identifier of the JSON key.
+func exportName(key string) string {
+	b := []byte(key)
+	for i, c := range b {
+		if c != '_' && (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			b[i] = '_'
+		}
+	}
+	switch {
+	case len(b) == 0 || b[0] == '_' || b[0] >= '0' && b[0] <= '9':
+		return "X" + string(b)
+	case b[0] >= 'a' && b[0] <= 'z':
+		b[0] -= 'a' - 'A'
+	}
+	return string(b)
+}
+
+// Run reads the commands and the expressions line by line, and writes the results.
+func (s *Session) Run(r io.Reader, w io.Writer) error {
+	trace := false
+	scanner := bufio.NewScanner(r)
+	fmt.Fprint(w, prompt)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		cmd, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			cmd, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		var err error
+		switch {
+		case line == "":
+		case cmd == ":quit" || cmd == ":q":
+			return nil
+		case cmd == ":help":
+			fmt.Fprint(w, help)
+		case cmd == ":load":
+			var data []byte
+			if data, err = ioutil.ReadFile(arg); err == nil {
+				err = s.LoadJSON(data)
+			}
+		case cmd == ":field":
+			err = s.SetField(arg)
+		case cmd == ":fields":
+			for _, name := range s.Fields() {
+				f, _ := fieldByName(s.value, name)
+				fmt.Fprintf(w, "%s %s\n", name, f.Type)
+			}
+		case cmd == ":trace":
+			trace = !trace
+			fmt.Fprintf(w, "trace: %v\n", trace)
+		case strings.HasPrefix(cmd, ":"):
+			err = fmt.Errorf("unknown command %s, try :help", cmd)
+		default:
+			var res Result
+			if res, err = s.Eval(line); err == nil {
+				fmt.Fprintf(w, "%#v\n", res.Value)
+				if trace && len(res.Trace) > 1 {
+					for _, step := range res.Trace[1:] {
+						fmt.Fprintf(w, "%s%s => %#v\n", strings.Repeat("  ", step.Depth), step.Expr, step.Value)
+					}
+				}
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(w, "error: %s\n", err.Error())
+		}
+		fmt.Fprint(w, prompt)
+	}
+	return scanner.Err()
+}
+
+const prompt = "> "
+
+const help = `:load file.json  load the JSON object
+:field Name      set the top-level field that $ refers to, empty to clear it
+:fields          list the fields
+:trace           toggle the trace of the sub-expressions
+:quit            quit
+otherwise        evaluate the expression, such as len((Name)$)>3
+`
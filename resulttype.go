@@ -0,0 +1,126 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+)
+
+// resultTypeRegexp matches the declared result type before the expression, such as bool: in {@:bool: $>0}
+var resultTypeRegexp = regexp.MustCompile(`^\s*(bool|string|number)\s*:`)
+
+// parseTypedExpr parses the expression of the selector with the optional declared result type, such as bool: $>0,
+// and checks the result type if it can be inferred statically.
+func parseTypedExpr(vm *VM, selector, exprStr string) (*Expr, error) {
+	var typ string
+	var prefix int
+	src := exprStr
+	if m := resultTypeRegexp.FindStringSubmatch(exprStr); m != nil {
+		typ = m[1]
+		prefix = len(m[0])
+		exprStr = exprStr[prefix:]
+	}
+	expr, err := parseVMExpr(vm, exprStr)
+	if err != nil {
+		if serr, ok := err.(*SyntaxError); ok && prefix > 0 {
+			// the offset is of the source with the declared result type
+			serr.Expr = src
+			serr.Offset += prefix
+		}
+		return nil, err
+	}
+	if typ == "" {
+		return expr, nil
+	}
+	if inferred := inferResultType(expr.AST()); inferred != "" && inferred != typ {
+		return nil, newKindError(ErrTypeMismatch, "%s: the result is %s, declared %s", selector, inferred, typ)
+	}
+	expr.resultType = typ
+	return expr, nil
+}
+
+// ResultType returns the declared result type, bool, string or number, "" if it is not declared.
+func (p *Expr) ResultType() string {
+	return p.resultType
+}
+
+// checkResultType returns the error if the value does not match the declared result type.
+func (p *Expr) checkResultType(selector string, v interface{}) error {
+	if p.resultType == "" {
+		return nil
+	}
+	if typ := valueResultType(v); typ != p.resultType {
+		switch {
+		case v == nil:
+			typ = "nil"
+		case typ == "":
+			typ = fmt.Sprintf("%T", v)
+		}
+		return newKindError(ErrTypeMismatch, "%s: the result %v is %s, declared %s", selector, v, typ, p.resultType)
+	}
+	return nil
+}
+
+func valueResultType(v interface{}) string {
+	switch v.(type) {
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case float64, *big.Rat:
+		return "number"
+	}
+	return ""
+}
+
+// inferResultType returns the result type of the node, "" if it is unknown until evaluation.
+func inferResultType(n *Node) string {
+	if n == nil {
+		return ""
+	}
+	if n.Prefix != "" {
+		return "bool"
+	}
+	switch n.Kind {
+	case LiteralNode:
+		if n.Value == nil {
+			return "nil"
+		}
+		return valueResultType(n.Value)
+	case GroupNode:
+		return inferResultType(n.Args[0])
+	case OperatorNode:
+		switch n.Op {
+		case "==", "!=", ">", ">=", "<", "<=", "&&", "||":
+			return "bool"
+		case "-", "*", "/", "%":
+			return "number"
+		case "+":
+			return inferResultType(n.Args[0])
+		}
+	case FuncNode:
+		switch n.Name {
+		case "regexp", "exists", "missing", "any", "all":
+			return "bool"
+		case "len", "count":
+			return "number"
+		case "sprintf":
+			return "string"
+		}
+	}
+	return ""
+}
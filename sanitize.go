@@ -0,0 +1,103 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"math"
+	"reflect"
+)
+
+const sanExprName = "san"
+
+// Sanitize evaluates the san expressions, such as {san:lower(trim($))},
+// and sets the values to their fields in the registration order.
+// NOTE:
+//  The number value is converted to the numeric field type, such as clamp($,0,100) to int,
+//  the value that is out of the range of the type, or not an integer for the integer type, is not converted;
+//  The field is not changed if the value is nil, or the field is not reachable;
+//  The pointer fields are set through, the nil pointer is not allocated;
+//  The error is ErrTypeMismatch if the value cannot be set to the field.
+func (t *TagExpr) Sanitize() error {
+	return t.assign(t.s.sanitizers, sanExprName)
+}
+
+// assign evaluates the expressions in order and sets the values to their fields.
+func (t *TagExpr) assign(entries []*exprEntry, phase string) error {
+	for _, e := range entries {
+		v, err := t.safeRun(e)
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			continue
+		}
+		f, ok := t.Field(e.field)
+		if !ok || !f.Reachable() {
+			continue
+		}
+		if err = setExprValue(f.Value(), v); err != nil {
+			return newKindError(ErrTypeMismatch, "%s %s: %s", phase, e.field, err.Error())
+		}
+	}
+	return nil
+}
+
+func setExprValue(field reflect.Value, v interface{}) error {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+	switch r := v.(type) {
+	case float64:
+		switch field.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if !field.OverflowFloat(r) {
+				field.SetFloat(r)
+				return nil
+			}
+			return newKindError(ErrTypeMismatch, "%v overflows %s", r, field.Type().String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			// the float64 of math.MaxInt64 is 2^63, which is out of the range
+			if r == math.Trunc(r) && r >= math.MinInt64 && r < math.MaxInt64 && !field.OverflowInt(int64(r)) {
+				field.SetInt(int64(r))
+				return nil
+			}
+			return newKindError(ErrTypeMismatch, "%v is not an integer in the range of %s", r, field.Type().String())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if r == math.Trunc(r) && r >= 0 && r < math.MaxUint64 && !field.OverflowUint(uint64(r)) {
+				field.SetUint(uint64(r))
+				return nil
+			}
+			return newKindError(ErrTypeMismatch, "%v is not an integer in the range of %s", r, field.Type().String())
+		}
+	case string:
+		if field.Kind() == reflect.String {
+			field.SetString(r)
+			return nil
+		}
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8 {
+			field.SetBytes([]byte(r))
+			return nil
+		}
+	case bool:
+		if field.Kind() == reflect.Bool {
+			field.SetBool(r)
+			return nil
+		}
+	}
+	return newKindError(ErrTypeMismatch, "cannot set %T to %s", v, field.Type().String())
+}
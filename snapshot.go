@@ -0,0 +1,114 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"reflect"
+	"sort"
+)
+
+// maxSnapshotDepth the nesting depth of the collections and pointers copied into the snapshot,
+// the deeper values are nil, which also stops the cyclic pointers.
+const maxSnapshotDepth = 32
+
+// Snapshot the values of all the fields captured by TagExpr.Snapshot, keyed by the field selector,
+// such as fieldName, fieldName1.fieldName2.
+// NOTE:
+//  The values are the expression values, such as float64 for the numbers;
+//  The slices and arrays are copied as []interface{}, the maps as map[interface{}]interface{},
+//  and the struct elements as map[string]interface{} of their exported fields.
+type Snapshot map[string]interface{}
+
+// Snapshot captures the values of all the fields reachable by the selectors, including the nested ones,
+// the field of an unreachable parent is nil.
+// NOTE:
+//  The struct fields are not captured as a whole, their own fields are;
+//  The collections are copied, so that the later changes of the struct do not affect the snapshot.
+func (t *TagExpr) Snapshot() Snapshot {
+	snap := make(Snapshot, len(t.s.fields))
+	for name, f := range t.s.fields {
+		if f.valueGetter == nil {
+			continue
+		}
+		h := FieldHandle{field: f, addr: f.addrGetter(t.ptr)}
+		snap[name] = snapshotValue(h.Value(), 0)
+	}
+	return snap
+}
+
+// Diff returns the sorted field selectors whose values differ between the snapshot and the newer one,
+// such as the old and new versions of a struct for the audit logging.
+// NOTE:
+//  Evaluate the rules of the changed field by EvalGlob(field + "@*").
+func (s Snapshot) Diff(newer Snapshot) []string {
+	var changed []string
+	for name, v := range s {
+		if nv, ok := newer[name]; !ok || !reflect.DeepEqual(v, nv) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range newer {
+		if _, ok := s[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func snapshotValue(vv reflect.Value, depth int) interface{} {
+	if depth > maxSnapshotDepth {
+		return nil
+	}
+	for vv.Kind() == reflect.Ptr || vv.Kind() == reflect.Interface {
+		if vv.IsNil() {
+			return nil
+		}
+		vv = vv.Elem()
+	}
+	switch vv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if vv.Kind() == reflect.Slice && (vv.IsNil() || vv.Type().Elem().Kind() == reflect.Uint8) {
+			return toExprValue(vv)
+		}
+		r := make([]interface{}, vv.Len())
+		for i := range r {
+			r[i] = snapshotValue(vv.Index(i), depth+1)
+		}
+		return r
+	case reflect.Map:
+		if vv.IsNil() {
+			return nil
+		}
+		r := make(map[interface{}]interface{}, vv.Len())
+		iter := vv.MapRange()
+		for iter.Next() {
+			r[toExprValue(iter.Key())] = snapshotValue(iter.Value(), depth+1)
+		}
+		return r
+	case reflect.Struct:
+		if r, ok := adaptNumber(vv); ok {
+			return r
+		}
+		r := make(map[string]interface{}, vv.NumField())
+		for i := 0; i < vv.NumField(); i++ {
+			if sf := vv.Type().Field(i); sf.PkgPath == "" {
+				r[sf.Name] = snapshotValue(vv.Field(i), depth+1)
+			}
+		}
+		return r
+	}
+	return toExprValue(vv)
+}
@@ -40,7 +40,7 @@ func (p *Expr) readLenFnExprNode(expr *string) ExprNode {
 	if operand == nil {
 		return nil
 	}
-	_, err := p.parseExprNode(subExprNode, operand)
+	_, err := p.parseSubExprNode(subExprNode, operand)
 	if err != nil {
 		*expr = lastStr
 		return nil
@@ -83,7 +83,7 @@ func (p *Expr) readRegexpFnExprNode(expr *string) ExprNode {
 		*expr = lastStr
 		return nil
 	}
-	rege, err := regexp.Compile(*s)
+	rege, err := p.vm.compileRegexp(*s)
 	if err != nil {
 		*expr = lastStr
 		return nil
@@ -92,14 +92,14 @@ func (p *Expr) readRegexpFnExprNode(expr *string) ExprNode {
 	trimLeftSpace(subExprNode)
 	if strings.HasPrefix(*subExprNode, ",") {
 		*subExprNode = (*subExprNode)[1:]
-		_, err = p.parseExprNode(trimLeftSpace(subExprNode), operand)
+		_, err = p.parseSubExprNode(trimLeftSpace(subExprNode), operand)
 		if err != nil {
 			*expr = lastStr
 			return nil
 		}
 	} else {
 		var currFieldVal = "$"
-		p.parseExprNode(&currFieldVal, operand)
+		p.parseSubExprNode(&currFieldVal, operand)
 	}
 	trimLeftSpace(subExprNode)
 	if *subExprNode != "" {
@@ -150,7 +150,7 @@ func (p *Expr) readSprintfFnExprNode(expr *string) ExprNode {
 		return nil
 	}
 	e := &sprintfFnExprNode{
-		format: *format,
+		format: unescapeString(*format),
 	}
 	for {
 		trimLeftSpace(subExprNode)
@@ -160,7 +160,7 @@ func (p *Expr) readSprintfFnExprNode(expr *string) ExprNode {
 		if strings.HasPrefix(*subExprNode, ",") {
 			*subExprNode = (*subExprNode)[1:]
 			operand := newGroupExprNode()
-			_, err := p.parseExprNode(trimLeftSpace(subExprNode), operand)
+			_, err := p.parseSubExprNode(trimLeftSpace(subExprNode), operand)
 			if err != nil {
 				*expr = lastStr
 				return nil
@@ -177,10 +177,202 @@ func (p *Expr) readSprintfFnExprNode(expr *string) ExprNode {
 func (se *sprintfFnExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
 	var args []interface{}
 	if n := len(se.args); n > 0 {
-		args = make([]interface{}, n)
+		args = tagExpr.allocArgs(n)
 		for i, e := range se.args {
 			args[i] = e.Run(currField, tagExpr)
 		}
 	}
 	return fmt.Sprintf(se.format, args...)
 }
+
+var funcList = make(map[string]func(*TagExpr, ...interface{}) interface{})
+
+// RegFunc registers function expression.
+// NOTE:
+//  example: iban($), phone($,'US');
+//  If @force=true, allow to cover the existed same @funcName;
+//  @fn must not be nil;
+//  The go number types always are float64;
+//  The go string types always are string;
+//  The args can be kept by @fn, they are not allocated from the arena of RunWithArena;
+//  It is not concurrency safe, should be called during initialization.
+func RegFunc(funcName string, fn func(...interface{}) interface{}, force ...bool) error {
+	if !funcNameRegexp.MatchString(funcName) {
+		return fmt.Errorf("invalid expression function name: %q", funcName)
+	}
+	if fn == nil {
+		return fmt.Errorf("nil expression function: %s", funcName)
+	}
+	if len(force) == 0 || !force[0] {
+		if _, ok := funcList[funcName]; ok {
+			return fmt.Errorf("duplicate registration expression function: %s", funcName)
+		}
+	}
+	funcList[funcName] = func(t *TagExpr, args ...interface{}) interface{} {
+		if t != nil && t.arena != nil {
+			// fn may keep the args, which must not be reused after the arena is reset
+			args = append([]interface{}(nil), args...)
+		}
+		return fn(args...)
+	}
+	delete(pureFuncs, funcName)
+	customFuncs[funcName] = true
+	return nil
+}
+
+// customFuncs the functions registered by RegFunc, which may have side effects.
+var customFuncs = make(map[string]bool)
+
+// pureFuncs the built-in functions whose result only depends on the arguments,
+// so that they can be folded at parse time when the arguments are constant.
+var pureFuncs = make(map[string]bool)
+
+// regFunc registers the pure built-in function, panic when it is duplicate.
+func regFunc(funcName string, fn func(*TagExpr, ...interface{}) interface{}) {
+	regVMFunc(funcName, fn)
+	pureFuncs[funcName] = true
+}
+
+// regVMFunc registers the built-in function that depends on the vm configuration,
+// panic when it is duplicate.
+func regVMFunc(funcName string, fn func(*TagExpr, ...interface{}) interface{}) {
+	if _, ok := funcList[funcName]; ok {
+		panic("duplicate registration built-in function: " + funcName)
+	}
+	funcList[funcName] = fn
+}
+
+var funcNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var funcSignRegexp = regexp.MustCompile(`^(\!*)([A-Za-z_][A-Za-z0-9_]*)\(`)
+
+type funcExprNode struct {
+	exprBackground
+	name       string
+	fn         func(*TagExpr, ...interface{}) interface{}
+	args       []ExprNode
+	boolPrefix *bool
+}
+
+func (p *Expr) readFuncExprNode(expr *string) ExprNode {
+	var fn func(*TagExpr, ...interface{}) interface{}
+	name, boolPrefix, args, found := p.readFuncCall(expr, func(name string) (ok bool) {
+		fn, ok = funcList[name]
+		return
+	})
+	if !found {
+		return nil
+	}
+	return &funcExprNode{
+		name:       name,
+		fn:         fn,
+		args:       args,
+		boolPrefix: boolPrefix,
+	}
+}
+
+// readFuncCall reads the function call whose name is accepted,
+// returns the function name, the bool prefix and the arguments.
+func (p *Expr) readFuncCall(expr *string, accept func(name string) bool) (name string, boolPrefix *bool, args []ExprNode, found bool) {
+	a := funcSignRegexp.FindStringSubmatch(*expr)
+	if a == nil || !accept(a[2]) {
+		return
+	}
+	lastStr := *expr
+	*expr = (*expr)[len(a[0])-1:]
+	subExprNode := readPairedSymbol(expr, '(', ')')
+	if subExprNode == nil {
+		*expr = lastStr
+		return
+	}
+	name = a[2]
+	if boolNum := len(a[1]); boolNum > 0 {
+		bol := true
+		for i := boolNum; i > 0; i-- {
+			bol = !bol
+		}
+		boolPrefix = &bol
+	}
+	if trimLeftSpace(subExprNode); *subExprNode == "" {
+		found = true
+		return
+	}
+	*subExprNode = "," + *subExprNode
+	for {
+		trimLeftSpace(subExprNode)
+		if len(*subExprNode) == 0 {
+			found = true
+			return
+		}
+		if !strings.HasPrefix(*subExprNode, ",") {
+			*expr = lastStr
+			return "", nil, nil, false
+		}
+		*subExprNode = (*subExprNode)[1:]
+		operand := newGroupExprNode()
+		_, err := p.parseSubExprNode(trimLeftSpace(subExprNode), operand)
+		if err != nil || operand.RightOperand() == nil {
+			*expr = lastStr
+			return "", nil, nil, false
+		}
+		sortPriority(operand.RightOperand())
+		args = append(args, operand)
+	}
+}
+
+// applyBoolPrefix applies the '!' prefix to the value, nil if it is not bool.
+func applyBoolPrefix(v interface{}, boolPrefix *bool) interface{} {
+	if boolPrefix == nil {
+		return v
+	}
+	if r, ok := v.(bool); ok {
+		return *boolPrefix == r
+	}
+	return nil
+}
+
+func (fe *funcExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
+	var args []interface{}
+	if n := len(fe.args); n > 0 {
+		args = tagExpr.allocArgs(n)
+		for i, e := range fe.args {
+			args[i] = e.Run(currField, tagExpr)
+		}
+	}
+	return applyBoolPrefix(fe.fn(tagExpr, args...), fe.boolPrefix)
+}
+
+// getStringArg returns the i-th argument of string type.
+func getStringArg(args []interface{}, i int) (string, bool) {
+	if i >= len(args) {
+		return "", false
+	}
+	s, ok := args[i].(string)
+	return s, ok
+}
+
+// matchAnyStringArg returns whether any of the string arguments matches,
+// nil if any argument is not string.
+func matchAnyStringArg(args []interface{}, match func(string) bool) interface{} {
+	var r bool
+	for _, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			return nil
+		}
+		r = r || match(s)
+	}
+	return r
+}
+
+// newStringPredicate creates a built-in function that checks the first string argument,
+// the result is nil if the argument is not string.
+func newStringPredicate(fn func(string) bool) func(*TagExpr, ...interface{}) interface{} {
+	return func(_ *TagExpr, args ...interface{}) interface{} {
+		s, ok := getStringArg(args, 0)
+		if !ok {
+			return nil
+		}
+		return fn(s)
+	}
+}
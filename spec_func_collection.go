@@ -0,0 +1,270 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// --------------------------- Built-in function: collection ---------------------------
+
+func init() {
+	regFunc("keys", func(_ *TagExpr, args ...interface{}) interface{} {
+		v, ok := getCollectionArg(args, 0)
+		if !ok || v.Kind() != reflect.Map {
+			return nil
+		}
+		r := make([]interface{}, 0, v.Len())
+		rangeCollection(v, func(k, _ reflect.Value) bool {
+			r = append(r, toExprValue(k))
+			return true
+		})
+		return r
+	})
+	regFunc("values", func(_ *TagExpr, args ...interface{}) interface{} {
+		v, ok := getCollectionArg(args, 0)
+		if !ok {
+			return nil
+		}
+		r := make([]interface{}, 0, v.Len())
+		rangeCollection(v, func(_, e reflect.Value) bool {
+			r = append(r, toExprValue(e))
+			return true
+		})
+		return r
+	})
+	regFunc("unique", func(_ *TagExpr, args ...interface{}) interface{} {
+		v, ok := getCollectionArg(args, 0)
+		if !ok {
+			return nil
+		}
+		seen := make(map[interface{}]bool, v.Len())
+		unique := true
+		rangeCollection(v, func(_, e reflect.Value) bool {
+			val := toExprValue(e)
+			if !isHashable(val) || seen[val] {
+				unique = false
+				return false
+			}
+			seen[val] = true
+			return true
+		})
+		return unique
+	})
+	regFunc("sorted", newMonotonicFunc(false))
+	regFunc("ascending", newMonotonicFunc(true))
+}
+
+// newMonotonicFunc creates the function that checks whether the elements are in increasing order,
+// the result is nil if the elements are not all numbers or all strings.
+func newMonotonicFunc(strict bool) func(*TagExpr, ...interface{}) interface{} {
+	return func(_ *TagExpr, args ...interface{}) interface{} {
+		v, ok := getCollectionArg(args, 0)
+		if !ok || v.Kind() == reflect.Map {
+			return nil
+		}
+		var r interface{} = true
+		var last interface{}
+		rangeCollection(v, func(_, e reflect.Value) bool {
+			curr := toExprValue(e)
+			switch curr.(type) {
+			case float64, string:
+			default:
+				r = nil
+				return false
+			}
+			if last != nil {
+				if reflect.TypeOf(last) != reflect.TypeOf(curr) {
+					r = nil
+					return false
+				}
+				if lessValue(curr, last) || strict && !lessValue(last, curr) {
+					r = false
+				}
+			}
+			last = curr
+			return true
+		})
+		return r
+	}
+}
+
+type diveFnExprNode struct {
+	exprBackground
+	name                  string
+	collection, predicate ExprNode
+	boolPrefix            *bool
+}
+
+// readDiveFnExprNode reads the function that tests each element of the collection,
+// such as count($,#v>0), any($,#v=='a'), all($,#k==0||#v!=nil), #k is the index or key;
+// the element is tested for truthy if the predicate is omitted.
+func (p *Expr) readDiveFnExprNode(expr *string) ExprNode {
+	lastStr := *expr
+	name, boolPrefix, args, found := p.readFuncCall(expr, func(name string) bool {
+		return name == "count" || name == "any" || name == "all"
+	})
+	if !found {
+		return nil
+	}
+	if len(args) == 0 || len(args) > 2 {
+		*expr = lastStr
+		return nil
+	}
+	e := &diveFnExprNode{
+		name:       name,
+		collection: args[0],
+		boolPrefix: boolPrefix,
+	}
+	if len(args) == 2 {
+		e.predicate = args[1]
+	}
+	return e
+}
+
+func (de *diveFnExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
+	v, ok := getCollectionArg([]interface{}{de.collection.Run(currField, tagExpr)}, 0)
+	if !ok {
+		return nil
+	}
+	if tagExpr == nil {
+		tagExpr = &TagExpr{}
+	}
+	if de.predicate != nil {
+		frame := &evalFrame{diving: true}
+		if tagExpr.frame != nil {
+			frame.unreached = tagExpr.frame.unreached
+		}
+		tagExpr = tagExpr.withFrame(frame)
+	}
+	var n int
+	var r interface{}
+	switch de.name {
+	case "count":
+		rangeCollection(v, func(k, e reflect.Value) bool {
+			if de.test(currField, tagExpr, k, e) {
+				n++
+			}
+			return true
+		})
+		r = float64(n)
+	case "any":
+		r = false
+		rangeCollection(v, func(k, e reflect.Value) bool {
+			if de.test(currField, tagExpr, k, e) {
+				r = true
+				return false
+			}
+			return true
+		})
+	case "all":
+		r = true
+		rangeCollection(v, func(k, e reflect.Value) bool {
+			if !de.test(currField, tagExpr, k, e) {
+				r = false
+				return false
+			}
+			return true
+		})
+	}
+	return applyBoolPrefix(r, de.boolPrefix)
+}
+
+func (de *diveFnExprNode) test(currField string, tagExpr *TagExpr, key, elem reflect.Value) bool {
+	v := toExprValue(elem)
+	if de.predicate == nil {
+		return isTruthy(v)
+	}
+	tagExpr.frame.dive = diveFrame{k: toExprValue(key), v: v}
+	return isTruthy(de.predicate.Run(currField, tagExpr))
+}
+
+// isTruthy reports whether v is true, non-zero number or non-empty string.
+func isTruthy(v interface{}) bool {
+	switch r := v.(type) {
+	case float64:
+		return r != 0
+	case string:
+		return r != ""
+	case bool:
+		return r
+	}
+	return false
+}
+
+// rangeCollection calls fn for each key and element of the slice, array or map,
+// the map is ranged in the order of the sorted keys.
+func rangeCollection(v reflect.Value, fn func(k, e reflect.Value) bool) {
+	if v.Kind() != reflect.Map {
+		for i := 0; i < v.Len(); i++ {
+			if !fn(reflect.ValueOf(i), v.Index(i)) {
+				return
+			}
+		}
+		return
+	}
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return lessValue(toExprValue(keys[i]), toExprValue(keys[j]))
+	})
+	for _, k := range keys {
+		if !fn(k, v.MapIndex(k)) {
+			return
+		}
+	}
+}
+
+// getCollectionArg returns the i-th argument of slice, array or map type.
+func getCollectionArg(args []interface{}, i int) (reflect.Value, bool) {
+	if i >= len(args) || args[i] == nil {
+		return reflect.Value{}, false
+	}
+	v := reflect.ValueOf(args[i])
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v, true
+	}
+	return reflect.Value{}, false
+}
+
+func isHashable(v interface{}) bool {
+	return v == nil || reflect.TypeOf(v).Comparable()
+}
+
+// lessValue reports whether a is less than b,
+// the values of different types are ordered by type name.
+func lessValue(a, b interface{}) bool {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x < y
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return x < y
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			return !x && y
+		}
+	}
+	ta, tb := fmt.Sprintf("%T", a), fmt.Sprintf("%T", b)
+	if ta != tb {
+		return ta < tb
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// --------------------------- Built-in function: color ---------------------------
+
+func init() {
+	regFunc("hexcolor", newStringPredicate(hexColorRegexp.MatchString))
+	regFunc("rgb", newStringPredicate(func(s string) bool { return isRGB(s, "rgb", 3) }))
+	regFunc("rgba", newStringPredicate(func(s string) bool { return isRGB(s, "rgba", 4) }))
+}
+
+// hexColorRegexp matches the CSS hex colors of #RGB, #RGBA, #RRGGBB and #RRGGBBAA.
+var hexColorRegexp = regexp.MustCompile(`^#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// isRGB checks the CSS functional notation, such as 'rgb(255, 0, 0)', 'rgb(100%, 0%, 0%)' and 'rgba(255, 0, 0, 0.5)',
+// the channels are integers in [0, 255] or percentages, the alpha is a number in [0, 1] or a percentage.
+func isRGB(s, fn string, n int) bool {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if !strings.HasPrefix(s, fn+"(") || !strings.HasSuffix(s, ")") {
+		return false
+	}
+	a := strings.Split(s[len(fn)+1:len(s)-1], ",")
+	if len(a) != n {
+		return false
+	}
+	var percent int
+	for i, c := range a {
+		c = strings.TrimSpace(c)
+		if i == 3 {
+			return isColorAlpha(c)
+		}
+		if strings.HasSuffix(c, "%") {
+			percent++
+			if !isPercentage(c) {
+				return false
+			}
+			continue
+		}
+		v, err := strconv.Atoi(c)
+		if err != nil || v < 0 || v > 255 {
+			return false
+		}
+	}
+	// the channels are all integers or all percentages
+	return percent == 0 || percent == 3
+}
+
+// isPercentage checks the number in [0, 100] followed by '%'.
+func isPercentage(s string) bool {
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	return err == nil && f >= 0 && f <= 100
+}
+
+func isColorAlpha(s string) bool {
+	if strings.HasSuffix(s, "%") {
+		return isPercentage(s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return err == nil && f >= 0 && f <= 1
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// --------------------------- Built-in function: encoding ---------------------------
+
+func init() {
+	regFunc("isbase64", newBytesPredicate(isBase64))
+	regFunc("ishex", newBytesPredicate(func(b []byte) bool {
+		_, err := hex.DecodeString(string(b))
+		return len(b) > 0 && err == nil
+	}))
+	regFunc("isjson", newBytesPredicate(json.Valid))
+	regFunc("md5", newHashFunc(func(b []byte) []byte { r := md5.Sum(b); return r[:] }))
+	regFunc("sha1", newHashFunc(func(b []byte) []byte { r := sha1.Sum(b); return r[:] }))
+	regFunc("sha256", newHashFunc(func(b []byte) []byte { r := sha256.Sum256(b); return r[:] }))
+}
+
+// newHashFunc creates a built-in function that returns the lowercase hex digest
+// of the first argument of string or []byte type.
+func newHashFunc(sum func([]byte) []byte) func(*TagExpr, ...interface{}) interface{} {
+	return func(_ *TagExpr, args ...interface{}) interface{} {
+		if len(args) == 0 {
+			return nil
+		}
+		switch v := args[0].(type) {
+		case string:
+			return hex.EncodeToString(sum([]byte(v)))
+		case []byte:
+			return hex.EncodeToString(sum(v))
+		}
+		return nil
+	}
+}
+
+// newBytesPredicate creates a built-in function that checks the first argument of string or []byte,
+// the result is nil if the argument is other type.
+func newBytesPredicate(fn func([]byte) bool) func(*TagExpr, ...interface{}) interface{} {
+	return func(_ *TagExpr, args ...interface{}) interface{} {
+		if len(args) == 0 {
+			return nil
+		}
+		switch v := args[0].(type) {
+		case string:
+			return fn([]byte(v))
+		case []byte:
+			return fn(v)
+		}
+		return nil
+	}
+}
+
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding, base64.URLEncoding,
+	base64.RawStdEncoding, base64.RawURLEncoding,
+}
+
+// isBase64 reports whether b is standard or URL base64 encoded, padded or not.
+func isBase64(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	dst := make([]byte, len(b))
+	for _, enc := range base64Encodings {
+		if _, err := enc.Decode(dst, b); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// --------------------------- Built-in function: enum ---------------------------
+
+func init() {
+	regVMFunc("enum", func(t *TagExpr, args ...interface{}) interface{} {
+		name, ok := getStringArg(args, 0)
+		if !ok || len(args) != 2 {
+			return nil
+		}
+		vm := t.getVM()
+		if vm == nil {
+			return nil
+		}
+		set, ok := vm.enums[name]
+		if !ok {
+			return nil
+		}
+		if !isHashable(args[1]) {
+			return false
+		}
+		return set[args[1]]
+	})
+}
+
+// RegisterEnum registers the enum set used by the built-in function enum,
+// such as vm.RegisterEnum("status", []string{"open", "closed"}) for enum('status',$).
+// NOTE:
+//  @values must be slice or array of the number, string or bool types;
+//  It should be called before the vm is used.
+func (vm *VM) RegisterEnum(name string, values interface{}) error {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return fmt.Errorf("enum values must be slice or array: %T", values)
+	}
+	set := make(map[interface{}]bool, v.Len())
+	for i := v.Len() - 1; i >= 0; i-- {
+		elem := v.Index(i)
+		switch elem.Kind() {
+		case reflect.String:
+			set[elem.String()] = true
+		case reflect.Bool:
+			set[elem.Bool()] = true
+		case reflect.Float32, reflect.Float64,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			set[elem.Convert(float64Type).Float()] = true
+		default:
+			return fmt.Errorf("unsupported enum value type: %s", elem.Type().String())
+		}
+	}
+	if vm.enums == nil {
+		vm.enums = make(map[string]map[interface{}]bool)
+	}
+	vm.enums[name] = set
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+// --------------------------- Built-in function: exists ---------------------------
+
+type existsFnExprNode struct {
+	exprBackground
+	name       string
+	boolPrefix *bool
+}
+
+// readExistsFnExprNode reads the function that reports whether the path is present, regardless of its value,
+// such as exists((M)$['k']), missing((P.X)$), exists('P.X');
+// the argument is the selector, or the field path string, the current field if it is omitted.
+func (p *Expr) readExistsFnExprNode(expr *string) ExprNode {
+	lastStr := *expr
+	name, boolPrefix, args, found := p.readFuncCall(expr, func(name string) bool {
+		return name == "exists" || name == "missing"
+	})
+	if !found {
+		return nil
+	}
+	switch len(args) {
+	case 0:
+		var currFieldVal = "$"
+		operand := newGroupExprNode()
+		p.parseSubExprNode(&currFieldVal, operand)
+		args = append(args, operand)
+	case 1:
+	default:
+		*expr = lastStr
+		return nil
+	}
+	e := &existsFnExprNode{
+		name:       name,
+		boolPrefix: boolPrefix,
+	}
+	e.SetRightOperand(args[0])
+	return e
+}
+
+func (ee *existsFnExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
+	r := ee.exists(currField, tagExpr)
+	if ee.name == "missing" {
+		r = !r
+	}
+	if ee.boolPrefix != nil {
+		r = *ee.boolPrefix == r
+	}
+	return r
+}
+
+func (ee *existsFnExprNode) exists(currField string, tagExpr *TagExpr) bool {
+	switch r := unwrapGroup(ee.rightOperand).(type) {
+	case *selectorExprNode:
+		var subFields []interface{}
+		if n := len(r.subExprs); n > 0 {
+			subFields = tagExpr.allocArgs(n)
+			for i, e := range r.subExprs {
+				subFields[i] = e.Run(currField, tagExpr)
+			}
+		}
+		field := r.field
+		if field == "" {
+			field = currField
+		}
+		return tagExpr.existsValue(field, subFields)
+	case *stringExprNode:
+		return tagExpr.existsValue(r.val, nil)
+	}
+	v := ee.rightOperand.Run(currField, tagExpr)
+	return v != nil && !IsUnreachable(v)
+}
@@ -0,0 +1,189 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// --------------------------- Built-in function: finance ---------------------------
+
+func init() {
+	regFunc("iban", newStringPredicate(isIBAN))
+	regFunc("bic", newStringPredicate(bicRegexp.MatchString))
+	regFunc("ccbrand", func(_ *TagExpr, args ...interface{}) interface{} {
+		s, ok := getStringArg(args, 0)
+		if !ok {
+			return nil
+		}
+		return cardBrand(s)
+	})
+	regFunc("money", func(_ *TagExpr, args ...interface{}) interface{} {
+		currency, ok := getStringArg(args, 1)
+		if !ok || len(args) != 2 {
+			return nil
+		}
+		if r, ok := moneyAmount(args[0], currency); ok {
+			return r
+		}
+		return nil
+	})
+}
+
+var decimalRegexp = regexp.MustCompile(`^[+-]?\d+(\.\d+)?$`)
+
+// moneyAmount returns the exact amount of the decimal string, float64 or *big.Rat in the currency,
+// false if the currency is unknown, or the amount has more decimal places than its minor units, such as 10.001 USD.
+func moneyAmount(v interface{}, currency string) (*big.Rat, bool) {
+	units, ok := currencyUnit[currency]
+	if !ok {
+		return nil, false
+	}
+	var r *big.Rat
+	switch x := v.(type) {
+	case string:
+		if !decimalRegexp.MatchString(x) {
+			return nil, false
+		}
+		r, ok = new(big.Rat).SetString(x)
+	case float64:
+		r, ok = floatToRat(x)
+	case *big.Rat:
+		r, ok = x, true
+	}
+	if !ok {
+		return nil, false
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(units)), nil)
+	if !new(big.Rat).Mul(r, new(big.Rat).SetInt(scale)).IsInt() {
+		return nil, false
+	}
+	return r, true
+}
+
+// ibanLength is the IBAN length of each country, from the SWIFT IBAN registry.
+var ibanLength = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22,
+	"BH": 22, "BI": 27, "BR": 29, "BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24,
+	"DE": 22, "DJ": 27, "DK": 18, "DO": 28, "EE": 20, "EG": 29, "ES": 24, "FI": 18,
+	"FK": 18, "FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23, "GL": 18, "GR": 27,
+	"GT": 28, "HR": 21, "HU": 28, "IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27,
+	"JO": 30, "KW": 30, "KZ": 20, "LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20,
+	"LV": 21, "LY": 25, "MC": 27, "MD": 24, "ME": 22, "MK": 19, "MN": 20, "MR": 27,
+	"MT": 31, "MU": 30, "NI": 28, "NL": 18, "NO": 15, "OM": 23, "PK": 24, "PL": 28,
+	"PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "RU": 33, "SA": 24, "SC": 31,
+	"SD": 18, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "SO": 23, "ST": 25, "SV": 28,
+	"TL": 23, "TN": 24, "TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20, "YE": 30,
+}
+
+// isIBAN checks the country length and the ISO 7064 mod 97-10 checksum,
+// the space separators of the print format are allowed.
+func isIBAN(s string) bool {
+	s = strings.ToUpper(strings.Replace(s, " ", "", -1))
+	if len(s) < 4 || ibanLength[s[:2]] != len(s) {
+		return false
+	}
+	s = s[4:] + s[:4]
+	var mod int
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			mod = (mod*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			mod = (mod*100 + int(r-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return mod == 1
+}
+
+var bicRegexp = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+
+type cardRule struct {
+	brand          string
+	prefixes       [][2]int // closed intervals of the IIN prefixes
+	minLen, maxLen int
+}
+
+// cardRules is ordered, the more specific ranges must be in front.
+var cardRules = []cardRule{
+	{brand: "amex", prefixes: [][2]int{{34, 34}, {37, 37}}, minLen: 15, maxLen: 15},
+	{brand: "dinersclub", prefixes: [][2]int{{300, 305}, {36, 36}, {38, 39}}, minLen: 14, maxLen: 19},
+	{brand: "jcb", prefixes: [][2]int{{3528, 3589}}, minLen: 16, maxLen: 19},
+	{brand: "visa", prefixes: [][2]int{{4, 4}}, minLen: 13, maxLen: 19},
+	{brand: "maestro", prefixes: [][2]int{{5018, 5018}, {5020, 5020}, {5038, 5038}, {5893, 5893}, {6304, 6304}, {6759, 6759}, {6761, 6763}}, minLen: 12, maxLen: 19},
+	{brand: "mastercard", prefixes: [][2]int{{51, 55}, {2221, 2720}}, minLen: 16, maxLen: 16},
+	{brand: "discover", prefixes: [][2]int{{6011, 6011}, {622126, 622925}, {644, 649}, {65, 65}}, minLen: 16, maxLen: 19},
+	{brand: "unionpay", prefixes: [][2]int{{62, 62}}, minLen: 16, maxLen: 19},
+}
+
+// cardBrand returns the brand of the card number that passes the Luhn check,
+// otherwise returns "". The space and hyphen separators are allowed.
+func cardBrand(s string) string {
+	s = strings.NewReplacer(" ", "", "-", "").Replace(s)
+	if !luhn(s) {
+		return ""
+	}
+	for _, rule := range cardRules {
+		if len(s) < rule.minLen || len(s) > rule.maxLen {
+			continue
+		}
+		for _, p := range rule.prefixes {
+			if matchIIN(s, p[0], p[1]) {
+				return rule.brand
+			}
+		}
+	}
+	return ""
+}
+
+func matchIIN(s string, low, high int) bool {
+	n := 0
+	for i := low; i > 0; i /= 10 {
+		n++
+	}
+	var prefix int
+	for i := 0; i < n; i++ {
+		prefix = prefix*10 + int(s[i]-'0')
+	}
+	return prefix >= low && prefix <= high
+}
+
+func luhn(s string) bool {
+	if len(s) < 12 {
+		return false
+	}
+	var sum int
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
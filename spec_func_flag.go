@@ -0,0 +1,56 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+// --------------------------- Built-in function: flag ---------------------------
+
+func init() {
+	regVMFunc("flag", func(t *TagExpr, args ...interface{}) interface{} {
+		name, ok := getStringArg(args, 0)
+		if !ok || len(args) != 1 {
+			return nil
+		}
+		vm := t.getVM()
+		if vm == nil || vm.flagProvider == nil {
+			return false
+		}
+		return vm.flagProvider.Enabled(name)
+	})
+}
+
+// FlagProvider the provider of the feature flags of the built-in function flag,
+// such as the adapter of LaunchDarkly, Unleash or the config center.
+type FlagProvider interface {
+	// Enabled reports whether the feature flag is on, it should be fast and concurrency safe.
+	Enabled(name string) bool
+}
+
+// FlagFunc the function adapter of FlagProvider
+type FlagFunc func(name string) bool
+
+// Enabled implements FlagProvider.
+func (f FlagFunc) Enabled(name string) bool {
+	return f(name)
+}
+
+// SetFlagProvider sets the provider of the feature flags,
+// so that the rules can be rolled out progressively, such as flag('new-policy') && len($)>=12 || len($)>=8.
+// NOTE:
+//  flag(name) is false if no provider is set;
+//  It should be called before the vm is used.
+func (vm *VM) SetFlagProvider(provider FlagProvider) *VM {
+	vm.flagProvider = provider
+	return vm
+}
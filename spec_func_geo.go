@@ -0,0 +1,89 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import "math"
+
+// --------------------------- Built-in function: geo ---------------------------
+
+func init() {
+	regFunc("latlng", func(_ *TagExpr, args ...interface{}) interface{} {
+		c, ok := getFloatArgs(args, 2)
+		if !ok {
+			return nil
+		}
+		return isLatLng(c[0], c[1])
+	})
+	regFunc("within_bbox", func(_ *TagExpr, args ...interface{}) interface{} {
+		c, ok := getFloatArgs(args, 6)
+		if !ok {
+			return nil
+		}
+		return withinBBox(c[0], c[1], c[2], c[3], c[4], c[5])
+	})
+	regFunc("distance", func(_ *TagExpr, args ...interface{}) interface{} {
+		c, ok := getFloatArgs(args, 4)
+		if !ok || !isLatLng(c[0], c[1]) || !isLatLng(c[2], c[3]) {
+			return nil
+		}
+		return haversine(c[0], c[1], c[2], c[3])
+	})
+}
+
+// getFloatArgs returns the n arguments as float64, false if the count or any type does not match.
+func getFloatArgs(args []interface{}, n int) ([]float64, bool) {
+	if len(args) != n {
+		return nil, false
+	}
+	r := make([]float64, n)
+	for i, arg := range args {
+		f, ok := arg.(float64)
+		if !ok {
+			return nil, false
+		}
+		r[i] = f
+	}
+	return r, true
+}
+
+// isLatLng checks the latitude is in [-90, 90] and the longitude is in [-180, 180].
+func isLatLng(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// withinBBox checks the coordinate is in the box of the south-west and north-east corners,
+// the box crosses the antimeridian if minLng is greater than maxLng.
+func withinBBox(lat, lng, minLat, minLng, maxLat, maxLng float64) bool {
+	if !isLatLng(lat, lng) || lat < minLat || lat > maxLat {
+		return false
+	}
+	if minLng <= maxLng {
+		return lng >= minLng && lng <= maxLng
+	}
+	return lng >= minLng || lng <= maxLng
+}
+
+// earthRadius the mean radius of the earth in kilometers
+const earthRadius = 6371.0088
+
+// haversine returns the great-circle distance in kilometers between the two coordinates.
+func haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	const rad = math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
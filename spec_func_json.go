@@ -0,0 +1,121 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// --------------------------- Built-in function: jsonpath ---------------------------
+
+func init() {
+	regFunc("jsonpath", func(_ *TagExpr, args ...interface{}) interface{} {
+		path, ok := getStringArg(args, 1)
+		if !ok || len(args) != 2 {
+			return nil
+		}
+		var data []byte
+		switch v := args[0].(type) {
+		case string:
+			data = []byte(v)
+		case []byte:
+			data = v
+		default:
+			return nil
+		}
+		steps, ok := parseJSONPath(path)
+		if !ok {
+			return nil
+		}
+		var doc interface{}
+		if json.Unmarshal(data, &doc) != nil {
+			return nil
+		}
+		return queryJSONPath(doc, steps)
+	})
+}
+
+// parseJSONPath parses the simple JSONPath, such as $.user.age, $.items[0]['first name'].
+// The key step is string, the index step is int.
+func parseJSONPath(path string) (steps []interface{}, ok bool) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, false
+	}
+	path = path[1:]
+	for path != "" {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			i := strings.IndexAny(path, ".[")
+			if i < 0 {
+				i = len(path)
+			}
+			if i == 0 {
+				return nil, false
+			}
+			steps = append(steps, path[:i])
+			path = path[i:]
+		case '[':
+			i := strings.IndexByte(path, ']')
+			if i < 0 {
+				return nil, false
+			}
+			sub := strings.TrimSpace(path[1:i])
+			path = path[i+1:]
+			if len(sub) >= 2 && (sub[0] == '\'' || sub[0] == '"') && sub[len(sub)-1] == sub[0] {
+				steps = append(steps, sub[1:len(sub)-1])
+				continue
+			}
+			idx, err := strconv.Atoi(sub)
+			if err != nil {
+				return nil, false
+			}
+			steps = append(steps, idx)
+		default:
+			return nil, false
+		}
+	}
+	return steps, true
+}
+
+// queryJSONPath returns the value of the path, nil if it does not exist.
+// The negative index counts from the end of the array.
+func queryJSONPath(doc interface{}, steps []interface{}) interface{} {
+	for _, step := range steps {
+		switch k := step.(type) {
+		case string:
+			m, ok := doc.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			doc = m[k]
+		case int:
+			a, ok := doc.([]interface{})
+			if !ok {
+				return nil
+			}
+			if k < 0 {
+				k += len(a)
+			}
+			if k < 0 || k >= len(a) {
+				return nil
+			}
+			doc = a[k]
+		}
+	}
+	return doc
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// --------------------------- Built-in function: jwt ---------------------------
+
+func init() {
+	regVMFunc("jwt", func(t *TagExpr, args ...interface{}) interface{} {
+		s, ok := getStringArg(args, 0)
+		if !ok {
+			return nil
+		}
+		var algs map[string]bool
+		if vm := t.getVM(); vm != nil {
+			algs = vm.jwtAlgs
+		}
+		return isJWT(s, algs)
+	})
+}
+
+// SetJWTAlgs sets the allowed algorithms of the built-in function jwt, such as "RS256" and "ES256".
+// NOTE:
+//  By default any algorithm but "none" is allowed, "none" is allowed only if it is listed;
+//  The function checks the structure only, the signature should still be verified cryptographically;
+//  It should be called before the vm is used.
+func (vm *VM) SetJWTAlgs(algs ...string) *VM {
+	vm.jwtAlgs = make(map[string]bool, len(algs))
+	for _, alg := range algs {
+		vm.jwtAlgs[alg] = true
+	}
+	return vm
+}
+
+// isJWT checks the JWS compact serialization: the header and payload are unpadded base64url JSON objects,
+// the header has the allowed alg, and the signature is unpadded base64url, empty only for the alg "none".
+func isJWT(s string, algs map[string]bool) bool {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	var header struct {
+		Alg *string `json:"alg"`
+	}
+	var payload map[string]interface{}
+	if !decodeJWTPart(parts[0], &header) || !decodeJWTPart(parts[1], &payload) || header.Alg == nil {
+		return false
+	}
+	alg := *header.Alg
+	if len(algs) > 0 {
+		if !algs[alg] {
+			return false
+		}
+	} else if alg == "" || alg == "none" {
+		return false
+	}
+	if alg == "none" {
+		return parts[2] == ""
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	return err == nil && len(sig) > 0
+}
+
+func decodeJWTPart(part string, v interface{}) bool {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil || len(b) == 0 || b[0] != '{' {
+		return false
+	}
+	return json.Unmarshal(b, v) == nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"strings"
+)
+
+// --------------------------- Built-in function: locale ---------------------------
+
+var (
+	countrySet   = newStringSet(iso3166Codes)
+	languageSet  = newStringSet(iso639Codes)
+	timezoneSet  = newStringSet(tzNames)
+	currencyUnit = make(map[string]int, 200) // currency code -> number of minor units
+)
+
+func init() {
+	for _, s := range strings.Fields(iso4217Codes) {
+		currencyUnit[s[:3]] = int(s[3] - '0')
+	}
+	regFunc("iso3166", newStringPredicate(func(s string) bool {
+		return countrySet[s]
+	}))
+	regFunc("iso4217", newStringPredicate(func(s string) bool {
+		_, ok := currencyUnit[s]
+		return ok
+	}))
+	regFunc("bcp47", newStringPredicate(isBCP47))
+	regFunc("tz", newStringPredicate(func(s string) bool {
+		return timezoneSet[s]
+	}))
+}
+
+func newStringSet(fields string) map[string]bool {
+	a := strings.Fields(fields)
+	m := make(map[string]bool, len(a))
+	for _, s := range a {
+		m[s] = true
+	}
+	return m
+}
+
+// isBCP47 checks the well-formedness of the language tag,
+// such as en, en-US, zh-Hans-CN, sr-Latn-RS, es-419, de-DE-1996, en-US-x-twain.
+func isBCP47(s string) bool {
+	subtags := strings.Split(s, "-")
+	lang := strings.ToLower(subtags[0])
+	switch {
+	case len(lang) == 2:
+		if !languageSet[lang] {
+			return false
+		}
+	case len(lang) == 3:
+		if !isAlpha(lang) {
+			return false
+		}
+	case lang == "x" || lang == "i":
+		return len(subtags) > 1 && checkSubtags(subtags[1:], 1, 8)
+	default:
+		return false
+	}
+	subtags = subtags[1:]
+	// script
+	if len(subtags) > 0 && len(subtags[0]) == 4 && isAlpha(subtags[0]) {
+		subtags = subtags[1:]
+	}
+	// region
+	if len(subtags) > 0 {
+		switch r := subtags[0]; {
+		case len(r) == 2 && countrySet[strings.ToUpper(r)]:
+			subtags = subtags[1:]
+		case len(r) == 3 && isDigit(r):
+			subtags = subtags[1:]
+		}
+	}
+	// variants
+	for len(subtags) > 0 {
+		v := subtags[0]
+		if !isAlnum(v) || !(len(v) >= 5 && len(v) <= 8 || len(v) == 4 && isDigit(v[:1])) {
+			break
+		}
+		subtags = subtags[1:]
+	}
+	// extensions and private use
+	for len(subtags) > 0 {
+		singleton := strings.ToLower(subtags[0])
+		if len(singleton) != 1 || !isAlnum(singleton) {
+			return false
+		}
+		if singleton == "x" {
+			return checkSubtags(subtags[1:], 1, 8)
+		}
+		var i = 1
+		for i < len(subtags) && len(subtags[i]) > 1 {
+			i++
+		}
+		if !checkSubtags(subtags[1:i], 2, 8) {
+			return false
+		}
+		subtags = subtags[i:]
+	}
+	return true
+}
+
+func checkSubtags(subtags []string, minLen, maxLen int) bool {
+	if len(subtags) == 0 {
+		return false
+	}
+	for _, s := range subtags {
+		if len(s) < minLen || len(s) > maxLen || !isAlnum(s) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isDigit(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isAlnum(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
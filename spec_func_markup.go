@@ -0,0 +1,128 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// --------------------------- Built-in function: markup ---------------------------
+
+func init() {
+	regVMFunc("nohtml", func(t *TagExpr, args ...interface{}) interface{} {
+		s, ok := getStringArg(args, 0)
+		if !ok {
+			return nil
+		}
+		return !t.getMarkupPolicy().hasHTML(s)
+	})
+	regVMFunc("noscript", func(t *TagExpr, args ...interface{}) interface{} {
+		s, ok := getStringArg(args, 0)
+		if !ok {
+			return nil
+		}
+		return !t.getMarkupPolicy().hasScript(s)
+	})
+}
+
+// MarkupPolicy the policy of the built-in functions nohtml and noscript
+type MarkupPolicy struct {
+	// AllowedTags the tag names accepted by nohtml, compared case-insensitively, such as b, i, br
+	AllowedTags []string
+	// ScriptPatterns the extra substrings rejected by noscript, compared case-insensitively
+	ScriptPatterns []string
+}
+
+type markupPolicy struct {
+	allowedTags    map[string]bool
+	scriptPatterns []string
+}
+
+var defaultMarkupPolicy = newMarkupPolicy(MarkupPolicy{})
+
+func newMarkupPolicy(policy MarkupPolicy) *markupPolicy {
+	p := &markupPolicy{allowedTags: make(map[string]bool, len(policy.AllowedTags))}
+	for _, tag := range policy.AllowedTags {
+		p.allowedTags[strings.ToLower(tag)] = true
+	}
+	p.scriptPatterns = append(p.scriptPatterns, defaultScriptPatterns...)
+	for _, s := range policy.ScriptPatterns {
+		p.scriptPatterns = append(p.scriptPatterns, strings.ToLower(s))
+	}
+	return p
+}
+
+// SetMarkupPolicy sets the policy of the built-in functions nohtml and noscript.
+// NOTE:
+//  It should be called before the vm is used.
+func (vm *VM) SetMarkupPolicy(policy MarkupPolicy) *VM {
+	vm.markupPolicy = newMarkupPolicy(policy)
+	return vm
+}
+
+func (t *TagExpr) getMarkupPolicy() *markupPolicy {
+	if vm := t.getVM(); vm != nil && vm.markupPolicy != nil {
+		return vm.markupPolicy
+	}
+	return defaultMarkupPolicy
+}
+
+var markupRegexp = regexp.MustCompile(`<(!--|![A-Za-z]|\?|/?([A-Za-z][A-Za-z0-9-]*))`)
+
+// hasHTML reports whether s contains the tags that are not allowed, the comments or the declarations,
+// also in the entity-encoded form such as &lt;b&gt;.
+func (p *markupPolicy) hasHTML(s string) bool {
+	for _, text := range [2]string{s, html.UnescapeString(s)} {
+		for _, m := range markupRegexp.FindAllStringSubmatch(text, -1) {
+			if m[2] == "" || !p.allowedTags[strings.ToLower(m[2])] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// defaultScriptPatterns the substrings of the common script injections,
+// matched against the lowercased text without the whitespaces and the control characters.
+var defaultScriptPatterns = []string{
+	"<script", "</script", "javascript:", "vbscript:", "livescript:",
+	"data:text/html", "expression(", "<iframe", "<object", "<embed", "srcdoc=",
+}
+
+var eventHandlerRegexp = regexp.MustCompile(`(?i)[\s/"'<]on[a-z]+\s*=`)
+
+// hasScript reports whether s contains the script injection patterns,
+// such as the script elements, the script URLs and the event handler attributes,
+// also in the entity-encoded or whitespace-obfuscated form such as java&#9;script:.
+func (p *markupPolicy) hasScript(s string) bool {
+	text := html.UnescapeString(s)
+	if eventHandlerRegexp.MatchString(text) {
+		return true
+	}
+	compact := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.ToLower(text))
+	for _, pattern := range p.scriptPatterns {
+		if strings.Contains(compact, pattern) {
+			return true
+		}
+	}
+	return false
+}
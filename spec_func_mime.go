@@ -0,0 +1,107 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"encoding/base64"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// --------------------------- Built-in function: mime ---------------------------
+
+func init() {
+	regFunc("mime", func(_ *TagExpr, args ...interface{}) interface{} {
+		s, ok := getStringArg(args, 0)
+		if !ok {
+			return nil
+		}
+		t := mediaType(s)
+		if len(args) == 1 {
+			return t
+		}
+		return matchAnyStringArg(args[1:], func(want string) bool {
+			return t != "" && matchMediaType(t, want)
+		})
+	})
+	regFunc("datauri", func(_ *TagExpr, args ...interface{}) interface{} {
+		s, ok := getStringArg(args, 0)
+		if !ok {
+			return nil
+		}
+		t, ok := dataURIType(s)
+		if len(args) == 1 {
+			return ok
+		}
+		return matchAnyStringArg(args[1:], func(want string) bool {
+			return ok && matchMediaType(t, want)
+		})
+	})
+}
+
+// mediaType returns the lower-case media type without the parameters, such as 'text/html' for 'Text/HTML; charset=utf-8',
+// '' if s is not a valid media type of the type/subtype form.
+func mediaType(s string) string {
+	t, _, err := mime.ParseMediaType(s)
+	if err != nil || strings.Count(t, "/") != 1 || strings.HasPrefix(t, "/") || strings.HasSuffix(t, "/") {
+		return ""
+	}
+	return t
+}
+
+// matchMediaType matches the media type with the pattern, which may be 'image/*' or '*/*'.
+func matchMediaType(t, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	switch {
+	case pattern == "*/*":
+		return true
+	case strings.HasSuffix(pattern, "/*"):
+		return strings.HasPrefix(t, pattern[:len(pattern)-1])
+	}
+	return t == pattern
+}
+
+// dataURIType checks the RFC 2397 data URI, whose base64 payload must be decodable,
+// and returns its media type, 'text/plain' if omitted.
+func dataURIType(s string) (string, bool) {
+	if len(s) < 5 || !strings.EqualFold(s[:5], "data:") {
+		return "", false
+	}
+	i := strings.IndexByte(s, ',')
+	if i < 0 {
+		return "", false
+	}
+	meta, data := s[5:i], s[i+1:]
+	isBase64 := false
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		meta, isBase64 = meta[:len(meta)-len(";base64")], true
+	}
+	t := "text/plain"
+	if meta != "" {
+		if strings.HasPrefix(meta, ";") {
+			meta = t + meta
+		}
+		if t = mediaType(meta); t == "" {
+			return "", false
+		}
+	}
+	if isBase64 {
+		_, err := base64.StdEncoding.DecodeString(data)
+		return t, err == nil
+	}
+	_, err := url.PathUnescape(data)
+	return t, err == nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"strconv"
+	"strings"
+)
+
+// --------------------------- Built-in function: net ---------------------------
+
+func init() {
+	regFunc("hostname", newStringPredicate(isHostname))
+	regFunc("fqdn", newStringPredicate(isFQDN))
+	regFunc("port", func(_ *TagExpr, args ...interface{}) interface{} {
+		if len(args) == 0 {
+			return nil
+		}
+		switch v := args[0].(type) {
+		case float64:
+			return v == float64(int(v)) && isPort(int(v))
+		case string:
+			p, err := strconv.Atoi(v)
+			return err == nil && v[0] != '+' && isPort(p)
+		}
+		return nil
+	})
+}
+
+// hostnameLabels splits the RFC 1123 host name into the labels, false if it is invalid,
+// i.e. it is longer than 253 bytes, or any label is not 1 to 63 letters, digits and hyphens
+// that neither starts nor ends with a hyphen; the trailing dot of the root is allowed.
+func hostnameLabels(s string) ([]string, bool) {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return nil, false
+	}
+	labels := strings.Split(s, ".")
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return nil, false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return nil, false
+			}
+		}
+	}
+	return labels, true
+}
+
+func isHostname(s string) bool {
+	_, ok := hostnameLabels(s)
+	return ok
+}
+
+// isFQDN checks the host name has two labels at least, and its top-level label is not all digits,
+// so that neither 'localhost' nor '10.0.0.1' is regarded as the fully qualified domain name.
+func isFQDN(s string) bool {
+	labels, ok := hostnameLabels(s)
+	if !ok || len(labels) < 2 {
+		return false
+	}
+	_, err := strconv.Atoi(labels[len(labels)-1])
+	return err != nil
+}
+
+func isPort(p int) bool {
+	return p >= 1 && p <= 65535
+}
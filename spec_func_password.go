@@ -0,0 +1,128 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"strings"
+	"unicode"
+)
+
+// --------------------------- Built-in function: password ---------------------------
+
+func init() {
+	regVMFunc("pwstrength", func(t *TagExpr, args ...interface{}) interface{} {
+		s, ok := getStringArg(args, 0)
+		if !ok {
+			return nil
+		}
+		policy := defaultPasswordPolicy
+		if vm := t.getVM(); vm != nil && vm.passwordPolicy != nil {
+			policy = vm.passwordPolicy
+		}
+		return float64(policy.score(s))
+	})
+}
+
+// PasswordPolicy the policy of the built-in function pwstrength
+type PasswordPolicy struct {
+	// MinLength the password shorter than it scores 0, default 8
+	MinLength int
+	// LongLength the password not shorter than it scores 1 extra, default 12
+	LongLength int
+	// CommonPasswords the passwords score 0, compared case-insensitively;
+	// default is a built-in list of the most common passwords
+	CommonPasswords []string
+}
+
+type passwordPolicy struct {
+	minLength, longLength int
+	common                map[string]bool
+}
+
+var defaultPasswordPolicy = newPasswordPolicy(PasswordPolicy{})
+
+func newPasswordPolicy(policy PasswordPolicy) *passwordPolicy {
+	p := &passwordPolicy{
+		minLength:  policy.MinLength,
+		longLength: policy.LongLength,
+	}
+	if p.minLength <= 0 {
+		p.minLength = 8
+	}
+	if p.longLength <= 0 {
+		p.longLength = 12
+	}
+	common := policy.CommonPasswords
+	if common == nil {
+		common = strings.Fields(commonPasswords)
+	}
+	p.common = make(map[string]bool, len(common))
+	for _, s := range common {
+		p.common[strings.ToLower(s)] = true
+	}
+	return p
+}
+
+// SetPasswordPolicy sets the policy of the built-in function pwstrength.
+// NOTE:
+//  It should be called before the vm is used.
+func (vm *VM) SetPasswordPolicy(policy PasswordPolicy) *VM {
+	vm.passwordPolicy = newPasswordPolicy(policy)
+	return vm
+}
+
+// score returns the password strength from 0 to 4:
+// one point for each extra character class of lowercase, uppercase, digit and symbol,
+// plus one point for the long password.
+func (p *passwordPolicy) score(s string) int {
+	n := len([]rune(s))
+	if n < p.minLength || p.common[strings.ToLower(s)] {
+		return 0
+	}
+	var lower, upper, digit, symbol bool
+	for _, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	var score = -1
+	for _, b := range [4]bool{lower, upper, digit, symbol} {
+		if b {
+			score++
+		}
+	}
+	if n >= p.longLength {
+		score++
+	}
+	if score > 4 {
+		score = 4
+	}
+	return score
+}
+
+const commonPasswords = `
+123456 123456789 12345678 12345 1234567 1234567890 111111 123123 000000 654321
+password password1 password123 passw0rd p@ssw0rd qwerty qwerty123 qwertyuiop 1q2w3e4r
+abc123 iloveyou admin admin123 welcome welcome1 letmein monkey dragon football
+baseball sunshine princess master superman trustno1 login starwars whatever
+freedom shadow michael jennifer zaq12wsx asdfghjkl 1qaz2wsx changeme secret
+`
@@ -0,0 +1,93 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"path"
+	"strings"
+)
+
+// --------------------------- Built-in function: path ---------------------------
+
+func init() {
+	regFunc("safepath", newStringPredicate(isSafePath))
+	regFunc("safefilename", newStringPredicate(isSafeFilename))
+	regFunc("ext", func(_ *TagExpr, args ...interface{}) interface{} {
+		s, ok := getStringArg(args, 0)
+		if !ok {
+			return nil
+		}
+		e := fileExt(s)
+		if len(args) == 1 {
+			return e
+		}
+		return matchAnyStringArg(args[1:], func(want string) bool {
+			return e == strings.ToLower(strings.TrimPrefix(want, "."))
+		})
+	})
+}
+
+// fileExt returns the lower-case extension of the file name without the dot, such as 'pdf' for 'a/Report.PDF'.
+func fileExt(s string) string {
+	s = s[strings.LastIndexAny(s, `/\`)+1:]
+	return strings.ToLower(strings.TrimPrefix(path.Ext(s), "."))
+}
+
+// isSafePath checks the relative path does not escape its root, i.e. it is not empty or absolute,
+// it has no '..' element and no NUL, the backslash is regarded as a separator too.
+func isSafePath(s string) bool {
+	if s == "" || strings.IndexByte(s, 0) >= 0 {
+		return false
+	}
+	s = strings.Replace(s, `\`, "/", -1)
+	if strings.HasPrefix(s, "/") || (len(s) >= 2 && s[1] == ':') {
+		return false
+	}
+	for _, elem := range strings.Split(s, "/") {
+		if elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// reservedFilenames the device names reserved by Windows, regardless of the extension
+var reservedFilenames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// isSafeFilename checks the single file name is portable and harmless, i.e. it is at most 255 bytes,
+// has no separator, control or Windows-reserved character, does not end with a dot or space,
+// and is not '.', '..' or a Windows device name such as 'CON.txt'.
+func isSafeFilename(s string) bool {
+	if s == "" || len(s) > 255 || s == "." || s == ".." {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return false
+		}
+	}
+	if last := s[len(s)-1]; last == '.' || last == ' ' {
+		return false
+	}
+	base := s
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+	return !reservedFilenames[strings.ToUpper(base)]
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"strings"
+)
+
+// --------------------------- Built-in function: phone ---------------------------
+
+func init() {
+	regFunc("phone", func(_ *TagExpr, args ...interface{}) interface{} {
+		s, ok := getStringArg(args, 0)
+		if !ok {
+			return nil
+		}
+		var region string
+		if len(args) > 1 {
+			if region, ok = getStringArg(args, 1); !ok {
+				return nil
+			}
+		}
+		return isPhone(s, strings.ToUpper(region))
+	})
+}
+
+type phoneRule struct {
+	code           string // country calling code
+	minLen, maxLen int    // length of the national significant number
+	trunk          string // national trunk prefix
+}
+
+// phoneRules is a lightweight numbering plan table of the common regions.
+var phoneRules = map[string]phoneRule{
+	"AE": {"971", 8, 9, "0"}, "AR": {"54", 10, 11, "0"}, "AT": {"43", 4, 13, "0"},
+	"AU": {"61", 9, 9, "0"}, "BE": {"32", 8, 9, "0"}, "BR": {"55", 10, 11, "0"},
+	"CA": {"1", 10, 10, "1"}, "CH": {"41", 9, 9, "0"}, "CN": {"86", 5, 12, "0"},
+	"DE": {"49", 6, 13, "0"}, "DK": {"45", 8, 8, ""}, "EG": {"20", 8, 10, "0"},
+	"ES": {"34", 9, 9, ""}, "FI": {"358", 5, 12, "0"}, "FR": {"33", 9, 9, "0"},
+	"GB": {"44", 9, 10, "0"}, "HK": {"852", 8, 8, ""}, "ID": {"62", 8, 12, "0"},
+	"IE": {"353", 7, 9, "0"}, "IL": {"972", 8, 9, "0"}, "IN": {"91", 10, 10, "0"},
+	"IT": {"39", 6, 11, ""}, "JP": {"81", 9, 10, "0"}, "KR": {"82", 8, 10, "0"},
+	"MX": {"52", 10, 10, ""}, "MY": {"60", 7, 10, "0"}, "NG": {"234", 8, 10, "0"},
+	"NL": {"31", 9, 9, "0"}, "NO": {"47", 8, 8, ""}, "NZ": {"64", 8, 10, "0"},
+	"PH": {"63", 8, 10, "0"}, "PL": {"48", 9, 9, ""}, "PT": {"351", 9, 9, ""},
+	"RU": {"7", 10, 10, "8"}, "SA": {"966", 9, 9, "0"}, "SE": {"46", 7, 10, "0"},
+	"SG": {"65", 8, 8, ""}, "TH": {"66", 8, 9, "0"}, "TR": {"90", 10, 10, "0"},
+	"TW": {"886", 8, 9, "0"}, "US": {"1", 10, 10, "1"}, "VN": {"84", 9, 10, "0"},
+	"ZA": {"27", 9, 9, "0"},
+}
+
+// isPhone reports whether s is a valid phone number.
+// NOTE:
+//  If region is empty, s must be in the E.164 international format, such as +8613800138000;
+//  Otherwise s can also be in the national format of the region;
+//  The space, hyphen, dot and parentheses separators are allowed.
+func isPhone(s, region string) bool {
+	s = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(s)
+	international := false
+	if strings.HasPrefix(s, "+") {
+		s, international = s[1:], true
+	} else if strings.HasPrefix(s, "00") {
+		s, international = s[2:], true
+	}
+	if s == "" || len(s) > 15 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	if region != "" {
+		rule, ok := phoneRules[region]
+		if !ok {
+			return false
+		}
+		if international {
+			if !strings.HasPrefix(s, rule.code) {
+				return false
+			}
+			return rule.checkLen(s[len(rule.code):])
+		}
+		if rule.trunk != "" && strings.HasPrefix(s, rule.trunk) && !rule.checkLen(s) {
+			s = s[len(rule.trunk):]
+		}
+		return rule.checkLen(s)
+	}
+	if !international {
+		return false
+	}
+	for _, rule := range phoneRules {
+		if strings.HasPrefix(s, rule.code) && rule.checkLen(s[len(rule.code):]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r phoneRule) checkLen(nsn string) bool {
+	if len(nsn) < r.minLen || len(nsn) > r.maxLen {
+		return false
+	}
+	switch r.trunk {
+	case "":
+		return true
+	case "1": // NANP area codes start with 2-9
+		return nsn[0] >= '2'
+	default:
+		return nsn[0] != '0'
+	}
+}
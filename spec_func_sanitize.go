@@ -0,0 +1,72 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"regexp"
+	"strings"
+)
+
+// --------------------------- Built-in function: sanitize ---------------------------
+
+func init() {
+	regFunc("trim", newStringMapper(strings.TrimSpace))
+	regFunc("lower", newStringMapper(strings.ToLower))
+	regFunc("upper", newStringMapper(strings.ToUpper))
+	regFunc("striphtml", newStringMapper(stripHTML))
+	regFunc("clamp", func(_ *TagExpr, args ...interface{}) interface{} {
+		if len(args) != 3 {
+			return nil
+		}
+		v, ok0 := args[0].(float64)
+		min, ok1 := args[1].(float64)
+		max, ok2 := args[2].(float64)
+		if !ok0 || !ok1 || !ok2 || min > max {
+			return nil
+		}
+		switch {
+		case v < min:
+			return min
+		case v > max:
+			return max
+		}
+		return v
+	})
+}
+
+// newStringMapper creates a built-in function that maps the first string argument,
+// the result is nil if the argument is not string.
+func newStringMapper(fn func(string) string) func(*TagExpr, ...interface{}) interface{} {
+	return func(_ *TagExpr, args ...interface{}) interface{} {
+		s, ok := getStringArg(args, 0)
+		if !ok {
+			return nil
+		}
+		return fn(s)
+	}
+}
+
+var (
+	htmlBlockRegexp = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)\s*>`)
+	htmlTagRegexp   = regexp.MustCompile(`(?s)<!--.*?-->|</?[A-Za-z][^>]*>`)
+)
+
+// stripHTML removes the HTML tags, the comments and the content of script and style elements.
+// NOTE:
+//  The entities such as &lt; are kept, so that no tag is produced by the unescaping.
+func stripHTML(s string) string {
+	s = htmlBlockRegexp.ReplaceAllString(s, "")
+	return htmlTagRegexp.ReplaceAllString(s, "")
+}
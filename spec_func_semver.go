@@ -0,0 +1,153 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"strconv"
+	"strings"
+)
+
+// --------------------------- Built-in function: semver ---------------------------
+
+func init() {
+	regFunc("semver", newStringPredicate(func(s string) bool {
+		_, ok := parseSemver(s)
+		return ok
+	}))
+	regFunc("semver_gte", func(_ *TagExpr, args ...interface{}) interface{} {
+		s0, ok0 := getStringArg(args, 0)
+		s1, ok1 := getStringArg(args, 1)
+		if !ok0 || !ok1 {
+			return nil
+		}
+		v0, ok0 := parseSemver(s0)
+		v1, ok1 := parseSemver(s1)
+		if !ok0 || !ok1 {
+			return nil
+		}
+		return v0.compare(v1) >= 0
+	})
+}
+
+type semver struct {
+	major, minor, patch uint64
+	prerelease          []string
+}
+
+// parseSemver parses the semantic version 2.0.0, the 'v' prefix is allowed.
+func parseSemver(s string) (v semver, ok bool) {
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		if !checkIdentifiers(s[i+1:], false) {
+			return
+		}
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		if !checkIdentifiers(s[i+1:], true) {
+			return
+		}
+		v.prerelease = strings.Split(s[i+1:], ".")
+		s = s[:i]
+	}
+	a := strings.Split(s, ".")
+	if len(a) != 3 {
+		return
+	}
+	var nums [3]uint64
+	for i, n := range a {
+		if !isDigit(n) || len(n) > 1 && n[0] == '0' {
+			return
+		}
+		var err error
+		if nums[i], err = strconv.ParseUint(n, 10, 64); err != nil {
+			return
+		}
+	}
+	v.major, v.minor, v.patch = nums[0], nums[1], nums[2]
+	return v, true
+}
+
+func checkIdentifiers(s string, noLeadingZero bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, r := range id {
+			if r != '-' && !isAlnum(string(r)) {
+				return false
+			}
+		}
+		if noLeadingZero && len(id) > 1 && id[0] == '0' && isDigit(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// compare returns -1, 0 or 1 according to the semantic version precedence.
+func (v semver) compare(w semver) int {
+	for _, d := range [3][2]uint64{{v.major, w.major}, {v.minor, w.minor}, {v.patch, w.patch}} {
+		if d[0] != d[1] {
+			if d[0] < d[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(v.prerelease) == 0 && len(w.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(w.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.prerelease) && i < len(w.prerelease); i++ {
+		a, b := v.prerelease[i], w.prerelease[i]
+		if a == b {
+			continue
+		}
+		aNum, bNum := isDigit(a), isDigit(b)
+		switch {
+		case aNum && bNum:
+			if len(a) != len(b) {
+				if len(a) < len(b) {
+					return -1
+				}
+				return 1
+			}
+			if a < b {
+				return -1
+			}
+			return 1
+		case aNum:
+			return -1
+		case bNum:
+			return 1
+		case a < b:
+			return -1
+		default:
+			return 1
+		}
+	}
+	switch {
+	case len(v.prerelease) < len(w.prerelease):
+		return -1
+	case len(v.prerelease) > len(w.prerelease):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"strings"
+)
+
+// --------------------------- Built-in function: sql ---------------------------
+
+func init() {
+	regFunc("likesafe", func(_ *TagExpr, args ...interface{}) interface{} {
+		s, escape, ok := getLikeArgs(args)
+		if !ok {
+			return nil
+		}
+		return isLikeSafe(s, escape)
+	})
+	regFunc("likeescape", func(_ *TagExpr, args ...interface{}) interface{} {
+		s, escape, ok := getLikeArgs(args)
+		if !ok {
+			return nil
+		}
+		return likeEscape(s, escape)
+	})
+}
+
+// getLikeArgs returns the string and the escape character, which is \ by default.
+func getLikeArgs(args []interface{}) (s string, escape byte, ok bool) {
+	if s, ok = getStringArg(args, 0); !ok {
+		return
+	}
+	switch len(args) {
+	case 1:
+		return s, '\\', true
+	case 2:
+		e, ok := getStringArg(args, 1)
+		if !ok || len(e) != 1 {
+			return "", 0, false
+		}
+		return s, e[0], true
+	}
+	return "", 0, false
+}
+
+// isLikeSafe reports whether s can be used as the literal text of a LIKE pattern:
+// the wildcards % and _ are escaped, no quote is present, and the escape character is not dangling.
+func isLikeSafe(s string, escape byte) bool {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == escape:
+			if i++; i == len(s) {
+				return false
+			}
+			if n := s[i]; n != '%' && n != '_' && n != escape {
+				return false
+			}
+		case c == '%' || c == '_' || c == '\'' || c == '"':
+			return false
+		}
+	}
+	return true
+}
+
+// likeEscape escapes the wildcards % and _ and the escape character,
+// so that s matches itself literally in a LIKE pattern.
+// NOTE:
+//  The quotes are kept, they must be passed by the query parameters.
+func likeEscape(s string, escape byte) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '%' || c == '_' || c == escape {
+			b.WriteByte(escape)
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// --------------------------- Built-in function: string ---------------------------
+
+func init() {
+	regFunc("split", func(_ *TagExpr, args ...interface{}) interface{} {
+		s, ok0 := getStringArg(args, 0)
+		sep, ok1 := getStringArg(args, 1)
+		if !ok0 || !ok1 || len(args) != 2 {
+			return nil
+		}
+		if s == "" {
+			return []interface{}{}
+		}
+		a := strings.Split(s, sep)
+		r := make([]interface{}, len(a))
+		for i, v := range a {
+			r[i] = v
+		}
+		return r
+	})
+	regFunc("join", func(_ *TagExpr, args ...interface{}) interface{} {
+		v, ok0 := getCollectionArg(args, 0)
+		sep, ok1 := getStringArg(args, 1)
+		if !ok0 || !ok1 || len(args) != 2 {
+			return nil
+		}
+		a := make([]string, 0, v.Len())
+		rangeCollection(v, func(_, e reflect.Value) bool {
+			a = append(a, fmt.Sprint(toExprValue(e)))
+			return true
+		})
+		return strings.Join(a, sep)
+	})
+	regFunc("replace", func(tagExpr *TagExpr, args ...interface{}) interface{} {
+		s, ok0 := getStringArg(args, 0)
+		pattern, ok1 := getStringArg(args, 1)
+		repl, ok2 := getStringArg(args, 2)
+		if !ok0 || !ok1 || !ok2 || len(args) != 3 {
+			return nil
+		}
+		re, err := tagExpr.getVM().compileRegexp(pattern)
+		if err != nil {
+			return nil
+		}
+		return re.ReplaceAllString(s, repl)
+	})
+}
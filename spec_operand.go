@@ -15,6 +15,7 @@
 package tagexpr
 
 import (
+	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
@@ -110,15 +111,41 @@ func readStringExprNode(expr *string) ExprNode {
 	if sptr == nil {
 		return nil
 	}
-	e := &stringExprNode{val: *sptr}
+	e := &stringExprNode{val: unescapeString(*sptr)}
 	return e
 }
 
+// unescapeString returns the value of the string literal, whose \' and \\ are the escaped quote and backslash,
+// the other backslashes are kept as they are, such as \d of the regular expressions.
+func unescapeString(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '\'' || s[i+1] == '\\') {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// quoteReplacer escapes the backslashes and the quotes of the string literal
+var quoteReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// quoteString returns the string literal of s, which is unescaped to s by unescapeString.
+func quoteString(s string) string {
+	return "'" + quoteReplacer.Replace(s) + "'"
+}
+
 func (se *stringExprNode) Run(currField string, tagExpr *TagExpr) interface{} { return se.val }
 
 type digitalExprNode struct {
 	exprBackground
-	val float64
+	val   float64
+	exact *big.Rat // the exact value for the number adapters, nil if val is exact
 }
 
 var digitalRegexp = regexp.MustCompile(`^[\+\-]?\d+(\.\d+)?([\)\],\+\-\*\/%><\|&!=\^ \t\\]|$)`)
@@ -135,6 +162,11 @@ func readDigitalExprNode(expr *string) ExprNode {
 	*expr = (*expr)[len(s):]
 	e := &digitalExprNode{}
 	e.val, _ = strconv.ParseFloat(s, 64)
+	if exact, ok := new(big.Rat).SetString(s); ok {
+		if r, _ := floatToRat(e.val); r == nil || r.Cmp(exact) != 0 {
+			e.exact = exact
+		}
+	}
 	return e
 }
 
@@ -151,22 +183,24 @@ func readPairedSymbol(p *string, left, right rune) *string {
 		return nil
 	}
 	s = s[1:]
-	var last1 = left
-	var last2 rune
+	var escaped bool
 	var leftLevel, rightLevel int
 	for i, r := range s {
-		if r == right && (last1 != '\\' || last2 == '\\') {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == right:
 			if leftLevel == rightLevel {
 				*p = s[i+1:]
 				sub := s[:i]
 				return &sub
 			}
 			rightLevel++
-		} else if r == left && (last1 != '\\' || last2 == '\\') {
+		case r == left:
 			leftLevel++
 		}
-		last2 = last1
-		last1 = r
 	}
 	return nil
 }
@@ -188,3 +222,135 @@ func readNilExprNode(expr *string) ExprNode {
 }
 
 func (*nilExprNode) Run(currField string, tagExpr *TagExpr) interface{} { return nil }
+
+// diveFrame the element being iterated by the built-in functions such as count, any and all,
+// k is the index of slice or array, or the key of map.
+type diveFrame struct {
+	k, v interface{}
+}
+
+type diveExprNode struct {
+	exprBackground
+	key        bool
+	boolPrefix *bool
+}
+
+var diveRegexp = regexp.MustCompile(`^(\!*)#([kv])([\)\],\+\-\*\/%><\|&!=\^ \t\\]|$)`)
+
+func readDiveExprNode(expr *string) ExprNode {
+	a := diveRegexp.FindStringSubmatch(*expr)
+	if a == nil {
+		return nil
+	}
+	*expr = (*expr)[len(a[0])-len(a[3]):]
+	e := &diveExprNode{key: a[2] == "k"}
+	if boolNum := len(a[1]); boolNum > 0 {
+		bol := true
+		for i := boolNum; i > 0; i-- {
+			bol = !bol
+		}
+		e.boolPrefix = &bol
+	}
+	return e
+}
+
+func (de *diveExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
+	if tagExpr == nil || tagExpr.frame == nil || !tagExpr.frame.diving {
+		return nil
+	}
+	frame := tagExpr.frame.dive
+	if de.key {
+		return applyBoolPrefix(frame.k, de.boolPrefix)
+	}
+	return applyBoolPrefix(frame.v, de.boolPrefix)
+}
+
+type ruleExprNode struct {
+	exprBackground
+	selector   string
+	boolPrefix *bool
+}
+
+var ruleRegexp = regexp.MustCompile(`^(\!*)@([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*)(@[A-Za-z_][A-Za-z0-9_]*)?([\)\],\+\-\*\/%><\|&!=\^ \t\\]|$)`)
+
+// readRuleExprNode reads the result of another expression by its selector,
+// such as @Name for Name@, @Name@min for Name@min, to compose the rules like @Name && (@Email || @Phone).
+func readRuleExprNode(expr *string) ExprNode {
+	a := ruleRegexp.FindStringSubmatch(*expr)
+	if a == nil {
+		return nil
+	}
+	*expr = (*expr)[len(a[0])-len(a[4]):]
+	e := &ruleExprNode{selector: a[2] + "@"}
+	if a[3] != "" {
+		e.selector += a[3][1:]
+	}
+	if boolNum := len(a[1]); boolNum > 0 {
+		bol := true
+		for i := boolNum; i > 0; i-- {
+			bol = !bol
+		}
+		e.boolPrefix = &bol
+	}
+	return e
+}
+
+func (re *ruleExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
+	if tagExpr == nil || tagExpr.s == nil {
+		return nil
+	}
+	e, ok := tagExpr.s.getExpr(re.selector)
+	if !ok {
+		return nil
+	}
+	return applyBoolPrefix(e.expr.run(e.field, tagExpr), re.boolPrefix)
+}
+
+// checkRuleCycles rejects the expressions that reference themselves by @selector, directly or indirectly.
+func checkRuleCycles(s *Struct) error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[*exprEntry]int)
+	var visit func(e *exprEntry, path []string) error
+	visit = func(e *exprEntry, path []string) error {
+		switch state[e] {
+		case done:
+			return nil
+		case visiting:
+			return newKindError(ErrSyntax, "cyclic rule references: %s", strings.Join(append(path, e.selector), " -> "))
+		}
+		state[e] = visiting
+		for _, selector := range referencedRules(e.expr.AST()) {
+			if dep, ok := s.exprs[selector]; ok {
+				if err := visit(dep, append(path, e.selector)); err != nil {
+					return err
+				}
+			}
+		}
+		state[e] = done
+		return nil
+	}
+	for _, e := range s.exprList {
+		if err := visit(e, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// referencedRules returns the selectors of the @selector references in the syntax tree.
+func referencedRules(node *Node) []string {
+	if node == nil {
+		return nil
+	}
+	var selectors []string
+	if node.Kind == RuleNode {
+		selectors = append(selectors, node.Name)
+	}
+	for _, arg := range node.Args {
+		selectors = append(selectors, referencedRules(arg)...)
+	}
+	return selectors
+}
@@ -83,7 +83,7 @@ func newRemainderExprNode() ExprNode { return &remainderExprNode{} }
 
 func (re *remainderExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
 	v1, _ := re.rightOperand.Run(currField, tagExpr).(float64)
-	if v1 == 0 {
+	if int64(v1) == 0 {
 		return math.NaN()
 	}
 	v0, _ := re.leftOperand.Run(currField, tagExpr).(float64)
@@ -99,6 +99,9 @@ func (ee *equalExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
 	v1 := ee.rightOperand.Run(currField, tagExpr)
 	switch r := v0.(type) {
 	case float64:
+		if c, ok := compareRat(ee, v0, v1); ok {
+			return c == 0
+		}
 		var r1 float64
 		r1, _ = v1.(float64)
 		return r == r1
@@ -113,6 +116,9 @@ func (ee *equalExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
 	case nil:
 		return v1 == nil
 	default:
+		if c, ok := compareRat(ee, v0, v1); ok {
+			return c == 0
+		}
 		return false
 	}
 }
@@ -134,6 +140,9 @@ func (ge *greaterExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
 	v1 := ge.rightOperand.Run(currField, tagExpr)
 	switch r := v0.(type) {
 	case float64:
+		if c, ok := compareRat(ge, v0, v1); ok {
+			return c > 0
+		}
 		var r1 float64
 		r1, _ = v1.(float64)
 		return r > r1
@@ -142,6 +151,9 @@ func (ge *greaterExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
 		r1, _ = v1.(string)
 		return r > r1
 	default:
+		if c, ok := compareRat(ge, v0, v1); ok {
+			return c > 0
+		}
 		return false
 	}
 }
@@ -155,6 +167,9 @@ func (ge *greaterEqualExprNode) Run(currField string, tagExpr *TagExpr) interfac
 	v1 := ge.rightOperand.Run(currField, tagExpr)
 	switch r := v0.(type) {
 	case float64:
+		if c, ok := compareRat(ge, v0, v1); ok {
+			return c >= 0
+		}
 		var r1 float64
 		r1, _ = v1.(float64)
 		return r >= r1
@@ -163,6 +178,9 @@ func (ge *greaterEqualExprNode) Run(currField string, tagExpr *TagExpr) interfac
 		r1, _ = v1.(string)
 		return r >= r1
 	default:
+		if c, ok := compareRat(ge, v0, v1); ok {
+			return c >= 0
+		}
 		return false
 	}
 }
@@ -176,6 +194,9 @@ func (le *lessExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
 	v1 := le.rightOperand.Run(currField, tagExpr)
 	switch r := v0.(type) {
 	case float64:
+		if c, ok := compareRat(le, v0, v1); ok {
+			return c < 0
+		}
 		var r1 float64
 		r1, _ = v1.(float64)
 		return r < r1
@@ -184,6 +205,9 @@ func (le *lessExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
 		r1, _ = v1.(string)
 		return r < r1
 	default:
+		if c, ok := compareRat(le, v0, v1); ok {
+			return c < 0
+		}
 		return false
 	}
 }
@@ -197,6 +221,9 @@ func (le *lessEqualExprNode) Run(currField string, tagExpr *TagExpr) interface{}
 	v1 := le.rightOperand.Run(currField, tagExpr)
 	switch r := v0.(type) {
 	case float64:
+		if c, ok := compareRat(le, v0, v1); ok {
+			return c <= 0
+		}
 		var r1 float64
 		r1, _ = v1.(float64)
 		return r <= r1
@@ -205,58 +232,41 @@ func (le *lessEqualExprNode) Run(currField string, tagExpr *TagExpr) interface{}
 		r1, _ = v1.(string)
 		return r <= r1
 	default:
+		if c, ok := compareRat(le, v0, v1); ok {
+			return c <= 0
+		}
 		return false
 	}
 }
 
-type andExprNode struct{ exprBackground }
+type andExprNode struct {
+	exprBackground
+	branchCounter
+}
 
 func newAndExprNode() ExprNode { return &andExprNode{} }
 
 func (ae *andExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
-	for _, e := range [2]ExprNode{ae.leftOperand, ae.rightOperand} {
-		switch r := e.Run(currField, tagExpr).(type) {
-		case float64:
-			if r == 0 {
-				return false
-			}
-		case string:
-			if r == "" {
-				return false
-			}
-		case bool:
-			if !r {
-				return false
-			}
-		case nil:
-			return false
-		default:
-			return false
-		}
+	if !isTruthy(ae.leftOperand.Run(currField, tagExpr)) {
+		ae.record(tagExpr, true)
+		return false
 	}
-	return true
+	ae.record(tagExpr, false)
+	return isTruthy(ae.rightOperand.Run(currField, tagExpr))
 }
 
-type orExprNode struct{ exprBackground }
+type orExprNode struct {
+	exprBackground
+	branchCounter
+}
 
 func newOrExprNode() ExprNode { return &orExprNode{} }
 
 func (oe *orExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
-	for _, e := range [2]ExprNode{oe.leftOperand, oe.rightOperand} {
-		switch r := e.Run(currField, tagExpr).(type) {
-		case float64:
-			if r != 0 {
-				return true
-			}
-		case string:
-			if r != "" {
-				return true
-			}
-		case bool:
-			if r {
-				return true
-			}
-		}
+	if isTruthy(oe.leftOperand.Run(currField, tagExpr)) {
+		oe.record(tagExpr, true)
+		return true
 	}
-	return false
+	oe.record(tagExpr, false)
+	return isTruthy(oe.rightOperand.Run(currField, tagExpr))
 }
@@ -23,23 +23,25 @@ type selectorExprNode struct {
 	exprBackground
 	field, name string
 	subExprs    []ExprNode
+	safe        []bool // whether each subscript is nil-safe, such as ?.Name and ?[0]; nil if none is
 	boolPrefix  *bool
 }
 
 func (p *Expr) readSelectorExprNode(expr *string) ExprNode {
-	field, name, subSelector, boolPrefix, found := findSelector(expr)
+	field, name, subSelector, safe, boolPrefix, found := findSelector(expr)
 	if !found {
 		return nil
 	}
 	operand := &selectorExprNode{
 		field:      field,
 		name:       name,
+		safe:       safe,
 		boolPrefix: boolPrefix,
 	}
 	operand.subExprs = make([]ExprNode, 0, len(subSelector))
 	for _, s := range subSelector {
 		grp := newGroupExprNode()
-		_, err := p.parseExprNode(&s, grp)
+		_, err := p.parseSubExprNode(&s, grp)
 		if err != nil {
 			return nil
 		}
@@ -49,9 +51,11 @@ func (p *Expr) readSelectorExprNode(expr *string) ExprNode {
 	return operand
 }
 
-var selectorRegexp = regexp.MustCompile(`^(\!*)(\([ \t]*[A-Za-z_]+[A-Za-z0-9_\.]*[ \t]*\))?(\$)([\)\[\],\+\-\*\/%><\|&!=\^ \t\\]|$)`)
+var selectorRegexp = regexp.MustCompile(`^(\!*)(\([ \t]*[A-Za-z_]+[A-Za-z0-9_\.]*[ \t]*\))?(\$)([\)\[\],\+\-\*\/%><\|&!=\^ \t\\\?]|$)`)
 
-func findSelector(expr *string) (field string, name string, subSelector []string, boolPrefix *bool, found bool) {
+var elemFieldRegexp = regexp.MustCompile(`^\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+func findSelector(expr *string) (field string, name string, subSelector []string, safe []bool, boolPrefix *bool, found bool) {
 	raw := *expr
 	a := selectorRegexp.FindAllStringSubmatch(raw, -1)
 	if len(a) != 1 {
@@ -64,15 +68,34 @@ func findSelector(expr *string) (field string, name string, subSelector []string
 	name = r[3]
 	*expr = (*expr)[len(a[0][0])-len(r[4]):]
 	for {
+		// the nil-safe subscript, such as $?.Name and $?[0], yields nil instead of Unreachable{}
+		isSafe := strings.HasPrefix(*expr, "?.") || strings.HasPrefix(*expr, "?[")
+		if isSafe {
+			*expr = (*expr)[1:]
+		}
+		if len(subSelector) > 0 || isSafe {
+			// the field of the struct element, such as $[0].Name, is the same as $[0]['Name']
+			if m := elemFieldRegexp.FindStringSubmatch(*expr); m != nil {
+				*expr = (*expr)[len(m[0]):]
+				subSelector = append(subSelector, "'"+m[1]+"'")
+				safe = appendSafe(safe, len(subSelector), isSafe)
+				continue
+			}
+		}
 		sub := readPairedSymbol(expr, '[', ']')
 		if sub == nil {
+			if isSafe {
+				*expr = raw
+				return "", "", nil, nil, nil, false
+			}
 			break
 		}
 		if *sub == "" || (*sub)[0] == '[' {
 			*expr = raw
-			return "", "", nil, nil, false
+			return "", "", nil, nil, nil, false
 		}
 		subSelector = append(subSelector, strings.TrimSpace(*sub))
+		safe = appendSafe(safe, len(subSelector), isSafe)
 	}
 	if boolNum := len(r[1]); boolNum > 0 {
 		bol := true
@@ -85,10 +108,21 @@ func findSelector(expr *string) (field string, name string, subSelector []string
 	return
 }
 
+// appendSafe appends the nil-safe flag of the nth subscript, safe is allocated on the first nil-safe one.
+func appendSafe(safe []bool, n int, isSafe bool) []bool {
+	if safe == nil && !isSafe {
+		return nil
+	}
+	for len(safe) < n-1 {
+		safe = append(safe, false)
+	}
+	return append(safe, isSafe)
+}
+
 func (ve *selectorExprNode) Run(currField string, tagExpr *TagExpr) interface{} {
 	var subFields []interface{}
 	if n := len(ve.subExprs); n > 0 {
-		subFields = make([]interface{}, n)
+		subFields = tagExpr.allocArgs(n)
 		for i, e := range ve.subExprs {
 			subFields[i] = e.Run(currField, tagExpr)
 		}
@@ -97,7 +131,7 @@ func (ve *selectorExprNode) Run(currField string, tagExpr *TagExpr) interface{}
 	if field == "" {
 		field = currField
 	}
-	v := tagExpr.getValue(field, subFields)
+	v := tagExpr.getValue(field, subFields, ve.safe)
 	if ve.boolPrefix == nil {
 		return v
 	}
@@ -102,6 +102,7 @@ func TestFindSelector(t *testing.T) {
 		field       string
 		name        string
 		subSelector []string
+		safe        []bool
 		boolPrefix  *bool
 		found       bool
 		last        string
@@ -124,10 +125,14 @@ func TestFindSelector(t *testing.T) {
 		{expr: "$[[[]]]", field: "", name: "", subSelector: nil, last: "$[[[]]]"},
 		{expr: "$[(A)$[1]]", field: "", name: "$", subSelector: []string{"(A)$[1]"}, found: true, last: ""},
 		{expr: "$>0&&$<10", field: "", name: "$", subSelector: nil, found: true, last: ">0&&$<10"},
+		{expr: "(A)$?.B?.C", field: "A", name: "$", subSelector: []string{"'B'", "'C'"}, safe: []bool{true, true}, found: true, last: ""},
+		{expr: "$[0]?[1].C==1", field: "", name: "$", subSelector: []string{"0", "1", "'C'"}, safe: []bool{false, true, false}, found: true, last: "==1"},
+		{expr: "$?", field: "", name: "$", subSelector: nil, found: true, last: "?"},
+		{expr: "$?.1", field: "", name: "", subSelector: nil, last: "$?.1"},
 	}
 	for _, c := range cases {
 		last := c.expr
-		field, name, subSelector, boolPrefix, found := findSelector(&last)
+		field, name, subSelector, safe, boolPrefix, found := findSelector(&last)
 		if found != c.found {
 			t.Fatalf("%q found: got: %v, want: %v", c.expr, found, c.found)
 		}
@@ -143,6 +148,9 @@ func TestFindSelector(t *testing.T) {
 		if !reflect.DeepEqual(subSelector, c.subSelector) {
 			t.Fatalf("%q subSelector: got: %v, want: %v", c.expr, subSelector, c.subSelector)
 		}
+		if !reflect.DeepEqual(safe, c.safe) {
+			t.Fatalf("%q safe: got: %v, want: %v", c.expr, safe, c.safe)
+		}
 		if last != c.last {
 			t.Fatalf("%q last: got: %q, want: %q", c.expr, last, c.last)
 		}
@@ -16,9 +16,11 @@
 package tagexpr
 
 import (
-	"errors"
-	"fmt"
+	"encoding/json"
+	"path"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"unsafe"
@@ -26,18 +28,55 @@ import (
 
 // VM struct tag expression interpreter
 type VM struct {
-	tagName   string
-	structJar map[string]*Struct
-	rw        sync.RWMutex
+	tagName         string
+	structJar       map[reflect.Type]*Struct
+	rw              sync.RWMutex
+	passwordPolicy  *passwordPolicy
+	markupPolicy    *markupPolicy
+	enums           map[string]map[interface{}]bool
+	regexps         *regexpCache
+	costOrder       bool
+	selectorFold    bool
+	compatTagName   string
+	coverage        bool
+	parseLimits     ParseLimits
+	registerLimits  RegisterLimits
+	unsupported     UnsupportedPolicy
+	unsupportedWarn func(structName string, field IgnoredField)
+	noRecover       bool
+	unreachable     bool
+	params          Params
+	flagProvider    FlagProvider
+	jwtAlgs         map[string]bool
+	parent          *VM // the vm whose registered structs are shared by the clone
+	ruleCache       map[string]*cachedNode
+	registryOnce    sync.Once
+	registryErr     error
 }
 
 // Struct tag expression set of struct
 type Struct struct {
-	vm           *VM
-	name         string
-	fields       map[string]*Field
-	exprs        map[string]*Expr
-	selectorList []string
+	vm         *VM
+	name       string
+	fields     map[string]*Field
+	exprs      map[string]*exprEntry
+	exprList   []*exprEntry
+	sanitizers []*exprEntry // the san expressions, evaluated by Sanitize
+	calcs      []*exprEntry // the calc expressions in the dependency order, evaluated by Calc
+	redactions []*exprEntry // the redact expressions, evaluated by VM.Redact
+	compares   []*exprEntry // the cmp expressions, evaluated by VM.Equal
+	aliasOnce  sync.Once
+	aliases    *selectorAliases
+	depth      int            // the nesting depth of the struct fields, 1 if there is no struct field
+	ignored    []IgnoredField // the fields whose values are always nil
+}
+
+// exprEntry the expression with its selectors computed at registration,
+// so that no string slicing is needed when evaluating.
+type exprEntry struct {
+	selector string
+	field    string
+	expr     *Expr
 }
 
 // Field tag expression set of struct field
@@ -45,24 +84,32 @@ type Field struct {
 	reflect.StructField
 	host        *Struct
 	valueGetter func(uintptr) interface{}
+	addrGetter  func(uintptr) uintptr // returns the field address, 0 if unreachable
+	jsonName    string                // the json names path of the field
+	jsonIgnored bool                  // the field or any parent is not encoded by the JSON encoder
+	mapName     string                // the mapstructure names path of the field
 }
 
 // New creates a tag expression interpreter that uses @tagName as the tag name.
 func New(tagName string) *VM {
 	return &VM{
 		tagName:   tagName,
-		structJar: make(map[string]*Struct, 256),
+		structJar: make(map[reflect.Type]*Struct, 256),
+		regexps:   newRegexpCache(),
 	}
 }
 
 // WarmUp preheating some interpreters of the struct type in batches,
 // to improve the performance of the vm.Run.
 func (vm *VM) WarmUp(structOrStructPtr ...interface{}) error {
+	if vm.parent != nil {
+		return vm.parent.WarmUp(structOrStructPtr...)
+	}
 	vm.rw.Lock()
 	defer vm.rw.Unlock()
 	for _, v := range structOrStructPtr {
 		if v == nil {
-			return errors.New("cannot warn up nil interface")
+			return newKindError(ErrNilDeref, "cannot warn up nil interface")
 		}
 		_, err := vm.registerStructLocked(reflect.TypeOf(v))
 		if err != nil {
@@ -72,31 +119,173 @@ func (vm *VM) WarmUp(structOrStructPtr ...interface{}) error {
 	return nil
 }
 
+// SetCostOrder sets whether to reorder the operands of the && and || chains
+// from cheap to expensive, such as comparisons before regexps and functions.
+// NOTE:
+//  The operands that may have side effects, such as the functions registered by RegFunc,
+//  the plugins and the references to the other expressions such as @Name, are not reordered;
+//  It should be called before the vm is used.
+func (vm *VM) SetCostOrder(enable bool) *VM {
+	vm.costOrder = enable
+	return vm
+}
+
 // Run returns the tag expression handler of the @structPtr.
 // NOTE:
 //  If the structure type has not been warmed up,
 //  it will be slower when it is first called.
 func (vm *VM) Run(structPtr interface{}) (*TagExpr, error) {
+	s, ptr, err := vm.getStruct(structPtr)
+	if err != nil {
+		return nil, err
+	}
+	te := s.newTagExpr(ptr)
+	te.vm = vm
+	return te, nil
+}
+
+// RunValue returns the tag expression handler of the struct pointer or the addressable struct.
+func (vm *VM) RunValue(v reflect.Value) (*TagExpr, error) {
+	switch {
+	case v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct:
+		if v.IsNil() {
+			return nil, newKindError(ErrNilDeref, "cannot run nil pointer")
+		}
+	case v.Kind() == reflect.Struct && v.CanAddr():
+		v = v.Addr()
+	case !v.IsValid():
+		return nil, newKindError(ErrNilDeref, "cannot run invalid value")
+	default:
+		return nil, newKindError(ErrTypeMismatch, "not structure pointer or addressable structure: %s", v.Type().String())
+	}
+	s, err := vm.loadStruct(v.Type().Elem())
+	if err != nil {
+		return nil, err
+	}
+	te := s.newTagExpr(v.Pointer())
+	te.vm = vm
+	return te, nil
+}
+
+// ParseExpr parses the expression, such as $>0&&(A)$!='', with the optional declared result type like the tags, such as bool: $>0,
+// the error is *SyntaxError if the syntax is incorrect, whose offset is of the whole expression.
+// NOTE:
+//  The error is ErrTypeMismatch if the inferred result type does not match the declared one.
+func (vm *VM) ParseExpr(expr string) (*Expr, error) {
+	return parseTypedExpr(vm, strconv.Quote(expr), expr)
+}
+
+// Struct returns the tag expression set of the struct type, registers it if necessary.
+// NOTE:
+//  The type can be structure or structure pointer.
+func (vm *VM) Struct(structType reflect.Type) (*Struct, error) {
+	if structType == nil {
+		return nil, newKindError(ErrNilDeref, "cannot run nil type")
+	}
+	t, err := vm.getStructType(structType)
+	if err != nil {
+		return nil, err
+	}
+	return vm.loadStruct(t)
+}
+
+// Name returns the type name of the struct.
+func (s *Struct) Name() string {
+	return s.name
+}
+
+// Range loop through each tag expression of the struct in the registration order.
+func (s *Struct) Range(fn func(selector string, expr *Expr) bool) {
+	for _, e := range s.exprList {
+		if !fn(e.selector, e.expr) {
+			return
+		}
+	}
+}
+
+// StructField returns the metadata of the field by the name.
+// NOTE:
+//  format: fieldName, fieldName1.fieldName2
+func (s *Struct) StructField(name string) (reflect.StructField, bool) {
+	f, ok := s.fields[name]
+	if !ok {
+		return reflect.StructField{}, false
+	}
+	return f.StructField, true
+}
+
+// FieldNames returns the sorted names of all the fields, including the nested ones, such as fieldName1.fieldName2.
+func (s *Struct) FieldNames() []string {
+	names := make([]string, 0, len(s.fields))
+	for name := range s.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IgnoredField the field whose value is always nil when evaluated, such as $ of a func or time.Time field
+type IgnoredField struct {
+	Field  string       // the field selector, such as fieldName1.fieldName2
+	Type   reflect.Type // the type of the field
+	Reason string       // why the value is nil, such as unsupported kind func
+}
+
+// IgnoredFields returns the fields whose values are always nil in the registration order, including the nested ones,
+// to find out at once that a rule such as $!=nil on a time.Time or custom type field never sees its value.
+// NOTE:
+//  The struct field is ignored as a value, its own fields can still be selected, such as (Addr.City)$;
+//  The struct types registered by RegNumberAdapter are not ignored.
+func (s *Struct) IgnoredFields() []IgnoredField {
+	return append([]IgnoredField(nil), s.ignored...)
+}
+
+func (s *Struct) ignore(field string, typ reflect.Type, reason string) {
+	s.ignored = append(s.ignored, IgnoredField{Field: field, Type: typ, Reason: reason})
+}
+
+// EvalAt returns the tag expression handler of the struct pointer.
+// NOTE:
+//  The pointer must point to the struct of the type that s is registered by.
+func (s *Struct) EvalAt(structPtr unsafe.Pointer) *TagExpr {
+	return s.newTagExpr(uintptr(structPtr))
+}
+
+// getStruct returns the tag expression set of the @structPtr type, and the pointer.
+func (vm *VM) getStruct(structPtr interface{}) (*Struct, uintptr, error) {
 	if structPtr == nil {
-		return nil, errors.New("cannot run nil interface")
+		return nil, 0, newKindError(ErrNilDeref, "cannot run nil interface")
 	}
 	v := reflect.ValueOf(structPtr)
 	if v.Kind() != reflect.Ptr {
-		return nil, fmt.Errorf("not structure pointer: %s", v.Type().String())
+		return nil, 0, newKindError(ErrTypeMismatch, "not structure pointer: %s", v.Type().String())
 	}
 	elem := v.Elem()
 	if elem.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("not structure pointer: %s", v.Type().String())
+		return nil, 0, newKindError(ErrTypeMismatch, "not structure pointer: %s", v.Type().String())
+	}
+	s, err := vm.loadStruct(elem.Type())
+	if err != nil {
+		return nil, 0, err
+	}
+	return s, v.Pointer(), nil
+}
+
+// loadStruct returns the tag expression set of the struct type, registers it if necessary.
+// NOTE:
+//  The struct is keyed by the reflect.Type, since the type names may collide,
+//  such as the instantiations of the generic types and the local types of the same name.
+func (vm *VM) loadStruct(t reflect.Type) (*Struct, error) {
+	if vm.parent != nil {
+		return vm.parent.loadStruct(t)
 	}
-	t := elem.Type()
-	tname := t.String()
 	var err error
 	vm.rw.RLock()
-	s, ok := vm.structJar[tname]
+	s, ok := vm.structJar[t]
 	vm.rw.RUnlock()
 	if !ok {
 		vm.rw.Lock()
-		s, ok = vm.structJar[tname]
+		s, ok = vm.structJar[t]
 		if !ok {
 			s, err = vm.registerStructLocked(t)
 			if err != nil {
@@ -106,26 +295,48 @@ func (vm *VM) Run(structPtr interface{}) (*TagExpr, error) {
 		}
 		vm.rw.Unlock()
 	}
-	return s.newTagExpr(v.Pointer()), nil
+	return s, nil
+}
+
+// skipTag the tag of the field that is excluded from the registration, such as `te:"-"`,
+// the field and its sub-struct have neither expressions nor selectors.
+const skipTag = "-"
+
+// skipped reports whether the field is tagged with skipTag, by the compatible tag if it has no tag of the vm.
+func (vm *VM) skipped(structField reflect.StructField) bool {
+	tag, ok := structField.Tag.Lookup(vm.tagName)
+	if !ok && vm.compatTagName != "" {
+		tag = strings.TrimSpace(structField.Tag.Get(vm.compatTagName))
+	}
+	return tag == skipTag
 }
 
-func (vm *VM) registerStructLocked(structType reflect.Type) (*Struct, error) {
-	structType, err := vm.getStructType(structType)
+func (vm *VM) registerStructLocked(structType reflect.Type) (s *Struct, err error) {
+	structType, err = vm.getStructType(structType)
 	if err != nil {
 		return nil, err
 	}
-	structTypeName := structType.String()
-	s, had := vm.structJar[structTypeName]
+	s, had := vm.structJar[structType]
 	if had {
 		return s, nil
 	}
 	s = vm.newStruct()
-	vm.structJar[structTypeName] = s
+	s.name = structType.String()
+	vm.structJar[structType] = s
+	defer func() {
+		if err != nil {
+			delete(vm.structJar, structType)
+		}
+	}()
+	limits := vm.getRegisterLimits()
 	var numField = structType.NumField()
 	var structField reflect.StructField
 	var sub *Struct
 	for i := 0; i < numField; i++ {
 		structField = structType.Field(i)
+		if vm.skipped(structField) {
+			continue
+		}
 		field, err := s.newField(structField)
 		if err != nil {
 			return nil, err
@@ -136,15 +347,36 @@ func (vm *VM) registerStructLocked(structType reflect.Type) (*Struct, error) {
 			t = t.Elem()
 			ptrDeep++
 		}
+		if toRat, ok := numberAdapters[t]; ok {
+			field.setRatGetter(t, toRat, ptrDeep)
+			continue
+		}
 		switch t.Kind() {
 		default:
 			field.valueGetter = func(ptr uintptr) interface{} { return nil }
+			s.ignore(field.Name, field.Type, "unsupported kind "+t.Kind().String())
+			if err = vm.checkUnsupported(s, field, t.Kind()); err != nil {
+				return nil, err
+			}
 		case reflect.Struct:
+			s.ignore(field.Name, field.Type, "struct value, only its fields are selectable")
 			sub, err = vm.registerStructLocked(field.Type)
 			if err != nil {
 				return nil, err
 			}
+			if sub.depth >= s.depth {
+				s.depth = sub.depth + 1
+			}
+			if err = limits.check(s); err != nil {
+				return nil, err
+			}
 			s.copySubFields(field, sub, ptrDeep)
+			for _, f := range sub.ignored {
+				s.ignore(field.Name+"."+f.Field, f.Type, f.Reason)
+			}
+			if err = limits.check(s); err != nil {
+				return nil, err
+			}
 		case reflect.Float32, reflect.Float64,
 			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
@@ -154,18 +386,30 @@ func (vm *VM) registerStructLocked(structType reflect.Type) (*Struct, error) {
 		case reflect.Bool:
 			field.setBoolGetter(ptrDeep)
 		case reflect.Map, reflect.Array, reflect.Slice:
-			field.setLengthGetter(ptrDeep)
+			if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+				field.setBytesGetter(ptrDeep, t == rawMessageType)
+				break
+			}
+			field.setLengthGetter(t, ptrDeep)
 		}
 	}
+	s.overrideEmbedded()
+	if s.calcs, err = sortCalcs(s.calcs); err != nil {
+		return nil, err
+	}
+	if err = checkRuleCycles(s); err != nil {
+		return nil, err
+	}
 	return s, nil
 }
 
 func (vm *VM) newStruct() *Struct {
 	return &Struct{
-		vm:           vm,
-		fields:       make(map[string]*Field, 16),
-		exprs:        make(map[string]*Expr, 64),
-		selectorList: make([]string, 0, 64),
+		vm:       vm,
+		fields:   make(map[string]*Field, 16),
+		exprs:    make(map[string]*exprEntry, 64),
+		exprList: make([]*exprEntry, 0, 64),
+		depth:    1,
 	}
 }
 
@@ -173,8 +417,20 @@ func (s *Struct) newField(structField reflect.StructField) (*Field, error) {
 	f := &Field{
 		StructField: structField,
 		host:        s,
+		mapName:     mapstructureFieldName(structField),
+	}
+	f.jsonName, f.jsonIgnored = jsonFieldName(structField)
+	f.addrGetter = func(ptr uintptr) uintptr {
+		return ptr + f.Offset
 	}
-	err := f.parseExprs(structField.Tag.Get(s.vm.tagName))
+	tag, ok := structField.Tag.Lookup(s.vm.tagName)
+	if !ok && s.vm.compatTagName != "" {
+		var err error
+		if tag, err = s.vm.translateCompatTag(s, structField); err != nil {
+			return nil, err
+		}
+	}
+	err := f.parseExprs(tag)
 	if err != nil {
 		return nil, err
 	}
@@ -182,65 +438,79 @@ func (s *Struct) newField(structField reflect.StructField) (*Field, error) {
 	return f, nil
 }
 
-func (f *Field) newFrom(ptr uintptr, ptrDeep int) reflect.Value {
-	v := reflect.NewAt(f.Type, unsafe.Pointer(ptr+f.Offset)).Elem()
+// derefPtr chases the pointers of the ptrDeep levels,
+// returns 0 if any of them is nil.
+func derefPtr(ptr uintptr, ptrDeep int) uintptr {
 	for i := 0; i < ptrDeep; i++ {
-		v = v.Elem()
+		p := *(*unsafe.Pointer)(unsafe.Pointer(ptr))
+		if p == nil {
+			return 0
+		}
+		ptr = uintptr(p)
 	}
-	return v
+	return ptr
 }
 
 func (f *Field) setFloatGetter(kind reflect.Kind, ptrDeep int) {
+	get, offset := float64Getters[kind], f.Offset
 	if ptrDeep == 0 {
 		f.valueGetter = func(ptr uintptr) interface{} {
-			return getFloat64(kind, ptr+f.Offset)
+			return get(ptr + offset)
 		}
 	} else {
 		f.valueGetter = func(ptr uintptr) interface{} {
-			v := f.newFrom(ptr, ptrDeep)
-			if v.CanAddr() {
-				return getFloat64(kind, v.UnsafeAddr())
+			if ptr = derefPtr(ptr+offset, ptrDeep); ptr == 0 {
+				return nil
 			}
-			return nil
+			return get(ptr)
 		}
 	}
 }
 
 func (f *Field) setBoolGetter(ptrDeep int) {
-	if ptrDeep == 0 {
-		f.valueGetter = func(ptr uintptr) interface{} {
-			return *(*bool)(unsafe.Pointer(ptr + f.Offset))
-		}
-	} else {
-		f.valueGetter = func(ptr uintptr) interface{} {
-			v := f.newFrom(ptr, ptrDeep)
-			if v.IsValid() {
-				return v.Bool()
-			}
+	f.valueGetter = func(ptr uintptr) interface{} {
+		if ptr = derefPtr(ptr+f.Offset, ptrDeep); ptr == 0 {
 			return nil
 		}
+		return *(*bool)(unsafe.Pointer(ptr))
 	}
 }
 
 func (f *Field) setStringGetter(ptrDeep int) {
-	if ptrDeep == 0 {
-		f.valueGetter = func(ptr uintptr) interface{} {
-			return *(*string)(unsafe.Pointer(ptr + f.Offset))
+	f.valueGetter = func(ptr uintptr) interface{} {
+		if ptr = derefPtr(ptr+f.Offset, ptrDeep); ptr == 0 {
+			return nil
 		}
-	} else {
-		f.valueGetter = func(ptr uintptr) interface{} {
-			v := f.newFrom(ptr, ptrDeep)
-			if v.IsValid() {
-				return v.String()
-			}
+		return *(*string)(unsafe.Pointer(ptr))
+	}
+}
+
+// setBytesGetter sets the getter of the []byte field, whose value is used as string.
+// NOTE:
+//  The string is copied from the bytes, since it may be kept by the results, the calc and san assignments
+//  and the regexp cache, which must not change with the bytes;
+//  If nilAbsent is true, such as json.RawMessage, the nil bytes are nil, which means absent.
+func (f *Field) setBytesGetter(ptrDeep int, nilAbsent bool) {
+	f.valueGetter = func(ptr uintptr) interface{} {
+		if ptr = derefPtr(ptr+f.Offset, ptrDeep); ptr == 0 {
+			return nil
+		}
+		b := *(*[]byte)(unsafe.Pointer(ptr))
+		if nilAbsent && b == nil {
 			return nil
 		}
+		return string(b)
 	}
 }
 
-func (f *Field) setLengthGetter(ptrDeep int) {
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+func (f *Field) setLengthGetter(elemType reflect.Type, ptrDeep int) {
 	f.valueGetter = func(ptr uintptr) interface{} {
-		return f.newFrom(ptr, ptrDeep).Interface()
+		if ptr = derefPtr(ptr+f.Offset, ptrDeep); ptr == 0 {
+			return nil
+		}
+		return reflect.NewAt(elemType, unsafe.Pointer(ptr)).Elem().Interface()
 	}
 }
 
@@ -251,13 +521,12 @@ func (f *Field) parseExprs(tag string) error {
 		return nil
 	}
 	if tag[0] != '{' {
-		expr, err := parseExpr(tag)
+		selector := f.Name + "@"
+		expr, err := parseTypedExpr(f.host.vm, selector, tag)
 		if err != nil {
 			return err
 		}
-		selector := f.Name + "@"
-		f.host.exprs[selector] = expr
-		f.host.selectorList = append(f.host.selectorList, selector)
+		f.host.addExpr(selector, expr)
 		return nil
 	}
 	var subtag *string
@@ -278,13 +547,12 @@ func (f *Field) parseExprs(tag string) error {
 					selector = f.Name + "@" + selector
 				}
 				if _, had := f.host.exprs[selector]; had {
-					return fmt.Errorf("duplicate expression name: %s", selector)
+					return newKindError(ErrSyntax, "duplicate expression name: %s", selector)
 				}
 				exprStr = strings.TrimSpace((*subtag)[idx+1:])
 				if exprStr != "" {
-					if expr, err := parseExpr(exprStr); err == nil {
-						f.host.exprs[selector] = expr
-						f.host.selectorList = append(f.host.selectorList, selector)
+					if expr, err := parseTypedExpr(f.host.vm, selector, exprStr); err == nil {
+						f.host.addExpr(selector, expr)
 					} else {
 						return err
 					}
@@ -296,17 +564,26 @@ func (f *Field) parseExprs(tag string) error {
 				}
 			}
 		}
-		return fmt.Errorf("syntax incorrect: %q", raw)
+		return newKindError(ErrSyntax, "syntax incorrect: %q", raw)
 	}
 }
 
 func (s *Struct) copySubFields(field *Field, sub *Struct, ptrDeep int) {
 	nameSpace := field.Name
 	for k, v := range sub.fields {
-		valueGetter := v.valueGetter
+		valueGetter, addrGetter := v.valueGetter, v.addrGetter
 		f := &Field{
 			StructField: v.StructField,
 			host:        v.host,
+			jsonName:    joinJSONName(field.jsonName, v.jsonName),
+			jsonIgnored: field.jsonIgnored || v.jsonIgnored,
+			mapName:     joinJSONName(field.mapName, v.mapName),
+		}
+		f.addrGetter = func(ptr uintptr) uintptr {
+			if ptr = derefPtr(ptr+field.Offset, ptrDeep); ptr == 0 {
+				return 0
+			}
+			return addrGetter(ptr)
 		}
 		if valueGetter != nil {
 			if ptrDeep == 0 {
@@ -315,21 +592,37 @@ func (s *Struct) copySubFields(field *Field, sub *Struct, ptrDeep int) {
 				}
 			} else {
 				f.valueGetter = func(ptr uintptr) interface{} {
-					newField := reflect.NewAt(field.Type, unsafe.Pointer(ptr+field.Offset))
-					for i := 0; i < ptrDeep; i++ {
-						newField = newField.Elem()
+					if ptr = derefPtr(ptr+field.Offset, ptrDeep); ptr == 0 {
+						return nil
 					}
-					return valueGetter(uintptr(newField.Pointer()))
+					return valueGetter(ptr)
 				}
 			}
 		}
 		s.fields[nameSpace+"."+k] = f
 	}
-	var selector string
-	for k, v := range sub.exprs {
-		selector = nameSpace + "." + k
-		s.exprs[selector] = v
-		s.selectorList = append(s.selectorList, selector)
+	for _, e := range sub.exprList {
+		s.addExpr(nameSpace+"."+e.selector, e.expr)
+	}
+}
+
+func (s *Struct) addExpr(selector string, expr *Expr) {
+	e := &exprEntry{
+		selector: selector,
+		field:    getFieldSelector(selector),
+		expr:     expr,
+	}
+	s.exprs[selector] = e
+	s.exprList = append(s.exprList, e)
+	switch {
+	case strings.HasSuffix(selector, "@"+sanExprName):
+		s.sanitizers = append(s.sanitizers, e)
+	case strings.HasSuffix(selector, "@"+calcExprName):
+		s.calcs = append(s.calcs, e)
+	case strings.HasSuffix(selector, "@"+redactExprName):
+		s.redactions = append(s.redactions, e)
+	case strings.HasSuffix(selector, "@"+cmpExprName):
+		s.compares = append(s.compares, e)
 	}
 }
 
@@ -339,7 +632,7 @@ func (vm *VM) getStructType(t reflect.Type) (reflect.Type, error) {
 		structType = structType.Elem()
 	}
 	if structType.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("not structure pointer or structure: %s", t.String())
+		return nil, newKindError(ErrTypeMismatch, "not structure pointer or structure: %s", t.String())
 	}
 	return structType, nil
 }
@@ -354,8 +647,12 @@ func (s *Struct) newTagExpr(ptr uintptr) *TagExpr {
 
 // TagExpr struct tag expression evaluator
 type TagExpr struct {
-	s   *Struct
-	ptr uintptr
+	s      *Struct
+	vm     *VM // the vm that runs it, whose runtime options are used, s.vm if it is nil
+	ptr    uintptr
+	arena  *Arena
+	params Params
+	frame  *evalFrame // the state of one evaluation, only set on the copy made by withFrame
 }
 
 // EvalFloat evaluate the value of the struct tag expression by the selector expression.
@@ -385,75 +682,270 @@ func (t *TagExpr) EvalBool(selector string) bool {
 // Eval evaluate the value of the struct tag expression by the selector expression.
 // NOTE:
 //  format: fieldName, fieldName.exprName, fieldName1.fieldName2.exprName1
-//  result types: float64, string, bool, nil
+//  result types: float64, string, bool, nil, and *big.Rat for the number adapters;
+//  Return nil if the evaluation panics, use EvalWithError to get the *EvalError.
 func (t *TagExpr) Eval(selector string) interface{} {
-	expr, ok := t.s.exprs[selector]
+	e, ok := t.s.getExpr(selector)
 	if !ok {
 		return nil
 	}
-	return expr.run(getFieldSelector(selector), t)
+	v, _ := t.safeRun(e)
+	return v
+}
+
+// EvalGlob evaluates all the tag expressions whose selectors match the pattern,
+// returns the map of the selector to the value.
+// NOTE:
+//  pattern syntax: the same as path.Match, such as User.*@, Addr*, *@msg;
+//  Return nil if the pattern is malformed.
+func (t *TagExpr) EvalGlob(pattern string) map[string]interface{} {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil
+	}
+	r := make(map[string]interface{})
+	for _, e := range t.s.exprList {
+		if ok, _ := path.Match(pattern, e.selector); ok {
+			r[e.selector], _ = t.safeRun(e)
+		}
+	}
+	return r
+}
+
+// Expr returns the parsed expression by the selector expression.
+// NOTE:
+//  The constant sub-expressions have been folded, Expr.String returns the simplified form.
+func (t *TagExpr) Expr(selector string) (*Expr, bool) {
+	e, ok := t.s.getExpr(selector)
+	if !ok {
+		return nil, false
+	}
+	return e.expr, true
+}
+
+// FieldHandle the struct field bound to the struct value of the TagExpr
+type FieldHandle struct {
+	field *Field
+	addr  uintptr
+}
+
+// Field returns the handle of the field by the name.
+// NOTE:
+//  format: fieldName, fieldName1.fieldName2
+func (t *TagExpr) Field(name string) (FieldHandle, bool) {
+	f, ok := t.s.fields[name]
+	if !ok {
+		return FieldHandle{}, false
+	}
+	return FieldHandle{field: f, addr: f.addrGetter(t.ptr)}, true
+}
+
+// StructField returns the metadata of the field.
+func (h FieldHandle) StructField() reflect.StructField {
+	return h.field.StructField
+}
+
+// Reachable reports whether the field is reachable,
+// false if any pointer of the parent struct chain is nil.
+func (h FieldHandle) Reachable() bool {
+	return h.addr != 0
+}
+
+// Value returns the current value of the field in its own type,
+// the invalid value if it is not reachable.
+func (h FieldHandle) Value() reflect.Value {
+	if h.addr == 0 {
+		return reflect.Value{}
+	}
+	return reflect.NewAt(h.field.Type, unsafe.Pointer(h.addr)).Elem()
+}
+
+// Interface returns the current value of the field in its own type, nil if it is not reachable.
+func (h FieldHandle) Interface() interface{} {
+	if h.addr == 0 {
+		return nil
+	}
+	return h.Value().Interface()
 }
 
 // Range loop through each tag expression
 // NOTE:
-//  eval result types: float64, string, bool, nil
+//  eval result types: float64, string, bool, nil, and *big.Rat for the number adapters
 func (t *TagExpr) Range(fn func(selector string, eval func() interface{}) bool) {
-	exprs := t.s.exprs
-	for _, selector := range t.s.selectorList {
-		if !fn(selector, func() interface{} {
-			return exprs[selector].run(getFieldSelector(selector), t)
+	for _, e := range t.s.exprList {
+		e := e
+		if !fn(e.selector, func() interface{} {
+			v, _ := t.safeRun(e)
+			return v
 		}) {
 			return
 		}
 	}
 }
 
-func (t *TagExpr) getValue(field string, subFields []interface{}) (v interface{}) {
-	f, ok := t.s.fields[field]
-	if !ok {
+// getVM returns the interpreter, nil if t is nil.
+func (t *TagExpr) getVM() *VM {
+	if t == nil || t.s == nil {
 		return nil
 	}
-	if f.valueGetter == nil {
-		return nil
+	if t.vm != nil {
+		return t.vm
 	}
-	v = f.valueGetter(t.ptr)
-	if v == nil {
+	return t.s.vm
+}
+
+func (t *TagExpr) getValue(field string, subFields []interface{}, safe []bool) (v interface{}) {
+	f, ok := t.s.fields[field]
+	if !ok {
 		return nil
 	}
-	if len(subFields) == 0 {
-		return v
+	v, step, ok := t.lookupValue(f, subFields)
+	if !ok && (step == 0 || step > len(safe) || !safe[step-1]) {
+		return t.unreachableValue()
 	}
-	vv := reflect.ValueOf(v)
-	for _, k := range subFields {
-		for vv.Kind() == reflect.Ptr {
+	return v
+}
+
+// lookupValue returns the value of the field and the subscripts,
+// false and the step that cannot be reached if the path is unreachable,
+// step 0 is the field, step i is the ith subscript.
+func (t *TagExpr) lookupValue(f *Field, subFields []interface{}) (_ interface{}, step int, _ bool) {
+	var vv reflect.Value
+	if f.valueGetter == nil {
+		if len(subFields) == 0 {
+			return nil, 0, true
+		}
+		// the struct field, navigated by the subscripts such as $?.Name
+		addr := f.addrGetter(t.ptr)
+		if addr == 0 {
+			return nil, 0, false
+		}
+		vv = reflect.NewAt(f.Type, unsafe.Pointer(addr)).Elem()
+	} else {
+		v := f.valueGetter(t.ptr)
+		if v == nil {
+			if f.addrGetter(t.ptr) == 0 {
+				return nil, 0, false
+			}
+			if len(subFields) > 0 && f.Type.Kind() == reflect.Ptr {
+				return nil, 1, false
+			}
+			return nil, 0, true
+		}
+		if len(subFields) == 0 {
+			return v, 0, true
+		}
+		vv = reflect.ValueOf(v)
+	}
+	for i, k := range subFields {
+		step = i + 1
+		// the interface elements of the decoded JSON, such as map[string]interface{} and []interface{}
+		for vv.Kind() == reflect.Ptr || vv.Kind() == reflect.Interface {
+			if vv.IsNil() {
+				return nil, step, false
+			}
 			vv = vv.Elem()
 		}
 		switch vv.Kind() {
 		case reflect.Slice, reflect.Array, reflect.String:
-			if float, ok := k.(float64); ok {
-				idx := int(float)
-				if idx >= vv.Len() {
-					return nil
+			float, ok := k.(float64)
+			if !ok {
+				return nil, step, false
+			}
+			idx := int(float)
+			if idx < 0 || idx >= vv.Len() {
+				return nil, step, false
+			}
+			vv = vv.Index(idx)
+		case reflect.Struct:
+			name, ok := k.(string)
+			if !ok {
+				return nil, step, false
+			}
+			sf, ok := vv.Type().FieldByName(name)
+			if !ok || sf.PkgPath != "" {
+				return nil, step, false
+			}
+			for i, x := range sf.Index {
+				if i > 0 && vv.Kind() == reflect.Ptr {
+					if vv.IsNil() {
+						return nil, step, false
+					}
+					vv = vv.Elem()
 				}
-				vv = vv.Index(idx)
-			} else {
-				return nil
+				vv = vv.Field(x)
 			}
 		case reflect.Map:
 			k := safeConvert(reflect.ValueOf(k), vv.Type().Key())
 			if !k.IsValid() {
-				return nil
+				return nil, step, false
+			}
+			if vv = vv.MapIndex(k); !vv.IsValid() {
+				return nil, step, false
 			}
-			vv = vv.MapIndex(k)
 		default:
-			return nil
+			return nil, step, false
 		}
 	}
-	for vv.Kind() == reflect.Ptr {
+	return toExprValue(vv), step, true
+}
+
+// existsValue reports whether the field and the subscripts are present,
+// the pointer or interface field without subscripts is not present if it is nil.
+func (t *TagExpr) existsValue(field string, subFields []interface{}) bool {
+	if t == nil || t.s == nil {
+		return false
+	}
+	f, ok := t.s.fields[field]
+	if !ok {
+		return false
+	}
+	if len(subFields) > 0 {
+		_, _, ok = t.lookupValue(f, subFields)
+		return ok
+	}
+	addr := f.addrGetter(t.ptr)
+	if addr == 0 {
+		return false
+	}
+	switch f.Type.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return !reflect.NewAt(f.Type, unsafe.Pointer(addr)).Elem().IsNil()
+	}
+	return true
+}
+
+// toExprValue converts the reflect value to the expression value,
+// the number types are converted to float64.
+// NOTE:
+//  The pointers and the interfaces are dereferenced, such as the elements of []*int and []interface{},
+//  so that they are coerced the same as the scalar fields.
+func toExprValue(vv reflect.Value) interface{} {
+	for vv.Kind() == reflect.Ptr || vv.Kind() == reflect.Interface {
 		vv = vv.Elem()
 	}
 	switch vv.Kind() {
+	case reflect.Invalid:
+		return nil
 	default:
+		if r, ok := adaptNumber(vv); ok {
+			return r
+		}
+		if vv.CanInterface() {
+			return vv.Interface()
+		}
+		return nil
+	case reflect.Slice:
+		if vv.Type().Elem().Kind() == reflect.Uint8 {
+			if vv.IsNil() && vv.Type() == rawMessageType {
+				return nil
+			}
+			return string(vv.Bytes())
+		}
+		if !vv.IsNil() && vv.CanInterface() {
+			return vv.Interface()
+		}
+		return nil
+	case reflect.Map, reflect.Chan, reflect.Func:
 		if !vv.IsNil() && vv.CanInterface() {
 			return vv.Interface()
 		}
@@ -488,34 +980,26 @@ func getFieldSelector(selector string) string {
 }
 
 func getFloat64(kind reflect.Kind, ptr uintptr) interface{} {
-	p := unsafe.Pointer(ptr)
-	switch kind {
-	case reflect.Float32:
-		return float64(*(*float32)(p))
-	case reflect.Float64:
-		return *(*float64)(p)
-	case reflect.Int:
-		return float64(*(*int)(p))
-	case reflect.Int8:
-		return float64(*(*int8)(p))
-	case reflect.Int16:
-		return float64(*(*int16)(p))
-	case reflect.Int32:
-		return float64(*(*int32)(p))
-	case reflect.Int64:
-		return float64(*(*int64)(p))
-	case reflect.Uint:
-		return float64(*(*uint)(p))
-	case reflect.Uint8:
-		return float64(*(*uint8)(p))
-	case reflect.Uint16:
-		return float64(*(*uint16)(p))
-	case reflect.Uint32:
-		return float64(*(*uint32)(p))
-	case reflect.Uint64:
-		return float64(*(*uint64)(p))
-	case reflect.Uintptr:
-		return float64(*(*uintptr)(p))
+	if get := float64Getters[kind]; get != nil {
+		return get(ptr)
 	}
 	return nil
 }
+
+// float64Getters the getters of the numeric kinds, so that the field getter
+// is specialized by the kind at registration, without switching on each read.
+var float64Getters = [reflect.UnsafePointer + 1]func(ptr uintptr) interface{}{
+	reflect.Float32: func(ptr uintptr) interface{} { return float64(*(*float32)(unsafe.Pointer(ptr))) },
+	reflect.Float64: func(ptr uintptr) interface{} { return *(*float64)(unsafe.Pointer(ptr)) },
+	reflect.Int:     func(ptr uintptr) interface{} { return float64(*(*int)(unsafe.Pointer(ptr))) },
+	reflect.Int8:    func(ptr uintptr) interface{} { return float64(*(*int8)(unsafe.Pointer(ptr))) },
+	reflect.Int16:   func(ptr uintptr) interface{} { return float64(*(*int16)(unsafe.Pointer(ptr))) },
+	reflect.Int32:   func(ptr uintptr) interface{} { return float64(*(*int32)(unsafe.Pointer(ptr))) },
+	reflect.Int64:   func(ptr uintptr) interface{} { return float64(*(*int64)(unsafe.Pointer(ptr))) },
+	reflect.Uint:    func(ptr uintptr) interface{} { return float64(*(*uint)(unsafe.Pointer(ptr))) },
+	reflect.Uint8:   func(ptr uintptr) interface{} { return float64(*(*uint8)(unsafe.Pointer(ptr))) },
+	reflect.Uint16:  func(ptr uintptr) interface{} { return float64(*(*uint16)(unsafe.Pointer(ptr))) },
+	reflect.Uint32:  func(ptr uintptr) interface{} { return float64(*(*uint32)(unsafe.Pointer(ptr))) },
+	reflect.Uint64:  func(ptr uintptr) interface{} { return float64(*(*uint64)(unsafe.Pointer(ptr))) },
+	reflect.Uintptr: func(ptr uintptr) interface{} { return float64(*(*uintptr)(unsafe.Pointer(ptr))) },
+}
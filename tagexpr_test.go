@@ -15,9 +15,20 @@
 package tagexpr
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 func BenchmarkTagExpr(b *testing.B) {
@@ -44,6 +55,59 @@ func BenchmarkTagExpr(b *testing.B) {
 	}
 }
 
+func BenchmarkTagExprArena(b *testing.B) {
+	b.StopTimer()
+	type T struct {
+		a int    `bench:"$%3"`
+		b string `bench:"sprintf('%s-%v',$,(a)$)=='b-10'"`
+	}
+	vm := New("bench")
+	err := vm.WarmUp(new(T))
+	if err != nil {
+		b.Fatal(err)
+	}
+	arena := NewArena()
+	b.ReportAllocs()
+	b.StartTimer()
+	var t = &T{10, "b"}
+	for i := 0; i < b.N; i++ {
+		tagExpr, err := vm.RunWithArena(t, arena)
+		if err != nil {
+			b.FailNow()
+		}
+		if tagExpr.EvalFloat("a@") != 1 || !tagExpr.EvalBool("b@") {
+			b.FailNow()
+		}
+		arena.Reset()
+	}
+}
+
+func BenchmarkSmallInt(b *testing.B) {
+	b.StopTimer()
+	type T struct {
+		a int8   `bench:"$>0"`
+		b uint16 `bench:"$>0"`
+		c int32  `bench:"$>0"`
+	}
+	vm := New("bench")
+	err := vm.WarmUp(new(T))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.StartTimer()
+	var t = &T{1, 2, 3}
+	for i := 0; i < b.N; i++ {
+		tagExpr, err := vm.Run(t)
+		if err != nil {
+			b.FailNow()
+		}
+		if !tagExpr.EvalBool("a@") || !tagExpr.EvalBool("b@") || !tagExpr.EvalBool("c@") {
+			b.FailNow()
+		}
+	}
+}
+
 func BenchmarkReflect(b *testing.B) {
 	b.StopTimer()
 	type T struct {
@@ -215,3 +279,2480 @@ func Test(t *testing.T) {
 		})
 	}
 }
+
+func TestNumericKinds(t *testing.T) {
+	type T struct {
+		A  int8    `tagexpr:"$"`
+		B  int16   `tagexpr:"$"`
+		C  int32   `tagexpr:"$"`
+		D  int64   `tagexpr:"$"`
+		E  int     `tagexpr:"$"`
+		F  uint8   `tagexpr:"$"`
+		G  uint16  `tagexpr:"$"`
+		H  uint32  `tagexpr:"$"`
+		I  uint64  `tagexpr:"$"`
+		J  uint    `tagexpr:"$"`
+		K  uintptr `tagexpr:"$"`
+		L  float32 `tagexpr:"$"`
+		M  float64 `tagexpr:"$"`
+		PA *int8   `tagexpr:"$"`
+	}
+	a := int8(-8)
+	v := &T{-1, -2, -3, -4, -5, 1, 2, 3, 4, 5, 6, 0.5, 7.5, &a}
+	vm := New("tagexpr")
+	te, err := vm.Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{
+		"A@": -1, "B@": -2, "C@": -3, "D@": -4, "E@": -5, "F@": 1, "G@": 2,
+		"H@": 3, "I@": 4, "J@": 5, "K@": 6, "L@": 0.5, "M@": 7.5, "PA@": -8,
+	}
+	for selector, f := range want {
+		if r := te.Eval(selector); r != f {
+			t.Fatalf("selector: %s, got: %v, want: %v", selector, r, f)
+		}
+	}
+}
+
+func TestPtrDeep(t *testing.T) {
+	type S struct {
+		A int `tagexpr:"$"`
+	}
+	type T struct {
+		A **int   `tagexpr:"$"`
+		B *string `tagexpr:"$"`
+		C *bool   `tagexpr:"$"`
+		D *[]int  `tagexpr:"len($)"`
+		E **S
+		F *S
+	}
+	vm := New("tagexpr")
+	te, err := vm.Run(new(T))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, selector := range []string{"A@", "B@", "C@", "E.A@", "F.A@"} {
+		if v := te.Eval(selector); v != nil {
+			t.Fatalf("selector: %s, got: %v, want: nil", selector, v)
+		}
+	}
+	a, b, c, d := 1, "b", true, []int{1, 2}
+	pa := &a
+	s := &S{A: 3}
+	te, err = vm.Run(&T{A: &pa, B: &b, C: &c, D: &d, E: &s, F: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"A@": 1.0, "B@": "b", "C@": true, "D@": 2.0, "E.A@": 3.0, "F.A@": 3.0}
+	for selector, v := range want {
+		if r := te.Eval(selector); r != v {
+			t.Fatalf("selector: %s, got: %v, want: %v", selector, r, v)
+		}
+	}
+}
+
+func TestArena(t *testing.T) {
+	type T struct {
+		A string `tagexpr:"sprintf('%s-%v',$,(B)$)"`
+		B int    `tagexpr:"{x:$>0&&iban('DE89370400440532013000')}"`
+	}
+	vm := New("tagexpr")
+	arena := NewArena()
+	for round := 0; round < 3; round++ {
+		var handlers []*TagExpr
+		for i := 0; i < 40; i++ {
+			te, err := vm.RunWithArena(&T{A: "a", B: i}, arena)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handlers = append(handlers, te)
+		}
+		for i, te := range handlers {
+			if r := te.EvalString("A@"); r != fmt.Sprintf("a-%d", i) {
+				t.Fatalf("got: %q, want: a-%d", r, i)
+			}
+			if r := te.EvalBool("B@x"); r != (i > 0) {
+				t.Fatalf("got: %v, want: %v", r, i > 0)
+			}
+		}
+		arena.Reset()
+	}
+	if _, err := vm.RunWithArena(nil, arena); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestArenaKeptArgs(t *testing.T) {
+	var kept [][]interface{}
+	if err := RegFunc("keepargs", func(args ...interface{}) interface{} {
+		kept = append(kept, args)
+		return true
+	}, true); err != nil {
+		t.Fatal(err)
+	}
+	type T struct {
+		A string `tagexpr:"keepargs($,sprintf('%s!',$))"`
+	}
+	vm := New("tagexpr")
+	arena := NewArena()
+	for _, a := range []string{"x", "y"} {
+		te, err := vm.RunWithArena(&T{A: a}, arena)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !te.EvalBool("A@") {
+			t.Fatal("want true")
+		}
+		arena.Reset()
+	}
+	if want := [][]interface{}{{"x", "x!"}, {"y", "y!"}}; !reflect.DeepEqual(kept, want) {
+		t.Fatalf("got %v, want %v", kept, want)
+	}
+}
+
+func TestRunValue(t *testing.T) {
+	type T struct {
+		A int    `tagexpr:"$+1"`
+		B string `tagexpr:"$+(C)$"`
+		C string
+	}
+	vm := New("tagexpr")
+	v := &T{A: 1, B: "b", C: "c"}
+	te, err := vm.RunValue(reflect.ValueOf(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := te.Eval("A@"); r != 2.0 {
+		t.Fatalf("got: %v, want: 2", r)
+	}
+	te, err = vm.RunValue(reflect.ValueOf(v).Elem())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := te.Eval("B@"); r != "bc" {
+		t.Fatalf("got: %v, want: bc", r)
+	}
+	for _, bad := range []reflect.Value{{}, reflect.ValueOf(*v), reflect.ValueOf(1), reflect.ValueOf((*T)(nil))} {
+		if _, err = vm.RunValue(bad); err == nil {
+			t.Fatalf("want error: %v", bad)
+		}
+	}
+	s, err := vm.Struct(reflect.TypeOf(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := s.EvalAt(unsafe.Pointer(v)).Eval("A@"); r != 2.0 {
+		t.Fatalf("got: %v, want: 2", r)
+	}
+	if _, err = vm.Struct(reflect.TypeOf(1)); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestFieldHandle(t *testing.T) {
+	type S struct {
+		a int8
+		B []string
+	}
+	type T struct {
+		A *string
+		S *S
+		V S
+	}
+	vm := New("tagexpr")
+	a := "a"
+	v := &T{A: &a, V: S{a: 1, B: []string{"x"}}}
+	te, err := vm.Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, ok := te.Field("A")
+	if !ok || !h.Reachable() || h.StructField().Name != "A" || h.Interface() != &a {
+		t.Fatalf("got: %v, %v", ok, h.Interface())
+	}
+	if h, ok = te.Field("V.a"); !ok || h.Value().Int() != 1 || h.StructField().Type.Kind() != reflect.Int8 {
+		t.Fatal("want V.a=1")
+	}
+	if h, ok = te.Field("V.B"); !ok || !reflect.DeepEqual(h.Interface(), []string{"x"}) {
+		t.Fatalf("got: %v", h.Interface())
+	}
+	if h, ok = te.Field("S.B"); !ok || h.Reachable() || h.Value().IsValid() || h.Interface() != nil {
+		t.Fatal("want unreachable S.B")
+	}
+	v.S = &S{a: 2}
+	if h, ok = te.Field("S.a"); !ok || !h.Reachable() || h.Interface() != int8(2) {
+		t.Fatalf("got: %v", h.Interface())
+	}
+	if _, ok = te.Field("X"); ok {
+		t.Fatal("want not found")
+	}
+}
+
+func TestEvalGlob(t *testing.T) {
+	type Addr struct {
+		City string `tagexpr:"{@:$!=''}{msg:'city required'}"`
+		Zip  string `tagexpr:"len($)==5"`
+	}
+	type User struct {
+		Name     string `tagexpr:"$!=''"`
+		Addr     Addr
+		AddrLine string `tagexpr:"{@:len($)<10}"`
+	}
+	vm := New("tagexpr")
+	te, err := vm.Run(&User{Name: "a", Addr: Addr{Zip: "12345"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = []struct {
+		pattern string
+		want    map[string]interface{}
+	}{
+		{pattern: "Addr.*@", want: map[string]interface{}{"Addr.City@": false, "Addr.Zip@": true}},
+		{pattern: "Addr*@", want: map[string]interface{}{"Addr.City@": false, "Addr.Zip@": true, "AddrLine@": true}},
+		{pattern: "*@msg", want: map[string]interface{}{"Addr.City@msg": "city required"}},
+		{pattern: "Name@", want: map[string]interface{}{"Name@": true}},
+		{pattern: "X*", want: map[string]interface{}{}},
+		{pattern: "[", want: nil},
+	}
+	for _, c := range cases {
+		if got := te.EvalGlob(c.pattern); !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("pattern: %q, got: %v, want: %v", c.pattern, got, c.want)
+		}
+	}
+}
+
+func TestSelectorFold(t *testing.T) {
+	type Base struct {
+		ID int `json:"id" tagexpr:"$>0"`
+	}
+	type Addr struct {
+		ZipCode string `json:"zip_code,omitempty" tagexpr:"{min:len($)>=5}"`
+	}
+	type User struct {
+		Base
+		FirstName string `json:"first_name" tagexpr:"$!=''"`
+		Addr      *Addr  `json:"addr"`
+		Secret    string `json:"-" tagexpr:"$==''"`
+	}
+	v := &User{Base: Base{ID: 1}, FirstName: "a", Addr: &Addr{ZipCode: "123"}, Secret: "s"}
+	te, err := New("tagexpr").Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := te.Eval("firstname@"); r != nil {
+		t.Fatalf("got: %v, want: nil", r)
+	}
+	te, err = New("tagexpr").SetSelectorFold(true).Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"FirstName@":        true,
+		"firstname@":        true,
+		"first_name@":       true,
+		"FIRST_NAME@":       true,
+		"addr.zip_code@min": false,
+		"ADDR.ZIPCODE@MIN":  false,
+		"id@":               true,
+		"base.id@":          true,
+		"secret@":           false,
+		"first_name@x":      nil,
+	}
+	for selector, r := range want {
+		if got := te.Eval(selector); got != r {
+			t.Fatalf("selector: %s, got: %v, want: %v", selector, got, r)
+		}
+	}
+	if _, ok := te.Expr("Addr.ZipCode@min"); !ok {
+		t.Fatal("want found")
+	}
+}
+
+func TestEvalByJSONPath(t *testing.T) {
+	type Name struct {
+		First string `json:"first_name" tagexpr:"{min:len($)>=2}"`
+	}
+	type User struct {
+		Name
+		Nick  string `tagexpr:"$!=''"`
+		Inner Name   `json:"inner"`
+	}
+	te, err := New("tagexpr").Run(&User{Name: Name{First: "a"}, Nick: "n", Inner: Name{First: "ab"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"first_name@min":       false,
+		"inner.first_name@min": true,
+		"Nick@":                true,
+		"Name.First@min":       nil,
+		"Inner.first_name@min": nil,
+		"INNER.first_name@min": nil,
+	}
+	for selector, r := range want {
+		if got := te.EvalByJSONPath(selector); got != r {
+			t.Fatalf("selector: %s, got: %v, want: %v", selector, got, r)
+		}
+	}
+}
+
+func TestParseJSONTag(t *testing.T) {
+	type Inner struct{ A int }
+	type inner struct{ B int }
+	type T struct {
+		Inner
+		*inner
+		Named  Inner `json:"named"`
+		Opt    int   `json:",omitempty,string"`
+		Dash   int   `json:"-,"`
+		Skip   int   `json:"-"`
+		Inline Inner `json:",inline"`
+		hidden int
+		Scalar int `json:"scalar,inline"`
+	}
+	want := []JSONTag{
+		{Inline: true},
+		{Ignored: true},
+		{Name: "named"},
+		{Name: "Opt", OmitEmpty: true, String: true},
+		{Name: "-"},
+		{Ignored: true},
+		{Inline: true},
+		{Ignored: true},
+		{Name: "scalar"},
+	}
+	typ := reflect.TypeOf(T{})
+	for i, w := range want {
+		if got := ParseJSONTag(typ.Field(i)); got != w {
+			t.Fatalf("%s: got %+v, want %+v", typ.Field(i).Name, got, w)
+		}
+	}
+	type U struct {
+		T
+		Hidden string `json:"-" tagexpr:"$!=''"`
+		Public struct {
+			private string `tagexpr:"$!=''"`
+		} `json:"public"`
+	}
+	s, err := New("tagexpr").Struct(reflect.TypeOf(U{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for field, want := range map[string]string{"T.Inline.A": "A", "T.Named.A": "named.A", "T.Dash": "-"} {
+		if got, ok := s.FieldPath(field, "json"); !ok || got != want {
+			t.Fatalf("FieldPath(%s): got %q, want %q", field, got, want)
+		}
+	}
+	for _, field := range []string{"Hidden", "Public.private", "T.Skip"} {
+		if got, ok := s.FieldPath(field, "json"); ok {
+			t.Fatalf("FieldPath(%s): got %q, want ignored", field, got)
+		}
+	}
+	te := s.EvalAt(unsafe.Pointer(&U{}))
+	if got := te.EvalByJSONPath("Hidden@"); got != nil {
+		t.Fatalf("got %v, want nil", got)
+	}
+}
+
+func TestEvalByMapPath(t *testing.T) {
+	type Server struct {
+		Port int `mapstructure:"port" tagexpr:"$>0"`
+	}
+	type Base struct {
+		Name string `mapstructure:"name" tagexpr:"$!=''"`
+	}
+	type Config struct {
+		Base    `mapstructure:",squash"`
+		Server  Server `mapstructure:"server"`
+		Workers int    `tagexpr:"$>0"`
+		Host    string `json:"endpoint" tagexpr:"$!=''"`
+		Target  string `mapstructure:"ENDPOINT" tagexpr:"$!=''"`
+	}
+	te, err := New("tagexpr").Run(&Config{Base: Base{Name: "n"}, Server: Server{Port: 0}, Workers: 1, Target: "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"server.port@":    false,
+		"SERVER.Port@":    false,
+		"name@":           true,
+		"Workers@":        true,
+		"workers@":        true,
+		"port@":           nil,
+		"server.missing@": nil,
+		"endpoint@":       true,
+		"host@":           false,
+	}
+	for selector, r := range want {
+		if got := te.EvalByMapPath(selector); got != r {
+			t.Fatalf("selector: %s, got: %v, want: %v", selector, got, r)
+		}
+	}
+	s, err := New("tagexpr").Struct(reflect.TypeOf(Config{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range [][3]string{
+		{"Server.Port", "mapstructure", "server.port"},
+		{"Base.Name", "mapstructure", "name"},
+		{"Base.Name", "json", "Name"},
+		{"Server.Port", "", "Server.Port"},
+	} {
+		if got, ok := s.FieldPath(c[0], c[1]); !ok || got != c[2] {
+			t.Fatalf("FieldPath(%s, %s): got %q, want %q", c[0], c[1], got, c[2])
+		}
+	}
+	if _, ok := s.FieldPath("X", "json"); ok {
+		t.Fatal("want not found")
+	}
+}
+
+func TestTranslateValidateTag(t *testing.T) {
+	var cases = []struct {
+		tag     string
+		kind    reflect.Kind
+		nilable bool
+		expr    string
+	}{
+		{tag: "required,min=3,max=32", kind: reflect.String, expr: "!($=='')&&len($)>=3&&len($)<=32"},
+		{tag: "required", kind: reflect.Slice, expr: "!(len($)==0)"},
+		{tag: "required", kind: reflect.Struct, expr: "true"},
+		{tag: "required", kind: reflect.Struct, nilable: true, expr: "!($==nil)"},
+		{tag: "omitempty,gt=0", kind: reflect.Float64, expr: "$==0||$>0"},
+		{tag: "omitempty,len=2,alpha", kind: reflect.String, expr: "$==''||(len($)==2&&regexp('^[a-zA-Z]+$'))"},
+		{tag: "eq=a,ne=b", kind: reflect.String, expr: "$=='a'&&$!='b'"},
+		{tag: "oneof=1 2", kind: reflect.Int, expr: "$==1||$==2"},
+		{tag: "oneof=a b,e164", kind: reflect.String, expr: "($=='a'||$=='b')&&phone($)"},
+		{tag: "startswith=a.b(", kind: reflect.String, expr: `regexp('^a\x2eb\x28')`},
+		{tag: "-", kind: reflect.String, expr: "-"},
+		{tag: "", kind: reflect.String, expr: ""},
+		{tag: "omitempty,min=1", kind: reflect.Slice, nilable: true, expr: "$==nil||len($)>=1"},
+		{tag: "required", kind: reflect.Int, nilable: true, expr: "!($==nil)"},
+	}
+	for _, c := range cases {
+		expr, err := TranslateValidateTag(c.tag, c.kind, c.nilable)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expr != c.expr {
+			t.Fatalf("tag: %q, got: %q, want: %q", c.tag, expr, c.expr)
+		}
+		if expr == "" || expr == skipTag {
+			continue
+		}
+		if _, err = parseExpr(expr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tag := range []string{"dive", "min", "min=a", "eq=it's", "contains=a", "eqfield=B"} {
+		if _, err := TranslateValidateTag(tag, reflect.Int, false); err == nil {
+			t.Fatalf("tag: %q, want error", tag)
+		}
+	}
+
+	type T struct {
+		Name  string `tagexpr:"!($=='')&&len($)>=3&&len($)<=32"`
+		Email string `tagexpr:"regexp('^[^@\\s]+@[^@\\s]+\\.[^@\\s]+$')"`
+	}
+	email, _ := TranslateValidateTag("email", reflect.String, false)
+	if want := reflect.TypeOf(T{}).Field(1).Tag.Get("tagexpr"); email != want {
+		t.Fatalf("got: %q, want: %q", email, want)
+	}
+	te, err := New("tagexpr").Run(&T{Name: "Tom", Email: "a@b.c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !te.EvalBool("Name@") || !te.EvalBool("Email@") {
+		t.Fatal("want valid")
+	}
+}
+
+func TestCompatTag(t *testing.T) {
+	type T struct {
+		A string   `validate:"required,max=3"`
+		B *int     `validate:"omitempty,gte=18"`
+		C []string `validate:"min=1" tagexpr:"len($)==2"`
+		D string
+		E time.Time  `validate:"required"`
+		F *time.Time `validate:"required"`
+	}
+	b := 17
+	v := &T{A: "abcd", B: &b, C: []string{"x", "y"}}
+	te, err := New("tagexpr").SetCompatTag("validate").Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"A@": false, "B@": false, "C@": true, "D@": nil, "E@": true, "F@": false}
+	for selector, r := range want {
+		if got := te.Eval(selector); got != r {
+			t.Fatalf("selector: %s, got: %v, want: %v", selector, got, r)
+		}
+	}
+	v.A, v.B = "abc", nil
+	if !te.EvalBool("A@") || !te.EvalBool("B@") {
+		t.Fatal("want valid")
+	}
+	type Sub struct {
+		X string `validate:"required"`
+	}
+	type U struct {
+		A string `validate:"required,dive,eqfield=B,min=1|max=2"`
+		B string
+		S Sub  `validate:"-"`
+		P *Sub `validate:" - "`
+	}
+	te, err = New("tagexpr").SetCompatTag("validate").Run(new(U))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := te.Expr("A@"); !ok || e.String() != "!($ == '')" {
+		t.Fatalf("A@: got %v, want the supported rules only", e)
+	}
+	if _, ok := te.Expr("S.X@"); ok {
+		t.Fatal("S: want skipped")
+	}
+	if _, ok := te.Expr("P.X@"); ok {
+		t.Fatal("P: want skipped")
+	}
+	var reasons []string
+	_, err = New("tagexpr").SetCompatTag("validate").SetUnsupportedPolicy(UnsupportedWarn, func(structName string, f IgnoredField) {
+		reasons = append(reasons, f.Field+": "+f.Reason)
+	}).Run(new(U))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{
+		"A: validate rule dive is left out: unsupported keyword: dive",
+		"A: validate rule eqfield=B is left out: unsupported keyword: eqfield",
+		"A: validate rule min=1|max=2 is left out: invalid number parameter of min: \"1|max=2\"",
+	}; !reflect.DeepEqual(reasons, want) {
+		t.Fatalf("got %q, want %q", reasons, want)
+	}
+	if _, err = New("tagexpr").SetCompatTag("validate").SetUnsupportedPolicy(UnsupportedError, nil).Run(new(U)); err == nil {
+		t.Fatal("want unsupported keyword error")
+	}
+	te, err = New("tagexpr").Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := te.Expr("A@"); ok {
+		t.Fatal("want no compat expression by default")
+	}
+}
+
+func TestExprInspect(t *testing.T) {
+	type T struct {
+		A int `tagexpr:"$<=10*60"`
+		B int `tagexpr:"{x:$+(1+2)}"`
+	}
+	vm := New("tagexpr")
+	te, err := vm.Run(&T{A: 100, B: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for selector, want := range map[string]string{"A@": "$ <= 600", "B@x": "$ + 3"} {
+		expr, ok := te.Expr(selector)
+		if !ok {
+			t.Fatalf("not found: %s", selector)
+		}
+		if got := expr.String(); got != want {
+			t.Fatalf("selector: %s, got: %q, want: %q", selector, got, want)
+		}
+	}
+	if _, ok := te.Expr("C@"); ok {
+		t.Fatal("want not found")
+	}
+	if r := te.Eval("B@x"); r != 4.0 {
+		t.Fatalf("got: %v, want: 4", r)
+	}
+	type U struct {
+		C *T
+		D string
+	}
+	s, err := vm.Struct(reflect.TypeOf(U{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := s.FieldNames(); !reflect.DeepEqual(names, []string{"C", "C.A", "C.B", "D"}) {
+		t.Fatalf("got: %v", names)
+	}
+}
+
+func TestMeta(t *testing.T) {
+	type T struct {
+		A string `tagexpr:"{@:$!=''}{meta:'pii, severity=high'}"`
+		B string `tagexpr:"{meta:(A)$}"`
+		C int    `tagexpr:"$>0"`
+	}
+	type U struct {
+		T *T
+		D string `tagexpr:"{meta:'internal'}"`
+	}
+	s, err := New("tagexpr").Struct(reflect.TypeOf(U{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Meta("T.A"); !reflect.DeepEqual(got, Meta{"pii": "", "severity": "high"}) || !got.Has("pii") {
+		t.Fatalf("Meta: got %v", got)
+	}
+	for _, field := range []string{"T.B", "T.C", "X"} {
+		if got := s.Meta(field); got != nil {
+			t.Fatalf("Meta(%s): got %v, want nil", field, got)
+		}
+	}
+	var fields []string
+	s.RangeMeta(func(field string, meta Meta) bool {
+		fields = append(fields, field)
+		return true
+	})
+	if !reflect.DeepEqual(fields, []string{"T.A", "D"}) {
+		t.Fatalf("RangeMeta: got %v", fields)
+	}
+	if got := ParseMeta(" a=1,,b "); !reflect.DeepEqual(got, Meta{"a": "1", "b": ""}) {
+		t.Fatalf("ParseMeta: got %v", got)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	type T struct {
+		A string   `tagexpr:"{san:lower(trim($))}"`
+		B *int     `tagexpr:"{san:clamp($,0,100)}"`
+		C *float32 `tagexpr:"{san:clamp($,0,1)}"`
+		D string   `tagexpr:"{san:striphtml($)}"`
+		F uint8    `tagexpr:"{san:$-10}"`
+		G bool     `tagexpr:"{san:(A)$!=''}"`
+	}
+	vm := New("tagexpr")
+	b := 120
+	v := &T{A: "  Foo@Example.COM ", B: &b, D: "<b>hi</b><script>alert(1)</script>&lt;", F: 20}
+	te, err := vm.Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = te.Sanitize(); err != nil {
+		t.Fatal(err)
+	}
+	want := T{A: "foo@example.com", B: v.B, D: "hi&lt;", F: 10, G: true}
+	if b != 100 || !reflect.DeepEqual(*v, want) {
+		t.Fatalf("got %+v, B=%d", *v, b)
+	}
+	v.F = 5
+	if err = te.Sanitize(); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("got %v, want ErrTypeMismatch", err)
+	}
+	type U struct {
+		E string `tagexpr:"{san:len($)}"`
+	}
+	te, err = vm.Run(&U{E: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = te.Sanitize(); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("got %v, want ErrTypeMismatch", err)
+	}
+	type I8 struct {
+		V int8 `tagexpr:"{san:$+200}"`
+	}
+	type I struct {
+		V int `tagexpr:"{san:$+0.7}"`
+	}
+	type Uint struct {
+		V uint `tagexpr:"{san:$-1}"`
+	}
+	type U8 struct {
+		V uint8 `tagexpr:"{san:$*0.5}"`
+	}
+	type F32 struct {
+		V   float32 `tagexpr:"{san:(F64)$}"`
+		F64 float64
+	}
+	for _, c := range []struct {
+		v, want interface{}
+		ok      bool
+	}{
+		{v: &I8{-100}, want: &I8{100}, ok: true},
+		{v: &I8{100}, want: &I8{100}},
+		{v: &I{3}, want: &I{3}},
+		{v: &Uint{0}, want: &Uint{0}},
+		{v: &U8{4}, want: &U8{2}, ok: true},
+		{v: &U8{3}, want: &U8{3}},
+		{v: &F32{F64: 0.5}, want: &F32{0.5, 0.5}, ok: true},
+		{v: &F32{1, math.MaxFloat64}, want: &F32{1, math.MaxFloat64}},
+	} {
+		te, err = vm.Run(c.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = te.Sanitize()
+		if c.ok && err != nil || !c.ok && !errors.Is(err, ErrTypeMismatch) {
+			t.Fatalf("%+v: got %v", c.want, err)
+		}
+		if !reflect.DeepEqual(c.v, c.want) {
+			t.Fatalf("got %+v, want %+v", c.v, c.want)
+		}
+	}
+}
+
+func TestCalc(t *testing.T) {
+	type T struct {
+		Greeting string `tagexpr:"{calc:'Hello, '+(FullName)$}"`
+		FullName string `tagexpr:"{calc:(First)$+' '+(Last)$}"`
+		First    string
+		Last     string
+		Total    int `tagexpr:"{calc:(Price)$*(Count)$}"`
+		Price    float64
+		Count    int
+	}
+	vm := New("tagexpr")
+	v := &T{First: "Ada", Last: "Lovelace", Price: 2.5, Count: 4}
+	te, err := vm.Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = te.Calc(); err != nil {
+		t.Fatal(err)
+	}
+	if v.FullName != "Ada Lovelace" || v.Greeting != "Hello, Ada Lovelace" || v.Total != 10 {
+		t.Fatalf("got %+v", *v)
+	}
+	type Bytes struct {
+		Text string `tagexpr:"{calc:(Raw)$}"`
+		Raw  []byte
+	}
+	b := &Bytes{Raw: []byte("Hello")}
+	te, _ = vm.Run(b)
+	if err = te.Calc(); err != nil {
+		t.Fatal(err)
+	}
+	b.Raw[0] = 'J'
+	if b.Text != "Hello" {
+		t.Fatalf("want the assigned string not sharing the bytes, got %q", b.Text)
+	}
+	type Cycle struct {
+		A string `tagexpr:"{calc:(B)$}"`
+		B string `tagexpr:"{calc:(C)$}"`
+		C string `tagexpr:"{calc:(A)$+'x'}"`
+	}
+	_, err = vm.Run(&Cycle{})
+	if !errors.Is(err, ErrSyntax) || !strings.Contains(err.Error(), "A -> B -> C -> A") {
+		t.Fatalf("got %v", err)
+	}
+	type Round struct {
+		Avg   int8 `tagexpr:"{calc:(Sum)$/(Count)$}"`
+		Sum   int
+		Count int
+	}
+	for _, c := range []struct {
+		v    Round
+		want int8
+		ok   bool
+	}{
+		{v: Round{Sum: 9, Count: 3}, want: 3, ok: true},
+		{v: Round{Sum: 10, Count: 3}},
+		{v: Round{Sum: 300, Count: 1}},
+	} {
+		te, err = vm.Run(&c.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = te.Calc()
+		if c.ok && err != nil || !c.ok && !errors.Is(err, ErrTypeMismatch) || c.v.Avg != c.want {
+			t.Fatalf("%d/%d: got %d, %v", c.v.Sum, c.v.Count, c.v.Avg, err)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	type Card struct {
+		Number string `tagexpr:"{redact:'[card]'}"`
+		CVV    int    `tagexpr:"{redact:true}"`
+	}
+	type T struct {
+		Name     string
+		Password string  `tagexpr:"{redact:true}"`
+		Token    *string `tagexpr:"{redact:(Name)$!='admin'}"`
+		Card     *Card
+		Tags     []string `tagexpr:"{redact:len($)>1}"`
+		secret   string   `tagexpr:"{redact:true}"`
+	}
+	token := "t0k3n"
+	v := &T{Name: "bob", Password: "pw", Token: &token, Card: &Card{Number: "4111", CVV: 123}, Tags: []string{"a", "b"}, secret: "s"}
+	vm := New("tagexpr")
+	r, err := vm.Redact(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := r.(*T)
+	if got.Name != "bob" || got.Password != DefaultRedactMask || got.Token != nil || got.Tags != nil || got.secret != DefaultRedactMask {
+		t.Fatalf("got %+v", *got)
+	}
+	if *got.Card != (Card{Number: "[card]"}) {
+		t.Fatalf("got %+v", *got.Card)
+	}
+	if v.Password != "pw" || v.Token != &token || token != "t0k3n" || *v.Card != (Card{Number: "4111", CVV: 123}) || v.secret != "s" {
+		t.Fatalf("original changed: %+v, %+v", *v, *v.Card)
+	}
+	v.Name, v.Card = "admin", nil
+	if r, err = vm.Redact(v); err != nil {
+		t.Fatal(err)
+	}
+	if got = r.(*T); got.Token != &token || got.Card != nil {
+		t.Fatalf("got %+v", *got)
+	}
+	if _, err = vm.Redact(T{}); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestElemField(t *testing.T) {
+	type Cell struct {
+		Name   string
+		Values []int
+		secret string
+	}
+	type Base struct{ ID int }
+	type Row struct {
+		*Base
+		Cell
+	}
+	type T struct {
+		Header []Cell          `tagexpr:"{name:(Header)$[0].Name}{value:$[1].Values[1]}{key:$[0]['Name']}"`
+		Rows   []*Row          `tagexpr:"{id:$[0].ID}{name:$[0].Name}{nil:$[1].ID}"`
+		ByKey  map[string]Cell `tagexpr:"$['a'].Name=='x'"`
+		Bad    []Cell          `tagexpr:"{secret:$[0].secret}{missing:$[0].Missing}{neg:$[-1].Name}{index:$[0][0]}"`
+	}
+	v := &T{
+		Header: []Cell{{Name: "id"}, {Values: []int{1, 2}}},
+		Rows:   []*Row{{Base: &Base{ID: 7}, Cell: Cell{Name: "r"}}, {}},
+		ByKey:  map[string]Cell{"a": {Name: "x"}},
+		Bad:    []Cell{{secret: "s"}},
+	}
+	te, err := New("tagexpr").Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"Header@name":  "id",
+		"Header@value": 2.0,
+		"Header@key":   "id",
+		"Rows@id":      7.0,
+		"Rows@name":    "r",
+		"Rows@nil":     nil,
+		"ByKey@":       true,
+		"Bad@secret":   nil,
+		"Bad@missing":  nil,
+		"Bad@neg":      nil,
+		"Bad@index":    nil,
+	}
+	for selector, r := range want {
+		got, err := te.EvalWithError(selector)
+		if err != nil || got != r {
+			t.Fatalf("%s: got %v, %v, want %v", selector, got, err, r)
+		}
+	}
+	if _, err = New("tagexpr").ParseExpr("$.Name"); err == nil {
+		t.Fatal("want syntax error")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	type Addr struct {
+		City string `tagexpr:"$!=''"`
+	}
+	type T struct {
+		Name  string   `tagexpr:"len($)>0"`
+		Age   *int     `tagexpr:"$==nil || $>=18"`
+		Tags  []string `tagexpr:"len($)<=3"`
+		Attrs map[string][]int
+		Addr  *Addr
+		note  string
+	}
+	age := 20
+	v := &T{Name: "a", Age: &age, Tags: []string{"x"}, Attrs: map[string][]int{"k": {1}}, Addr: &Addr{City: "bj"}}
+	vm := New("tagexpr")
+	te, err := vm.Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := te.Snapshot()
+	if old["Age"] != 20.0 || old["Addr.City"] != "bj" || !reflect.DeepEqual(old["Attrs"], map[interface{}]interface{}{"k": []interface{}{1.0}}) {
+		t.Fatalf("unexpected snapshot: %v", old)
+	}
+	if _, ok := old["Addr"]; ok {
+		t.Fatal("want the struct field not captured as a whole")
+	}
+	if diff := old.Diff(te.Snapshot()); len(diff) != 0 {
+		t.Fatalf("want no change, got %v", diff)
+	}
+	age = 16
+	v.Tags[0] = "y"
+	v.Attrs["k"][0] = 2
+	v.Addr = nil
+	v.note = "n"
+	diff := old.Diff(te.Snapshot())
+	if want := []string{"Addr.City", "Age", "Attrs", "Tags", "note"}; !reflect.DeepEqual(diff, want) {
+		t.Fatalf("got %v, want %v", diff, want)
+	}
+	if r := te.EvalGlob("Age@*"); r["Age@"] != false {
+		t.Fatalf("want the changed field failing its rule, got %v", r)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	type Item struct {
+		SKU string
+		Qty int
+	}
+	type Order struct {
+		UserID    int64   `cmp:"{cmp:true}"`
+		Items     []*Item `cmp:"{cmp:true}"`
+		Coupon    string  `cmp:"{cmp:$!=''}"`
+		RequestID string
+		CreatedAt int64 `cmp:"$>0"`
+	}
+	a := &Order{UserID: 1, Items: []*Item{{"x", 2}}, RequestID: "r1", CreatedAt: 1}
+	b := &Order{UserID: 1, Items: []*Item{{"x", 2}}, RequestID: "r2", CreatedAt: 2}
+	if ok, err := Equal(a, b, "cmp"); err != nil || !ok {
+		t.Fatalf("want equal, got %v, %v", ok, err)
+	}
+	b.Coupon = "c"
+	if ok, _ := Equal(a, b, "cmp"); ok {
+		t.Fatal("want the coupon compared if either is set")
+	}
+	b.Coupon, b.Items[0].Qty = "", 3
+	if ok, _ := Equal(a, b, "cmp"); ok {
+		t.Fatal("want the items compared deeply")
+	}
+	if _, err := Equal(a, Item{}, "cmp"); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("want ErrTypeMismatch, got %v", err)
+	}
+
+	type Event struct {
+		ID   string `te:"$!=''"`
+		At   int64  `te:"$>0"`
+		Note string
+	}
+	vm := New("te")
+	if ok, _ := vm.Equal(&Event{"a", 1, "x"}, &Event{"a", 1, "y"}); !ok {
+		t.Fatal("want the fields with tag expressions compared if there is no cmp expression")
+	}
+	if ok, _ := vm.Equal(&Event{"a", 1, "x"}, &Event{"a", 2, "x"}); ok {
+		t.Fatal("want not equal")
+	}
+}
+
+func TestMixedNesting(t *testing.T) {
+	type T struct {
+		Data   map[string]interface{}       `tagexpr:"{age:$['users'][0]['age']}{tag:$['users'][1]['tags'][1]}{missing:$['users'][2]['age']}{scalar:$['users'][0]['age'][0]}"`
+		Matrix []map[string][]int           `tagexpr:"{v:$[0]['a'][1]}{oob:$[0]['a'][2]}"`
+		Groups map[int][]*map[string]string `tagexpr:"$[1][0]['k']"`
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"users":[{"age":18},{"tags":["a","b"]}]}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	m := map[string]string{"k": "v"}
+	te, err := New("tagexpr").Run(&T{
+		Data:   data,
+		Matrix: []map[string][]int{{"a": {1, 2}}},
+		Groups: map[int][]*map[string]string{1: {&m}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"Data@age":     18.0,
+		"Data@tag":     "b",
+		"Data@missing": nil,
+		"Data@scalar":  nil,
+		"Matrix@v":     2.0,
+		"Matrix@oob":   nil,
+		"Groups@":      "v",
+	}
+	for selector, r := range want {
+		if got := te.Eval(selector); got != r {
+			t.Fatalf("%s: got %v, want %v", selector, got, r)
+		}
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	type P struct {
+		X int `tagexpr:"$"`
+	}
+	type T struct {
+		M  map[string]*int `tagexpr:"{k:$['k']}{nil:$['nil']}{missing:$['x']}{eq:$['x']==nil}"`
+		S  []int           `tagexpr:"{in:$[1]}{out:$[2]}"`
+		P  *P              `tagexpr:"$"`
+		PS *[]int          `tagexpr:"$[0]"`
+		I  interface{}     `tagexpr:"$[0]"`
+	}
+	k := 1
+	v := &T{M: map[string]*int{"k": &k, "nil": nil}, S: []int{1, 2}}
+	cases := []struct {
+		selector string
+		off, on  interface{}
+	}{
+		{"M@k", 1.0, 1.0},
+		{"M@nil", nil, nil},
+		{"M@missing", nil, Unreachable{}},
+		{"M@eq", true, false},
+		{"S@in", 2.0, 2.0},
+		{"S@out", nil, Unreachable{}},
+		{"P@", nil, nil},
+		{"P.X@", nil, Unreachable{}},
+		{"PS@", nil, Unreachable{}},
+		{"I@", nil, nil},
+	}
+	for _, enable := range []bool{false, true} {
+		te, err := New("tagexpr").SetUnreachable(enable).Run(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range cases {
+			want := c.off
+			if enable {
+				want = c.on
+			}
+			if got := te.Eval(c.selector); got != want {
+				t.Fatalf("unreachable=%v %s: got %#v, want %#v", enable, c.selector, got, want)
+			}
+		}
+	}
+	if !IsUnreachable(Unreachable{}) || IsUnreachable(nil) {
+		t.Fatal("IsUnreachable")
+	}
+}
+
+func TestExists(t *testing.T) {
+	type Addr struct {
+		City *string
+	}
+	type Patch struct {
+		Name  *string         `tagexpr:"{set:exists($)}{unset:missing()}"`
+		Age   *int            `tagexpr:"{set:exists($)}{nil:!exists($) && $==nil}"`
+		Addr  *Addr           `tagexpr:"{city:exists((Addr.City)$)}{path:exists('Addr.City')}"`
+		Attrs map[string]*int `tagexpr:"{k:exists($['k'])}{nil:exists($['nil']) && $['nil']==nil}{x:missing($['x'])}"`
+		Tags  []string        `tagexpr:"{in:exists($[1])}{out:!exists($[2])}{neg:!exists($[-1])}"`
+		Zero  int             `tagexpr:"exists($) && $==0"`
+		Value interface{}     `tagexpr:"{v:exists($)}{calc:exists(len((Tags)$))}"`
+	}
+	name, k := "n", 0
+	v := &Patch{Name: &name, Attrs: map[string]*int{"k": &k, "nil": nil}, Tags: []string{"a", "b"}}
+	want := map[string]interface{}{
+		"Name@set":   true,
+		"Name@unset": false,
+		"Age@set":    false,
+		"Age@nil":    true,
+		"Addr@city":  false,
+		"Addr@path":  false,
+		"Attrs@k":    true,
+		"Attrs@nil":  true,
+		"Attrs@x":    true,
+		"Tags@in":    true,
+		"Tags@out":   true,
+		"Tags@neg":   true,
+		"Zero@":      true,
+		"Value@v":    false,
+		"Value@calc": true,
+	}
+	for _, enable := range []bool{false, true} {
+		te, err := New("tagexpr").SetUnreachable(enable).Run(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for selector, r := range want {
+			if got := te.Eval(selector); got != r {
+				t.Fatalf("unreachable=%v %s: got %v, want %v", enable, selector, got, r)
+			}
+		}
+	}
+	city := "c"
+	v.Addr = &Addr{City: &city}
+	te, err := New("tagexpr").Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if te.Eval("Addr@city") != true || te.Eval("Addr@path") != true {
+		t.Fatal("want the city exists")
+	}
+	expr, err := New("tagexpr").ParseExpr("!missing((A)$['k'])")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := expr.String(); s != "!missing((A)$['k'])" {
+		t.Fatalf("String: %s", s)
+	}
+}
+
+func TestBytesField(t *testing.T) {
+	type Raw []byte
+	type T struct {
+		Token  []byte   `tagexpr:"{prefix:regexp('^Bearer ')}{len:len($)}{eq:$=='Bearer abc'}{idx:$[0]}"`
+		Body   *Raw     `tagexpr:"{json:isjson($)}{nil:$==nil}"`
+		Chunks [][]byte `tagexpr:"$[1]=='b'"`
+		Empty  []byte   `tagexpr:"$==''"`
+		Trim   []byte   `tagexpr:"{san:trim($)}"`
+	}
+	body := Raw(`{"a":1}`)
+	v := &T{Token: []byte("Bearer abc"), Body: &body, Chunks: [][]byte{[]byte("a"), []byte("b")}, Trim: []byte(" x ")}
+	te, err := New("tagexpr").Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"Token@prefix": true,
+		"Token@len":    10.0,
+		"Token@eq":     true,
+		"Token@idx":    66.0,
+		"Body@json":    true,
+		"Body@nil":     false,
+		"Chunks@":      true,
+		"Empty@":       true,
+	}
+	for selector, r := range want {
+		if got := te.Eval(selector); got != r {
+			t.Fatalf("%s: got %v, want %v", selector, got, r)
+		}
+	}
+	if err = te.Sanitize(); err != nil {
+		t.Fatal(err)
+	}
+	if string(v.Trim) != "x" {
+		t.Fatalf("Trim: %q", v.Trim)
+	}
+	v.Body = nil
+	if te.Eval("Body@nil") != true {
+		t.Fatal("want nil")
+	}
+}
+
+func TestRawMessage(t *testing.T) {
+	type T struct {
+		Payload json.RawMessage            `tagexpr:"{valid:isjson($)}{len:len($)}{age:jsonpath($,'$.user.age')}"`
+		Omitted json.RawMessage            `tagexpr:"{nil:$==nil}{exists:exists($)}"`
+		Ptr     *json.RawMessage           `tagexpr:"jsonpath($,'$[1]')"`
+		Items   map[string]json.RawMessage `tagexpr:"{a:isjson($['a'])}{nil:$['nil']==nil}"`
+	}
+	ptr := json.RawMessage(`[1,2]`)
+	v := &T{
+		Payload: json.RawMessage(`{"user":{"age":18}}`),
+		Ptr:     &ptr,
+		Items:   map[string]json.RawMessage{"a": json.RawMessage(`"x"`), "nil": nil},
+	}
+	te, err := New("tagexpr").Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"Payload@valid":  true,
+		"Payload@len":    19.0,
+		"Payload@age":    18.0,
+		"Omitted@nil":    true,
+		"Omitted@exists": true,
+		"Ptr@":           2.0,
+		"Items@a":        true,
+		"Items@nil":      true,
+	}
+	for selector, r := range want {
+		if got := te.Eval(selector); got != r {
+			t.Fatalf("%s: got %v, want %v", selector, got, r)
+		}
+	}
+}
+
+type testCents struct{ n int64 }
+
+func TestNumberAdapter(t *testing.T) {
+	err := RegNumberAdapter(reflect.TypeOf(testCents{}), func(ptr interface{}) (*big.Rat, bool) {
+		return big.NewRat(ptr.(*testCents).n, 100), true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if RegNumberAdapter(reflect.TypeOf(testCents{}), nil) == nil {
+		t.Fatal("want duplicate error")
+	}
+	if RegNumberAdapter(reflect.TypeOf(&testCents{}), nil) == nil {
+		t.Fatal("want invalid type error")
+	}
+	huge, _ := new(big.Int).SetString("9007199254740993", 10)
+	type T struct {
+		Int    big.Int             `tagexpr:"{eq:$==9007199254740993}{ne:$!=9007199254740992}{lt:9007199254740992<$}"`
+		Float  *big.Float          `tagexpr:"{gt:$>0.1}{le:$<=0.3}"`
+		Rat    *big.Rat            `tagexpr:"$==nil"`
+		Price  testCents           `tagexpr:"{eq:$==19.99}{ge:$>=19.99}{lt:$<20}{cmp:$==(Amount)$}"`
+		Amount big.Rat             `tagexpr:"$>=(Price)$"`
+		Prices map[string]*big.Int `tagexpr:"$['a']>1"`
+	}
+	v := &T{
+		Int:    *huge,
+		Float:  big.NewFloat(0.3),
+		Price:  testCents{1999},
+		Prices: map[string]*big.Int{"a": big.NewInt(2)},
+	}
+	v.Amount.SetString("19.99")
+	te, err := New("tagexpr").Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, selector := range []string{"Int@eq", "Int@ne", "Int@lt", "Float@gt", "Float@le", "Price@eq", "Price@ge", "Price@lt", "Price@cmp", "Amount@", "Prices@", "Rat@"} {
+		if got := te.Eval(selector); got != true {
+			t.Fatalf("%s: got %v, want true", selector, got)
+		}
+	}
+	v.Price.n = 2000
+	if got := te.Eval("Price@lt"); got != false {
+		t.Fatalf("Price@lt: got %v, want false", got)
+	}
+}
+
+func TestSafeNavigation(t *testing.T) {
+	type C struct{ V int }
+	type B struct{ C *C }
+	type A struct {
+		B    *B
+		List []*B
+	}
+	type T struct {
+		A    *A `tagexpr:"{safe:$?.B?.C?.V}{strict:$?.B.C.V}{index:$?.List?[0].C}{elem:$?.List[0]?.C}"`
+		Path A  `tagexpr:"{field:(A.B.C.V)$}{map:(M)$?['x']?.V}"`
+		M    map[string]*C
+	}
+	cases := []struct {
+		v      *T
+		want   map[string]interface{}
+		strict map[string]interface{}
+	}{
+		{
+			v:      &T{},
+			want:   map[string]interface{}{"A@safe": nil, "A@strict": nil, "A@index": nil, "A@elem": nil, "Path@field": nil, "Path@map": nil},
+			strict: map[string]interface{}{"A@safe": nil, "A@strict": nil, "A@index": nil, "A@elem": nil, "Path@field": Unreachable{}, "Path@map": nil},
+		},
+		{
+			v:      &T{A: &A{B: &B{}, List: []*B{{}}}},
+			want:   map[string]interface{}{"A@safe": nil, "A@strict": nil, "A@elem": nil},
+			strict: map[string]interface{}{"A@safe": nil, "A@strict": Unreachable{}, "A@elem": nil},
+		},
+		{
+			v:      &T{A: &A{B: &B{C: &C{V: 1}}}, M: map[string]*C{"x": {V: 2}}},
+			want:   map[string]interface{}{"A@safe": 1.0, "A@strict": 1.0, "A@index": nil, "Path@field": 1.0, "Path@map": 2.0},
+			strict: map[string]interface{}{"A@safe": 1.0, "A@strict": 1.0, "A@index": nil, "Path@field": 1.0, "Path@map": 2.0},
+		},
+	}
+	for i, c := range cases {
+		for _, strict := range []bool{false, true} {
+			te, err := New("tagexpr").SetUnreachable(strict).Run(c.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := c.want
+			if strict {
+				want = c.strict
+			}
+			for selector, r := range want {
+				if got := te.Eval(selector); got != r {
+					t.Fatalf("%d unreachable=%v %s: got %#v, want %#v", i, strict, selector, got, r)
+				}
+			}
+		}
+	}
+	expr, err := New("tagexpr").ParseExpr("(A)$?.B[0]?[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := expr.String(); s != "(A)$?['B'][0]?[1]" {
+		t.Fatalf("String: %s", s)
+	}
+}
+
+func TestRuleReference(t *testing.T) {
+	type Contact struct {
+		Name  string   `tagexpr:"$!=''"`
+		Email string   `tagexpr:"{@:regexp('^\\w+@\\w+\\.com$')}{min:len($)>=6}"`
+		Phone string   `tagexpr:"len($)>=5"`
+		_     struct{} `tagexpr:"{ok:@Name && (@Email || @Phone)}{min:!@Email@min}{missing:@Nothing}"`
+	}
+	cases := []struct {
+		v       *Contact
+		ok, min interface{}
+	}{
+		{&Contact{Name: "a", Email: "a@b.com"}, true, false},
+		{&Contact{Name: "a", Phone: "12345"}, true, true},
+		{&Contact{Name: "a"}, false, true},
+		{&Contact{Email: "a@b.com"}, false, false},
+	}
+	vm := New("tagexpr")
+	for i, c := range cases {
+		te, err := vm.Run(c.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := te.Eval("_@ok"); got != c.ok {
+			t.Fatalf("%d ok: got %v, want %v", i, got, c.ok)
+		}
+		if got := te.Eval("_@min"); got != c.min {
+			t.Fatalf("%d min: got %v, want %v", i, got, c.min)
+		}
+		if got := te.Eval("_@missing"); got != nil {
+			t.Fatalf("%d missing: got %v, want nil", i, got)
+		}
+	}
+	expr, err := vm.ParseExpr("!@A.B@min || @C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := expr.String(); s != "!@A.B@min || @C" {
+		t.Fatalf("String: %s", s)
+	}
+	type Cycle struct {
+		A int `tagexpr:"@B"`
+		B int `tagexpr:"{@:@C@x}"`
+		C int `tagexpr:"{x:@A}"`
+	}
+	_, err = vm.Run(&Cycle{})
+	if !errors.Is(err, ErrSyntax) || !strings.Contains(err.Error(), "A@ -> B@ -> C@x -> A@") {
+		t.Fatalf("want the cyclic error, got %v", err)
+	}
+}
+
+func TestEmbeddedOverride(t *testing.T) {
+	type Base struct {
+		Name  string `tagexpr:"{@:len($)>0}{msg:'name is required'}"`
+		Email string `tagexpr:"{@:$!=''}{msg:'email is required'}"`
+		Age   int    `tagexpr:"$>0"`
+	}
+	type Inner struct {
+		Base
+	}
+	type User struct {
+		Inner
+		Name  string `tagexpr:"len($)<=8"`
+		Email string `tagexpr:"{@:$==''||regexp('@')}{inherit:false}"`
+		Age   int
+	}
+	vm := New("tagexpr")
+	te, err := vm.Run(&User{Name: "Bartholomew"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var selectors []string
+	te.Range(func(selector string, _ func() interface{}) bool {
+		selectors = append(selectors, selector)
+		return true
+	})
+	want := []string{"Inner.Base.Name@msg", "Inner.Base.Age@", "Name@", "Email@", "Email@inherit"}
+	if !reflect.DeepEqual(selectors, want) {
+		t.Fatalf("selectors: got %v, want %v", selectors, want)
+	}
+	if got := te.Eval("Name@"); got != false {
+		t.Fatalf("Name@: got %v, want false", got)
+	}
+	te, err = vm.Run(&Base{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := te.Eval("Name@"); got != false {
+		t.Fatalf("Base Name@: got %v, want false", got)
+	}
+}
+
+func TestSkipField(t *testing.T) {
+	type Vendor struct {
+		Bad string `tagexpr:"$$$"`
+	}
+	type T struct {
+		A      int     `tagexpr:"$>0"`
+		Vendor *Vendor `tagexpr:"-"`
+		Skip   string  `tagexpr:"-"`
+	}
+	vm := New("tagexpr")
+	te, err := vm.Run(&T{A: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := vm.Struct(reflect.TypeOf(T{}))
+	if names := s.FieldNames(); !reflect.DeepEqual(names, []string{"A"}) {
+		t.Fatalf("FieldNames: got %v", names)
+	}
+	if _, ok := te.Field("Skip"); ok {
+		t.Fatal("want Skip unregistered")
+	}
+	if len(vm.structJar) != 1 {
+		t.Fatalf("want only T registered, got %d", len(vm.structJar))
+	}
+}
+
+func TestRegisterLimits(t *testing.T) {
+	type L3 struct {
+		A int `tagexpr:"$>0"`
+	}
+	type L2 struct {
+		L3 L3
+	}
+	type L1 struct {
+		L2 *L2
+	}
+	var cases = []struct {
+		limits RegisterLimits
+		reason string
+	}{
+		{RegisterLimits{}, ""},
+		{RegisterLimits{MaxDepth: 3, MaxFields: 3}, ""},
+		{RegisterLimits{MaxDepth: 2}, "tagexpr.L1: nested too deep, the limit is 2"},
+		{RegisterLimits{MaxFields: 2}, "tagexpr.L1: too many fields, the limit is 2"},
+		{RegisterLimits{MaxDepth: -1, MaxFields: 1}, "tagexpr.L2: too many fields, the limit is 1"},
+	}
+	for i, c := range cases {
+		vm := New("tagexpr").SetRegisterLimits(c.limits)
+		for j := 0; j < 2; j++ {
+			_, err := vm.Run(&L1{})
+			if c.reason == "" {
+				if err != nil {
+					t.Fatalf("%d: %v", i, err)
+				}
+				continue
+			}
+			if !errors.Is(err, ErrLimitExceeded) || err.Error() != c.reason {
+				t.Fatalf("%d: got %v, want %s", i, err, c.reason)
+			}
+			if _, ok := vm.structJar[reflect.TypeOf(L1{})]; ok {
+				t.Fatalf("%d: want L1 unregistered", i)
+			}
+		}
+	}
+}
+
+func TestIgnoredFields(t *testing.T) {
+	type Addr struct {
+		City string
+		Hook func()
+	}
+	type T struct {
+		Name    string
+		Done    chan bool
+		Addr    *Addr
+		Created time.Time `tagexpr:"$!=nil"`
+		Amount  big.Int
+	}
+	vm := New("tagexpr")
+	s, err := vm.Struct(reflect.TypeOf(T{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range s.IgnoredFields() {
+		if !strings.HasPrefix(f.Field, "Created.") {
+			got = append(got, f.Field+": "+f.Type.String()+": "+f.Reason)
+		}
+	}
+	want := []string{
+		"Done: chan bool: unsupported kind chan",
+		"Addr: *tagexpr.Addr: struct value, only its fields are selectable",
+		"Addr.Hook: func(): unsupported kind func",
+		"Created: time.Time: struct value, only its fields are selectable",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestUnsupportedPolicy(t *testing.T) {
+	type Inner struct {
+		Ptr unsafe.Pointer
+	}
+	type T struct {
+		A     int `tagexpr:"$>0"`
+		Hook  func()
+		Inner *Inner
+		Done  chan bool `tagexpr:"-"`
+		Any   interface{}
+	}
+	var warned []string
+	vm := New("tagexpr").SetUnsupportedPolicy(UnsupportedWarn, func(structName string, f IgnoredField) {
+		warned = append(warned, structName+"."+f.Field+": "+f.Reason)
+	})
+	if _, err := vm.Run(&T{A: 1}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"tagexpr.T.Hook: unsupported kind func", "tagexpr.Inner.Ptr: unsupported kind unsafe.Pointer"}
+	if !reflect.DeepEqual(warned, want) {
+		t.Fatalf("warned: got %q, want %q", warned, want)
+	}
+	vm = New("tagexpr").SetUnsupportedPolicy(UnsupportedError, nil)
+	_, err := vm.Run(&T{A: 1})
+	if !errors.Is(err, ErrTypeMismatch) || err.Error() != "tagexpr.T.Hook: unsupported kind func" {
+		t.Fatalf("want the unsupported error, got %v", err)
+	}
+	if _, err = New("tagexpr").Run(&T{A: 1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResultType(t *testing.T) {
+	type T struct {
+		A string `tagexpr:"{@:bool: $!=''}{msg:string: sprintf('bad %v',$)}{n:number: len($)}"`
+		B string `tagexpr:"{@:bool: (A)$}{x: string : $}"`
+		C int    `tagexpr:"number: $*2"`
+	}
+	vm := New("tagexpr")
+	te, err := vm.Run(&T{A: "a", B: "b", C: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for selector, want := range map[string]interface{}{"A@": true, "A@msg": "bad a", "A@n": 1.0, "B@x": "b", "C@": 6.0} {
+		got, err := te.EvalWithError(selector)
+		if err != nil || got != want {
+			t.Fatalf("%s: got %v, %v, want %v", selector, got, err, want)
+		}
+	}
+	_, err = te.EvalWithError("B@")
+	if !errors.Is(err, ErrTypeMismatch) || err.Error() != "B@: the result a is string, declared bool" {
+		t.Fatalf("want the result type error, got %v", err)
+	}
+	if e, _ := te.s.getExpr("A@n"); e.expr.ResultType() != "number" {
+		t.Fatalf("ResultType: got %q", e.expr.ResultType())
+	}
+	var cases = []struct {
+		v   interface{}
+		err string
+	}{
+		{&struct {
+			A string `tagexpr:"{@:bool: len($)}"`
+		}{}, "A@: the result is number, declared bool"},
+		{&struct {
+			A string `tagexpr:"string: $=='a' || !$"`
+		}{}, "A@: the result is bool, declared string"},
+		{&struct {
+			A string `tagexpr:"{x:number: 'a'+$}"`
+		}{}, "A@x: the result is string, declared number"},
+	}
+	for _, c := range cases {
+		_, err := vm.Run(c.v)
+		if !errors.Is(err, ErrTypeMismatch) || err.Error() != c.err {
+			t.Fatalf("got %v, want %s", err, c.err)
+		}
+	}
+}
+
+func TestEvalBoolStrict(t *testing.T) {
+	type T struct {
+		A int     `tagexpr:"$>0"`
+		B *bool   `tagexpr:"$"`
+		C string  `tagexpr:"$"`
+		D *string `tagexpr:"{@:bool: $}"`
+	}
+	te, err := New("tagexpr").Run(&T{C: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := te.EvalBoolStrict("A@"); r || err != nil {
+		t.Fatalf("A@: got %v, %v", r, err)
+	}
+	for selector, want := range map[string]string{
+		"B@": "B@: the result <nil> is not bool",
+		"C@": "C@: the result c is not bool",
+		"D@": "D@: the result <nil> is nil, declared bool",
+		"E@": "unknown selector: E@",
+	} {
+		r, err := te.EvalBoolStrict(selector)
+		if r || err == nil || err.Error() != want {
+			t.Fatalf("%s: got %v, %v, want %s", selector, r, err, want)
+		}
+	}
+	if _, err = te.EvalBoolStrict("B@"); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("want ErrTypeMismatch, got %v", err)
+	}
+}
+
+func TestUnreached(t *testing.T) {
+	type P struct {
+		X int
+	}
+	type T struct {
+		P *P             `tagexpr:"{a:(P.X)$>0}{b:$==nil}{c:(P)$?.X==nil}{d:missing((P.X)$)}"`
+		M map[string]int `tagexpr:"{a:$['k']==nil}{b:len($)==0}"`
+	}
+	te, err := New("tagexpr").Run(&T{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for selector, want := range map[string]bool{
+		"P@a": true, "P@b": false, "P@c": false, "P@d": false, "M@a": true, "M@b": false,
+	} {
+		if _, got, _ := te.EvalWithUnreached(selector); got != want {
+			t.Fatalf("%s: got %v, want %v", selector, got, want)
+		}
+	}
+}
+
+func TestConcurrentEval(t *testing.T) {
+	type P struct {
+		X int
+	}
+	type T struct {
+		Matrix [][]int `tagexpr:"all($,all(#v,#v>=#k))"`
+		P      *P      `tagexpr:"{a:(P.X)$>0}{b:$==nil}"`
+	}
+	te, err := New("tagexpr").Run(&T{Matrix: [][]int{{0, 1}, {2, 3}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan string, 64)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if v, err := te.EvalWithError("Matrix@"); v != true || err != nil {
+					errs <- fmt.Sprintf("Matrix@: got %v, %v", v, err)
+					return
+				}
+				if _, unreached, _ := te.EvalWithUnreached("P@a"); !unreached {
+					errs <- "P@a: want unreached"
+					return
+				}
+				if _, unreached, _ := te.EvalWithUnreached("P@b"); unreached {
+					errs <- "P@b: want reached"
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Fatal(e)
+	}
+}
+
+func TestClone(t *testing.T) {
+	type P struct {
+		X int
+	}
+	type T struct {
+		P     *P     `tagexpr:"(P.X)$"`
+		Items []int  `tagexpr:"len($)<=%max%"`
+		Beta  string `tagexpr:"flag('beta')"`
+	}
+	vm := New("tagexpr").SetParams(Params{"max": 1})
+	if err := vm.WarmUp(&T{}); err != nil {
+		t.Fatal(err)
+	}
+	pro := vm.Clone(func(c *VM) {
+		c.SetParams(Params{"max": 3}).SetUnreachable(true).SetFlagProvider(FlagFunc(func(string) bool { return true }))
+	})
+	strict := pro.Clone()
+	v := &T{Items: []int{1, 2}}
+	for _, c := range []struct {
+		vm         *VM
+		items      bool
+		p          interface{}
+		beta       bool
+		registered int
+	}{
+		{vm, false, nil, false, 2},
+		{pro, true, Unreachable{}, true, 0},
+		{strict, true, Unreachable{}, true, 0},
+	} {
+		te, err := c.vm.Run(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if te.s != vm.structJar[reflect.TypeOf(T{})] {
+			t.Fatal("want the shared struct")
+		}
+		if got := te.EvalBool("Items@"); got != c.items {
+			t.Fatalf("Items@: got %v, want %v", got, c.items)
+		}
+		if got := te.Eval("P@"); got != c.p {
+			t.Fatalf("P@: got %v, want %v", got, c.p)
+		}
+		if got := te.EvalBool("Beta@"); got != c.beta {
+			t.Fatalf("Beta@: got %v, want %v", got, c.beta)
+		}
+		if len(c.vm.structJar) != c.registered {
+			t.Fatalf("want %d structs registered by the vm, got %d", c.registered, len(c.vm.structJar))
+		}
+	}
+	if strict.parent != vm {
+		t.Fatal("want the clone of the clone sharing the first vm")
+	}
+}
+
+func TestCloneEnum(t *testing.T) {
+	type T struct {
+		Status string `tagexpr:"enum('status',$)"`
+	}
+	vm := New("tagexpr")
+	if err := vm.RegisterEnum("status", []string{"open"}); err != nil {
+		t.Fatal(err)
+	}
+	clone := vm.Clone()
+	if err := clone.RegisterEnum("status", []string{"open", "closed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := clone.RegisterEnum("level", []int{1}); err != nil {
+		t.Fatal(err)
+	}
+	v := &T{Status: "closed"}
+	for _, c := range []struct {
+		vm     *VM
+		status bool
+		enums  int
+	}{
+		{vm, false, 1},
+		{clone, true, 2},
+	} {
+		te, err := c.vm.Run(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := te.EvalBool("Status@"); got != c.status {
+			t.Fatalf("Status@: got %v, want %v", got, c.status)
+		}
+		if len(c.vm.enums) != c.enums {
+			t.Fatalf("want %d enums, got %d", c.enums, len(c.vm.enums))
+		}
+	}
+}
+
+func TestRuleCache(t *testing.T) {
+	type Item struct {
+		N int
+	}
+	type T struct {
+		A     int             `tagexpr:"{@:$>0 && $%2==1 || !($<=-3)}{x:(A)$+1.5*2-1/4}{y:number: $*2}"`
+		S     string          `tagexpr:"{@:regexp('^a+$') && regexp('b', (S)$)==false}{f:sprintf('%s-%v', $, len($))}{g:!!iso3166(upper($))}"`
+		Items []*Item         `tagexpr:"{@:count($,#v!=nil)==len($) && all($) && !any($,#k>9)}{x:$?[5]?.N==nil}"`
+		M     map[string]bool `tagexpr:"{@:exists($['k']) && !missing()}{x:@A && @S@f!='' && %p%==nil}{n:nil}"`
+	}
+	v := &T{A: 3, S: "aa", Items: []*Item{{N: 1}}, M: map[string]bool{"k": true}}
+	vm := New("tagexpr")
+	te, err := vm.Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = vm.SaveRuleCache(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	cached := New("tagexpr")
+	if err = cached.LoadRuleCache(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if len(cached.ruleCache) != 11 {
+		t.Fatalf("want 11 cached expressions, got %d", len(cached.ruleCache))
+	}
+	cte, err := cached.Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	te.Range(func(selector string, eval func() interface{}) bool {
+		e, _ := te.s.getExpr(selector)
+		ce, _ := cte.s.getExpr(selector)
+		if ce.expr.ps != nil || ce.expr.String() != e.expr.String() {
+			t.Fatalf("%s: got %q, want %q", selector, ce.expr.String(), e.expr.String())
+		}
+		if got, want := cte.Eval(selector), eval(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %v, want %v", selector, got, want)
+		}
+		return true
+	})
+	cached.ruleCache["$>0 && $%2==1 || !($<=-3)"] = &cachedNode{Kind: "group", Right: &cachedNode{Kind: "bool"}}
+	type U struct {
+		A int `tagexpr:"$>0 && $%2==1 || !($<=-3)"`
+	}
+	ute, err := cached.Run(&U{A: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ute.Eval("A@") != false {
+		t.Fatal("want the expression built from the cache")
+	}
+	err = New("tagexpr").SetCostOrder(true).LoadRuleCache(bytes.NewReader(data))
+	if err == nil || !strings.Contains(err.Error(), "rule cache mismatch") {
+		t.Fatalf("want the mismatch error, got %v", err)
+	}
+}
+
+func TestWriteRegistry(t *testing.T) {
+	type T struct {
+		A int `registry:"$>0 && $<10"`
+	}
+	vm := New("registry")
+	if err := vm.WarmUp(&T{}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := vm.WriteRegistry(&buf, "api"); err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+	prefix := "// Code generated by tagexpr.WriteRegistry. DO NOT EDIT.\n\npackage api\n\nimport tagexpr \"github.com/bytedance/go-tagexpr\"\n\nfunc init() {\n\ttagexpr.RegisterRuleCache(\"registry\", \""
+	if !strings.HasPrefix(src, prefix) {
+		t.Fatalf("unexpected source:\n%s", src)
+	}
+	var data bytes.Buffer
+	vm.SaveRuleCache(&data)
+	RegisterRuleCache("registry", data.String())
+	defer delete(ruleCacheRegistry, "registry")
+	cached := New("registry")
+	te, err := cached.Run(&T{A: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cached.ruleCache["$>0 && $<10"]; !ok || !te.EvalBool("A@") {
+		t.Fatal("want the expression built from the registered cache")
+	}
+	other := New("other")
+	if err = other.WarmUp(&T{}); err != nil || other.ruleCache != nil {
+		t.Fatal("want no cache of the other tag name")
+	}
+	if err = cached.LoadRegistry(); err != nil {
+		t.Fatal(err)
+	}
+	strict := New("registry").SetCostOrder(true)
+	if err = strict.LoadRegistry(); err == nil || !strings.Contains(err.Error(), "rule cache mismatch") {
+		t.Fatalf("want the mismatch error, got %v", err)
+	}
+	if te, err = strict.Run(&T{A: 3}); err != nil || !te.EvalBool("A@") {
+		t.Fatalf("want the expression parsed, got %v", err)
+	}
+}
+
+func TestParams(t *testing.T) {
+	type T struct {
+		Items []int  `tagexpr:"{max:len($)<=%max_items%}{plan:%plan%=='pro' || !%trial%}{missing:%nothing%==nil}"`
+		Note  string `tagexpr:"len($)<=%max_note%"`
+	}
+	vm := New("tagexpr").SetParams(Params{"max_items": 2, "plan": "free", "trial": true, "max_note": uint8(3)})
+	v := &T{Items: []int{1, 2, 3}, Note: "abc"}
+	te, err := vm.Run(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"Items@max": false, "Items@plan": false, "Items@missing": true, "Note@": true}
+	for selector, r := range want {
+		if got := te.Eval(selector); got != r {
+			t.Fatalf("%s: got %v, want %v", selector, got, r)
+		}
+	}
+	te.SetParams(Params{"max_items": 5.0, "plan": "pro"})
+	want = map[string]interface{}{"Items@max": true, "Items@plan": true, "Note@": true}
+	for selector, r := range want {
+		if got := te.Eval(selector); got != r {
+			t.Fatalf("%s: got %v, want %v", selector, got, r)
+		}
+	}
+	expr, err := vm.ParseExpr("$%2==0 && !%flag% && $<%max%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := expr.String(); s != "$ % 2 == 0 && !%flag% && $ < %max%" {
+		t.Fatalf("String: %s", s)
+	}
+}
+
+func TestFlag(t *testing.T) {
+	type T struct {
+		Password string `tagexpr:"flag('new-policy') && len($)>=12 || !flag('new-policy') && len($)>=8"`
+	}
+	flags := map[string]bool{}
+	v := &T{Password: "abcdefghi"}
+	for _, c := range []struct {
+		vm   *VM
+		on   bool
+		want bool
+	}{
+		{New("tagexpr"), false, true},
+		{New("tagexpr").SetFlagProvider(FlagFunc(func(name string) bool { return flags[name] })), false, true},
+		{New("tagexpr").SetFlagProvider(FlagFunc(func(name string) bool { return flags[name] })), true, false},
+	} {
+		flags["new-policy"] = c.on
+		te, err := c.vm.Run(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := te.Eval("Password@"); got != c.want {
+			t.Fatalf("flag=%v: got %v, want %v", c.on, got, c.want)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	type T struct {
+		A []int  `tagexpr:"len($)>1&&(any($,#v>2)||$[0]==1)"`
+		B string `tagexpr:"!($=='')"`
+	}
+	te, err := New("tagexpr").Run(&T{A: []int{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps, ok := te.Trace("A@")
+	if !ok {
+		t.Fatal("not found: A@")
+	}
+	want := []TraceStep{
+		{0, "len($) > 1 && (any($,#v > 2) || $[0] == 1)", true},
+		{1, "len($) > 1", true},
+		{2, "len($)", 2.0},
+		{3, "$", []int{1, 2}},
+		{1, "any($,#v > 2) || $[0] == 1", true},
+		{2, "any($,#v > 2)", false},
+		{3, "$", []int{1, 2}},
+		{2, "$[0] == 1", true},
+		{3, "$[0]", 1.0},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Fatalf("got: %+v, want: %+v", steps, want)
+	}
+	steps, _ = te.Trace("B@")
+	if len(steps) != 3 || steps[0].Value != false || steps[1].Expr != "$ == ''" || steps[2].Value != "" {
+		t.Fatalf("got: %+v", steps)
+	}
+	if _, ok = te.Trace("C@"); ok {
+		t.Fatal("want not found")
+	}
+}
+
+func TestRecover(t *testing.T) {
+	if err := RegFunc("boom", func(args ...interface{}) interface{} {
+		panic("boom")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	type T struct {
+		A []int `tagexpr:"{@:$!=nil}{b:any($,boom(#v))}"`
+	}
+	vm := New("tagexpr")
+	te, err := vm.Run(&T{A: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := te.Eval("A@b"); v != nil {
+		t.Fatalf("Eval: got %v, want nil", v)
+	}
+	_, err = te.EvalWithError("A@b")
+	evalErr, ok := err.(*EvalError)
+	if !ok || evalErr.Selector != "A@b" || evalErr.Panic != "boom" || len(evalErr.Stack) == 0 {
+		t.Fatalf("EvalWithError: got %#v", err)
+	}
+	if v, err := te.EvalWithError("A@"); v != true || err != nil {
+		t.Fatalf("EvalWithError: got %v, %v", v, err)
+	}
+	if te.frame != nil {
+		t.Fatalf("frame: got %+v, want nil", te.frame)
+	}
+
+	vm.SetRecover(false)
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("SetRecover(false): got panic %v", p)
+		}
+	}()
+	te.Eval("A@b")
+}
+
+func TestErrors(t *testing.T) {
+	type T struct {
+		A *struct{ B int } `tagexpr:"$!=nil"`
+		C []int            `tagexpr:"{@:$[0]>0}"`
+	}
+	vm := New("tagexpr")
+	_, err := vm.ParseExpr("1 + + 2")
+	var serr *SyntaxError
+	if !errors.Is(err, ErrSyntax) || !errors.As(err, &serr) {
+		t.Fatalf("ParseExpr: got %v", err)
+	}
+	type Dup struct {
+		A int `tagexpr:"{@:$>0}{@:$<9}"`
+	}
+	var cases = []struct {
+		v    interface{}
+		kind error
+	}{
+		{nil, ErrNilDeref},
+		{(*T)(nil), ErrTypeMismatch},
+		{T{}, ErrTypeMismatch},
+		{new(int), ErrTypeMismatch},
+		{new(Dup), ErrSyntax},
+	}
+	for _, c := range cases {
+		if _, err := vm.Run(c.v); !errors.Is(err, c.kind) {
+			t.Fatalf("Run(%T): got %v, want %v", c.v, err, c.kind)
+		}
+	}
+	if _, err := vm.RunValue(reflect.ValueOf((*T)(nil))); !errors.Is(err, ErrNilDeref) {
+		t.Fatalf("RunValue: got %v", err)
+	}
+	te, err := vm.Run(&T{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := te.EvalWithError("X@"); !errors.Is(err, ErrUnknownSelector) {
+		t.Fatalf("EvalWithError: got %v", err)
+	}
+	recovered := func(fn func()) (p interface{}) {
+		defer func() { p = recover() }()
+		fn()
+		return
+	}
+	var nilPtr *T
+	nilDeref := &EvalError{Panic: recovered(func() { _ = nilPtr.A })}
+	if !errors.Is(nilDeref, ErrNilDeref) || errors.Is(nilDeref, ErrTypeMismatch) {
+		t.Fatalf("EvalError: got %v", nilDeref)
+	}
+	mismatch := &EvalError{Panic: recovered(func() { reflect.ValueOf(1).Len() })}
+	if !errors.Is(mismatch, ErrTypeMismatch) || errors.Is(mismatch, ErrNilDeref) {
+		t.Fatalf("EvalError: got %v", mismatch)
+	}
+	typed := &EvalError{Panic: recovered(func() { panic(newKindError(ErrTypeMismatch, "not a number")) })}
+	var kindErr *kindError
+	if !errors.Is(typed, ErrTypeMismatch) || errors.Is(typed, ErrNilDeref) || !errors.As(typed, &kindErr) {
+		t.Fatalf("EvalError: got %v", typed)
+	}
+	untyped := &EvalError{Panic: recovered(func() { panic("reflect: not a number") })}
+	if errors.Is(untyped, ErrTypeMismatch) || errors.Unwrap(untyped) != nil {
+		t.Fatalf("EvalError: got %v", untyped)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	type Addr struct {
+		Zip string `tagexpr:"len($)==5||$==''"`
+	}
+	type User struct {
+		Name string `tagexpr:"{@:$!=''&&len($)<=8}{msg:'bad name'}"`
+		Addr *Addr
+	}
+	vm := New("tagexpr").SetCoverage(true)
+	for _, u := range []*User{
+		{Name: "Alice", Addr: &Addr{Zip: "12345"}},
+		{Name: "", Addr: &Addr{Zip: "123"}},
+	} {
+		te, err := vm.Run(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		te.Eval("Name@")
+		te.Eval("Addr.Zip@")
+	}
+	want := []ExprCoverage{
+		{Struct: "tagexpr.Addr", Selector: "Zip@", Expr: "len($) == 5 || $ == ''", Hits: 2, Branches: []BranchCoverage{
+			{Expr: "len($) == 5 || $ == ''", Op: "||", Short: 1, Full: 1},
+		}},
+		{Struct: "tagexpr.User", Selector: "Name@", Expr: "$ != '' && len($) <= 8", Hits: 2, Branches: []BranchCoverage{
+			{Expr: "$ != '' && len($) <= 8", Op: "&&", Short: 1, Full: 1},
+		}},
+		{Struct: "tagexpr.User", Selector: "Name@msg", Expr: "'bad name'", Hits: 0},
+	}
+	if got := vm.Coverage(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %+v, want: %+v", got, want)
+	}
+	var buf bytes.Buffer
+	if err := vm.WriteCoverage(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(buf.String(), "expressions: 2/3 evaluated, branches: 2/2 covered\n") {
+		t.Fatalf("got: %s", buf.String())
+	}
+	if c := New("tagexpr").Coverage(); len(c) != 0 {
+		t.Fatalf("got: %+v", c)
+	}
+}
+
+func TestRegexpCache(t *testing.T) {
+	type A struct {
+		Name string `tagexpr:"regexp('^[a-z]+$')"`
+	}
+	type B struct {
+		Name  string `tagexpr:"regexp('^[a-z]+$')"`
+		Alias string `tagexpr:"{x:regexp('^[a-z]+$')}{y:replace($,'[0-9]+','')=='ab'}"`
+	}
+	vm := New("tagexpr")
+	if err := vm.WarmUp(new(A), new(B)); err != nil {
+		t.Fatal(err)
+	}
+	stats := vm.RegexpCacheStats()
+	if stats.Patterns != 1 || stats.Hits != 2 || stats.Misses != 1 {
+		t.Fatalf("got: %+v", stats)
+	}
+	te, err := vm.Run(&B{Name: "abc", Alias: "a1b2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !te.EvalBool("Name@") || !te.EvalBool("Alias@y") || te.EvalBool("Alias@x") {
+		t.Fatal("want Name@ and Alias@y true, Alias@x false")
+	}
+	te.Eval("Alias@y")
+	stats = vm.RegexpCacheStats()
+	if stats.Patterns != 2 || stats.Hits != 3 || stats.Misses != 2 {
+		t.Fatalf("got: %+v", stats)
+	}
+}
+
+func TestRegexpCacheLimit(t *testing.T) {
+	type T struct {
+		Text    string `tagexpr:"replace($,(Pattern)$,'')"`
+		Pattern string
+	}
+	vm := New("tagexpr")
+	for i := 0; i < maxRegexpCachePatterns+10; i++ {
+		te, err := vm.Run(&T{Text: "a1", Pattern: "x" + strconv.Itoa(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		te.Eval("Text@")
+	}
+	stats := vm.RegexpCacheStats()
+	if stats.Patterns != maxRegexpCachePatterns || stats.Misses != maxRegexpCachePatterns+10 {
+		t.Fatalf("got: %+v", stats)
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	type T struct {
+		Password string `tagexpr:"pwstrength($)"`
+	}
+	vm := New("tagexpr").SetPasswordPolicy(PasswordPolicy{
+		MinLength:       4,
+		LongLength:      6,
+		CommonPasswords: []string{"Secret"},
+	})
+	var cases = []struct {
+		password string
+		score    float64
+	}{
+		{"abc", 0},
+		{"secret", 0},
+		{"abcd", 0},
+		{"abcd1", 1},
+		{"abcd12", 2},
+		{"Ab1!xy", 4},
+	}
+	for _, c := range cases {
+		tagExpr, err := vm.Run(&T{Password: c.password})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score := tagExpr.EvalFloat("Password@"); score != c.score {
+			t.Fatalf("password: %q, got: %v, want: %v", c.password, score, c.score)
+		}
+	}
+}
+
+func TestJWT(t *testing.T) {
+	type T struct {
+		Token string `tagexpr:"jwt($)"`
+	}
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	payload := enc(`{"sub":"1234567890"}`)
+	sig := enc("signature")
+	rs256 := enc(`{"alg":"RS256","typ":"JWT"}`) + "." + payload + "." + sig
+	hs256 := enc(`{"alg":"HS256"}`) + "." + payload + "." + sig
+	none := enc(`{"alg":"none"}`) + "." + payload + "."
+	var cases = []struct {
+		vm    *VM
+		token string
+		valid bool
+	}{
+		{New("tagexpr"), rs256, true},
+		{New("tagexpr"), hs256, true},
+		{New("tagexpr"), none, false},
+		{New("tagexpr"), rs256 + "=", false},
+		{New("tagexpr"), enc(`{"typ":"JWT"}`) + "." + payload + "." + sig, false},
+		{New("tagexpr"), enc(`{"alg":"RS256"}`) + "." + enc(`[1]`) + "." + sig, false},
+		{New("tagexpr"), enc(`{"alg":"RS256"}`) + "." + payload + ".", false},
+		{New("tagexpr"), "a.b", false},
+		{New("tagexpr").SetJWTAlgs("RS256", "ES256"), rs256, true},
+		{New("tagexpr").SetJWTAlgs("RS256", "ES256"), hs256, false},
+		{New("tagexpr").SetJWTAlgs("none"), none, true},
+	}
+	for i, c := range cases {
+		tagExpr, err := c.vm.Run(&T{Token: c.token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if valid := tagExpr.EvalBool("Token@"); valid != c.valid {
+			t.Fatalf("case %d: token: %q, got: %v, want: %v", i, c.token, valid, c.valid)
+		}
+	}
+}
+
+func TestMarkupPolicy(t *testing.T) {
+	type T struct {
+		Text string `tagexpr:"{html:nohtml($)}{script:noscript($)}"`
+	}
+	var cases = []struct {
+		text           string
+		noHTML, noJS   bool
+		allowedNoHTML  bool
+		configuredNoJS bool
+	}{
+		{"a < b and c > d", true, true, true, true},
+		{"<b>bold</b>", false, true, true, true},
+		{"&lt;b&gt;bold&lt;/b&gt;", false, true, true, true},
+		{"<!-- x -->", false, true, false, true},
+		{"<script>alert(1)</script>", false, false, false, false},
+		{"<a href='java\tscript:alert(1)'>x</a>", false, false, false, false},
+		{"java&#9;script:alert(1)", true, false, true, false},
+		{"<img src=x onerror=alert(1)>", false, false, false, false},
+		{"once = twice", true, true, true, true},
+		{"call eval_me(1)", true, true, true, false},
+	}
+	vm := New("tagexpr")
+	configured := New("tagexpr").SetMarkupPolicy(MarkupPolicy{
+		AllowedTags:    []string{"B", "i"},
+		ScriptPatterns: []string{"EVAL_ME("},
+	})
+	for _, c := range cases {
+		tagExpr, err := vm.Run(&T{Text: c.text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tagExpr.EvalBool("Text@html"); got != c.noHTML {
+			t.Fatalf("nohtml(%q): got %v", c.text, got)
+		}
+		if got := tagExpr.EvalBool("Text@script"); got != c.noJS {
+			t.Fatalf("noscript(%q): got %v", c.text, got)
+		}
+		tagExpr, err = configured.Run(&T{Text: c.text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tagExpr.EvalBool("Text@html"); got != c.allowedNoHTML {
+			t.Fatalf("configured nohtml(%q): got %v", c.text, got)
+		}
+		if got := tagExpr.EvalBool("Text@script"); got != c.configuredNoJS {
+			t.Fatalf("configured noscript(%q): got %v", c.text, got)
+		}
+	}
+}
+
+func TestLikeFunc(t *testing.T) {
+	type T struct {
+		Q string `tagexpr:"{safe:likesafe($)}{bang:likesafe($,'!')}{esc:likeescape($)}"`
+	}
+	var cases = []struct {
+		q          string
+		safe, bang bool
+		esc        string
+	}{
+		{"abc", true, true, "abc"},
+		{"50%", false, false, "50\\%"},
+		{"a_b", false, false, "a\\_b"},
+		{"50\\%", true, false, "50\\\\\\%"},
+		{"50!%", false, true, "50!\\%"},
+		{"it's", false, false, "it's"},
+		{"end\\", false, true, "end\\\\"},
+		{"\\a", false, true, "\\\\a"},
+	}
+	vm := New("tagexpr")
+	for _, c := range cases {
+		tagExpr, err := vm.Run(&T{Q: c.q})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tagExpr.EvalBool("Q@safe"); got != c.safe {
+			t.Fatalf("likesafe(%q): got %v", c.q, got)
+		}
+		if got := tagExpr.EvalBool("Q@bang"); got != c.bang {
+			t.Fatalf("likesafe(%q, '!'): got %v", c.q, got)
+		}
+		if got := tagExpr.EvalString("Q@esc"); got != c.esc {
+			t.Fatalf("likeescape(%q): got %q, want %q", c.q, got, c.esc)
+		}
+	}
+}
+
+func TestRegisterEnum(t *testing.T) {
+	type Status string
+	type T struct {
+		Status Status   `tagexpr:"enum('status',$)"`
+		Level  int      `tagexpr:"enum('level',$)"`
+		Kind   string   `tagexpr:"enum('kind',$)"`
+		Tags   []string `tagexpr:"enum('status',$)"`
+	}
+	vm := New("tagexpr")
+	if err := vm.RegisterEnum("status", []Status{"open", "closed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.RegisterEnum("level", [3]int8{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.RegisterEnum("level", 1); err == nil {
+		t.Fatal("want error of non-slice values")
+	}
+	if err := vm.RegisterEnum("level", []struct{}{{}}); err == nil {
+		t.Fatal("want error of unsupported values")
+	}
+	tagExpr, err := vm.Run(&T{Status: "open", Level: 4, Tags: []string{"open"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tagExpr.EvalBool("Status@") {
+		t.Fatal("Status@: want true")
+	}
+	if tagExpr.EvalBool("Level@") {
+		t.Fatal("Level@: want false")
+	}
+	if v := tagExpr.Eval("Kind@"); v != nil {
+		t.Fatalf("Kind@: got: %v, want: nil", v)
+	}
+	if v := tagExpr.Eval("Tags@"); v != false {
+		t.Fatalf("Tags@: got: %v, want: false", v)
+	}
+}
+
+func TestCollectionFunc(t *testing.T) {
+	type T struct {
+		Config map[string]int `tagexpr:"{keys:keys($)}{values:values($)}{len:len(keys($))}"`
+		Tags   []string       `tagexpr:"{unique:unique($)}{values:values($)}"`
+		IDs    [3]*int        `tagexpr:"unique($)"`
+		Name   string         `tagexpr:"keys($)"`
+		Breaks []float32      `tagexpr:"{sorted:sorted($)}{ascending:ascending($)}"`
+		Words  [3]string      `tagexpr:"{sorted:sorted($)}{ascending:ascending($)}"`
+		Mixed  []interface{}  `tagexpr:"sorted($)"`
+	}
+	one, two := 1, 2
+	vm := New("tagexpr")
+	tagExpr, err := vm.Run(&T{
+		Config: map[string]int{"b": 2, "a": 1, "c": 3},
+		Tags:   []string{"x", "y", "x"},
+		IDs:    [3]*int{&one, &two, nil},
+		Breaks: []float32{1, 2, 2, 3.5},
+		Words:  [3]string{"a", "b", "c"},
+		Mixed:  []interface{}{"a", 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = map[string]interface{}{
+		"Config@keys":      []interface{}{"a", "b", "c"},
+		"Config@values":    []interface{}{1.0, 2.0, 3.0},
+		"Config@len":       3.0,
+		"Tags@unique":      false,
+		"Tags@values":      []interface{}{"x", "y", "x"},
+		"IDs@":             true,
+		"Name@":            nil,
+		"Breaks@sorted":    true,
+		"Breaks@ascending": false,
+		"Words@sorted":     true,
+		"Words@ascending":  true,
+		"Mixed@":           nil,
+	}
+	for selector, want := range cases {
+		if got := tagExpr.Eval(selector); !reflect.DeepEqual(got, want) {
+			t.Fatalf("selector: %q, got: %v, want: %v", selector, got, want)
+		}
+	}
+}
+
+func TestCollectionCoercion(t *testing.T) {
+	type T struct {
+		Ptrs  []*int             `tagexpr:"{values:values($)}{sorted:sorted($)}{count:count($,#v>1)}"`
+		Any   []interface{}      `tagexpr:"{values:values($)}{ascending:ascending($)}{any:any($,#v==3)}{unique:unique($)}"`
+		Names map[string]*string `tagexpr:"{values:values($)}{all:all($,#v!='')}"`
+	}
+	one, two, three, name := 1, 2, 3, "a"
+	var nilPtr *int
+	vm := New("tagexpr")
+	tagExpr, err := vm.Run(&T{
+		Ptrs:  []*int{&one, &two, &three},
+		Any:   []interface{}{int8(1), uint(2), &three, 4.0, nilPtr},
+		Names: map[string]*string{"x": &name, "y": nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = map[string]interface{}{
+		"Ptrs@values":   []interface{}{1.0, 2.0, 3.0},
+		"Ptrs@sorted":   true,
+		"Ptrs@count":    2.0,
+		"Any@values":    []interface{}{1.0, 2.0, 3.0, 4.0, nil},
+		"Any@ascending": nil,
+		"Any@any":       true,
+		"Any@unique":    true,
+		"Names@values":  []interface{}{"a", nil},
+		"Names@all":     true,
+	}
+	for selector, want := range cases {
+		if got := tagExpr.Eval(selector); !reflect.DeepEqual(got, want) {
+			t.Fatalf("selector: %q, got: %v, want: %v", selector, got, want)
+		}
+	}
+	tagExpr, _ = vm.Run(&T{Any: []interface{}{int8(1), uint(2), &three, 4.0}})
+	if got := tagExpr.Eval("Any@ascending"); got != true {
+		t.Fatalf("got: %v, want: true", got)
+	}
+}
+
+func TestDiveFunc(t *testing.T) {
+	type T struct {
+		Scores []int            `tagexpr:"{count:count($,#v>0)}{any:any($,#v>2)}{all:all($,#v>=0)}{truthy:count($)}{not:!any($,#v>9)}"`
+		Matrix [][]int          `tagexpr:"{all:all($,count(#v,#v>0)>0)}{any:any($,all(#v))}"`
+		Attrs  map[string]*bool `tagexpr:"count(values($),#v==nil)"`
+		Empty  []string         `tagexpr:"{any:any($)}{all:all($)}"`
+		CSV    string           `tagexpr:"all(split($,','),regexp('^[a-z]+$',#v))"`
+		Name   string           `tagexpr:"count($,#v)"`
+		Rows   []string         `tagexpr:"{header:all($,#k>0||#v=='id,name')}{body:count($,#k>=1&&#v!='')}"`
+		Limits map[string]int   `tagexpr:"all($,regexp('^[a-z]+$',#k)&&#v>0)"`
+	}
+	yes := true
+	vm := New("tagexpr")
+	tagExpr, err := vm.Run(&T{
+		Scores: []int{0, 1, 3, 0},
+		Matrix: [][]int{{0, 1}, {2, 3}},
+		Attrs:  map[string]*bool{"a": &yes, "b": nil},
+		CSV:    "x,y,z",
+		Rows:   []string{"id,name", "1,a", ""},
+		Limits: map[string]int{"cpu": 2, "mem": 512},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = map[string]interface{}{
+		"Scores@count":  2.0,
+		"Scores@any":    true,
+		"Scores@all":    true,
+		"Scores@truthy": 2.0,
+		"Scores@not":    true,
+		"Matrix@all":    true,
+		"Matrix@any":    true,
+		"Attrs@":        1.0,
+		"Empty@any":     false,
+		"Empty@all":     true,
+		"Name@":         nil,
+		"CSV@":          true,
+		"Rows@header":   true,
+		"Rows@body":     1.0,
+		"Limits@":       true,
+	}
+	for selector, want := range cases {
+		if got := tagExpr.Eval(selector); !reflect.DeepEqual(got, want) {
+			t.Fatalf("selector: %q, got: %v, want: %v", selector, got, want)
+		}
+	}
+}
+
+func TestHashFunc(t *testing.T) {
+	type T struct {
+		Payload  []byte `tagexpr:"sha256($)==(Checksum)$"`
+		Checksum string
+	}
+	vm := New("tagexpr")
+	tagExpr, err := vm.Run(&T{
+		Payload:  []byte("abc"),
+		Checksum: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tagExpr.EvalBool("Payload@") {
+		t.Fatal("Payload@: want true")
+	}
+}
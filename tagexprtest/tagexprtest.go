@@ -0,0 +1,165 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tagexprtest provides the helpers for pinning the behavior of the struct tag expression rules in unit tests.
+package tagexprtest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	tagexpr "github.com/bytedance/go-tagexpr"
+)
+
+// DefaultTagName the tag name of the package-level helpers, the same as the validator examples
+const DefaultTagName = "vd"
+
+var update = flag.Bool("tagexprtest.update", false, "update the golden files of tagexprtest")
+
+// Asserter rule asserter
+type Asserter struct {
+	vm *tagexpr.VM
+}
+
+// New creates a rule asserter that uses @tagName as the tag name.
+func New(tagName string) *Asserter {
+	return &Asserter{
+		vm: tagexpr.New(tagName),
+	}
+}
+
+var defaultAsserter = New(DefaultTagName)
+
+// AssertValid asserts that all the rules of the struct pass, by the default tag name.
+func AssertValid(t testing.TB, structPtr interface{}) {
+	t.Helper()
+	defaultAsserter.AssertValid(t, structPtr)
+}
+
+// AssertFails asserts that the failed rules of the struct are exactly the selectors, by the default tag name.
+func AssertFails(t testing.TB, structPtr interface{}, selectors ...string) {
+	t.Helper()
+	defaultAsserter.AssertFails(t, structPtr, selectors...)
+}
+
+// AssertGolden asserts that the dump of the struct is the same as the golden file, by the default tag name.
+func AssertGolden(t testing.TB, structPtr interface{}, goldenFile string) {
+	t.Helper()
+	defaultAsserter.AssertGolden(t, structPtr, goldenFile)
+}
+
+// AssertValid asserts that all the rules of the struct pass.
+// NOTE:
+//  The rules are the expressions whose selectors end with @, such as Age@,
+//  the same as the ones checked by the validator.
+func (a *Asserter) AssertValid(t testing.TB, structPtr interface{}) {
+	t.Helper()
+	a.AssertFails(t, structPtr)
+}
+
+// AssertFails asserts that the failed rules of the struct are exactly the selectors, such as Age@.
+func (a *Asserter) AssertFails(t testing.TB, structPtr interface{}, selectors ...string) {
+	t.Helper()
+	failed, err := a.Failed(structPtr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]string(nil), selectors...)
+	sort.Strings(want)
+	sort.Strings(failed)
+	if strings.Join(failed, ",") != strings.Join(want, ",") {
+		t.Errorf("failed rules: got [%s], want [%s]", strings.Join(failed, ", "), strings.Join(want, ", "))
+	}
+}
+
+// Failed returns the selectors of the failed rules of the struct in the registration order.
+func (a *Asserter) Failed(structPtr interface{}) ([]string, error) {
+	te, err := a.vm.Run(structPtr)
+	if err != nil {
+		return nil, err
+	}
+	var failed []string
+	te.Range(func(selector string, eval func() interface{}) bool {
+		if strings.HasSuffix(selector, "@") {
+			if r, _ := eval().(bool); !r {
+				failed = append(failed, selector)
+			}
+		}
+		return true
+	})
+	return failed, nil
+}
+
+// Dump returns the results of all the expressions of the struct, one selector per line in the registration order,
+// such as Age@ = true.
+func (a *Asserter) Dump(structPtr interface{}) ([]byte, error) {
+	te, err := a.vm.Run(structPtr)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	te.Range(func(selector string, eval func() interface{}) bool {
+		fmt.Fprintf(&buf, "%s = %s\n", selector, formatValue(eval()))
+		return true
+	})
+	return buf.Bytes(), nil
+}
+
+func formatValue(v interface{}) string {
+	switch r := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(r)
+	case float64:
+		return strconv.FormatFloat(r, 'g', -1, 64)
+	}
+	return fmt.Sprintf("%#v", v)
+}
+
+// AssertGolden asserts that the dump of the struct is the same as the golden file,
+// such as testdata/user.golden.
+// NOTE:
+//  Run the tests with -tagexprtest.update to create or update the golden files.
+func (a *Asserter) AssertGolden(t testing.TB, structPtr interface{}, goldenFile string) {
+	t.Helper()
+	got, err := a.Dump(structPtr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err = os.MkdirAll(filepath.Dir(goldenFile), 0755); err == nil {
+			err = ioutil.WriteFile(goldenFile, got, 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("%s, run with -tagexprtest.update to create it", err.Error())
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("dump differs from %s:\ngot:\n%swant:\n%s", goldenFile, got, want)
+	}
+}
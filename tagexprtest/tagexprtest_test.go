@@ -0,0 +1,85 @@
+package tagexprtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type user struct {
+	Name string `vd:"{@:len($)>=3}{msg:'name too short'}"`
+	Age  int    `vd:"$>=18"`
+}
+
+// recorder records the failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatal(args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+	r.fatal = true
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+	r.fatal = true
+}
+
+func TestAssert(t *testing.T) {
+	AssertValid(t, &user{Name: "Alice", Age: 18})
+	AssertFails(t, &user{Name: "Al", Age: 18}, "Name@")
+	AssertFails(t, &user{Name: "Al", Age: 1}, "Age@", "Name@")
+
+	r := &recorder{TB: t}
+	AssertValid(r, &user{Name: "Al", Age: 18})
+	AssertFails(r, &user{Name: "Alice", Age: 1}, "Name@")
+	want := []string{
+		"failed rules: got [Name@], want []",
+		"failed rules: got [Age@], want [Name@]",
+	}
+	if strings.Join(r.errors, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got %q, want %q", r.errors, want)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tagexprtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	golden := filepath.Join(dir, "user.golden")
+
+	r := &recorder{TB: t}
+	AssertGolden(r, &user{Name: "Al", Age: 20}, golden)
+	if !r.fatal {
+		t.Fatal("want fatal for the missing golden file")
+	}
+
+	*update = true
+	AssertGolden(t, &user{Name: "Al", Age: 20}, golden)
+	*update = false
+	b, _ := ioutil.ReadFile(golden)
+	if want := "Name@ = false\nName@msg = \"name too short\"\nAge@ = true\n"; string(b) != want {
+		t.Fatalf("got %q, want %q", b, want)
+	}
+	AssertGolden(t, &user{Name: "Al", Age: 20}, golden)
+
+	r = &recorder{TB: t}
+	AssertGolden(r, &user{Name: "Alice", Age: 20}, golden)
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "Name@ = true") {
+		t.Fatalf("got %q", r.errors)
+	}
+}
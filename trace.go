@@ -0,0 +1,56 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+// TraceStep the value of a sub-expression
+type TraceStep struct {
+	Depth int    // nesting depth, 0 is the whole expression
+	Expr  string // source form of the sub-expression
+	Value interface{}
+}
+
+// Trace evaluates the expression and its sub-expressions by the selector expression,
+// returns the steps in pre-order, the literals and the redundant parentheses are omitted.
+// NOTE:
+//  The sub-expressions are evaluated independently, even if they are short-circuited;
+//  The predicates of count, any and all are not expanded, since #k and #v are only bound in them.
+func (t *TagExpr) Trace(selector string) ([]TraceStep, bool) {
+	e, ok := t.s.getExpr(selector)
+	if !ok {
+		return nil, false
+	}
+	var steps []TraceStep
+	var walk func(n ExprNode, depth int)
+	walk = func(n ExprNode, depth int) {
+		switch n.(type) {
+		case nil, *boolExprNode, *stringExprNode, *digitalExprNode, *nilExprNode:
+			return
+		}
+		steps = append(steps, TraceStep{
+			Depth: depth,
+			Expr:  nodeString(n),
+			Value: n.Run(e.field, t),
+		})
+		if r, ok := n.(*diveFnExprNode); ok {
+			walk(unwrapGroup(r.collection), depth+1)
+			return
+		}
+		for _, sub := range subNodes(n) {
+			walk(unwrapGroup(sub), depth+1)
+		}
+	}
+	walk(unwrapGroup(e.expr.expr.RightOperand()), 0)
+	return steps, true
+}
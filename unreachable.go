@@ -0,0 +1,68 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+// Unreachable the value of the selector whose path cannot be reached,
+// if the vm is set by SetUnreachable(true).
+type Unreachable struct{}
+
+// IsUnreachable reports whether v is the value of the unreachable path.
+func IsUnreachable(v interface{}) bool {
+	_, ok := v.(Unreachable)
+	return ok
+}
+
+// SetUnreachable sets whether the selector returns Unreachable{} instead of nil
+// when its path cannot be reached, to distinguish it from the nil or zero value.
+// NOTE:
+//  The path is unreachable if it passes through a nil pointer, an out-of-range index, a missing map key
+//  or an invalid subscript, such as (P.X)$ with the nil P, (S)$[9] with len(S)==2 and (M)$['k'] without the key k;
+//  The nil field itself is reachable, such as (P)$ with the nil P;
+//  The nil-safe subscripts always yield nil when they cannot be reached, such as (P)$?.X and (S)$?[9];
+//  Unreachable{} is not equal to nil or any other value;
+//  It should be called before the vm is used.
+func (vm *VM) SetUnreachable(enable bool) *VM {
+	vm.unreachable = enable
+	return vm
+}
+
+// EvalWithUnreached is the same as EvalWithError, and also reports whether the evaluation
+// passed through an unreachable path, such as (P.X)$ with the nil P.
+// NOTE:
+//  It works whether the vm is set by SetUnreachable or not;
+//  It costs the allocations of the evaluation state, which EvalWithError does not;
+//  The paths probed by exists and missing and the nil-safe subscripts are not counted.
+func (t *TagExpr) EvalWithUnreached(selector string) (v interface{}, unreached bool, err error) {
+	e, ok := t.s.getExpr(selector)
+	if !ok {
+		return nil, false, newKindError(ErrUnknownSelector, "unknown selector: %s", selector)
+	}
+	v, err = t.withFrame(&evalFrame{unreached: &unreached}).safeRun(e)
+	if err == nil {
+		err = e.expr.checkResultType(selector, v)
+	}
+	return v, unreached, err
+}
+
+// unreachableValue returns the value of the unreachable path.
+func (t *TagExpr) unreachableValue() interface{} {
+	if t.frame != nil && t.frame.unreached != nil {
+		*t.frame.unreached = true
+	}
+	if t.getVM().unreachable {
+		return Unreachable{}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagexpr
+
+import (
+	"log"
+	"reflect"
+)
+
+// UnsupportedPolicy the policy of the func, chan and unsafe.Pointer fields, whose values cannot be evaluated,
+// and of the rules of the compatible tag that cannot be translated, see SetCompatTag
+type UnsupportedPolicy int
+
+// The policies of the unsupported fields
+const (
+	// UnsupportedSkip the value is nil or the rule is left out silently, the default
+	UnsupportedSkip UnsupportedPolicy = iota
+	// UnsupportedWarn the value is nil or the rule is left out, and the field is reported to the warn function when registering
+	UnsupportedWarn
+	// UnsupportedError the struct is not registered, vm.Run returns ErrTypeMismatch, or the error of the rule
+	UnsupportedError
+)
+
+// SetUnsupportedPolicy sets the policy of the func, chan and unsafe.Pointer fields,
+// and of the rules of the compatible tag that cannot be translated, such as validate:"required,dive",
+// warn receives the struct name and the field for UnsupportedWarn, log.Printf is used if it is nil.
+// NOTE:
+//  The fields tagged with -, such as `te:"-"`, are not checked;
+//  The Reason of the field of the rule is such as validate rule dive is left out: unsupported keyword: dive;
+//  It should be called before the vm is used.
+func (vm *VM) SetUnsupportedPolicy(policy UnsupportedPolicy, warn func(structName string, field IgnoredField)) *VM {
+	vm.unsupported = policy
+	vm.unsupportedWarn = warn
+	return vm
+}
+
+// checkUnsupported applies the policy to the field of the kind, which has no value getter.
+func (vm *VM) checkUnsupported(s *Struct, field *Field, kind reflect.Kind) error {
+	switch kind {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+	default:
+		return nil
+	}
+	switch vm.unsupported {
+	case UnsupportedWarn:
+		f := IgnoredField{Field: field.Name, Type: field.Type, Reason: "unsupported kind " + kind.String()}
+		if vm.unsupportedWarn != nil {
+			vm.unsupportedWarn(s.name, f)
+		} else {
+			log.Printf("tagexpr: %s.%s is always nil: %s", s.name, f.Field, f.Reason)
+		}
+	case UnsupportedError:
+		return newKindError(ErrTypeMismatch, "%s.%s: unsupported kind %s", s.name, field.Name, kind)
+	}
+	return nil
+}
+
+// checkUnsupportedRule applies the policy to the rule of the compatible tag of the field of s, which cannot be translated,
+// returns nil if the rule is left out.
+func (vm *VM) checkUnsupportedRule(s *Struct, structField reflect.StructField, rule string, err error) error {
+	switch vm.unsupported {
+	case UnsupportedWarn:
+		f := IgnoredField{
+			Field:  structField.Name,
+			Type:   structField.Type,
+			Reason: vm.compatTagName + " rule " + rule + " is left out: " + err.Error(),
+		}
+		if vm.unsupportedWarn != nil {
+			vm.unsupportedWarn(s.name, f)
+		} else {
+			log.Printf("tagexpr: %s.%s: %s", s.name, f.Field, f.Reason)
+		}
+	case UnsupportedError:
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Plural categories of the messages, as CLDR
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+// Catalog the messages of the rule codes in the locales, such as:
+//  {"USR_NAME_LEN": {"one": "{field} must be at most {count} character", "other": "{field} must be at most {count} characters"}}
+type Catalog struct {
+	messages map[string]map[string]map[string]string // locale -> code -> plural category -> message
+	plurals  map[string]func(n float64) string       // language -> plural rule
+	formats  map[string]func(data []byte, v interface{}) error
+}
+
+// NewCatalog creates an empty message catalog, which loads the JSON files by default.
+func NewCatalog() *Catalog {
+	return &Catalog{
+		messages: make(map[string]map[string]map[string]string),
+		plurals:  make(map[string]func(float64) string),
+		formats:  map[string]func([]byte, interface{}) error{".json": json.Unmarshal},
+	}
+}
+
+// RegisterFormat registers the decoder of the catalog files with the extension, such as:
+//  c.RegisterFormat(".toml", toml.Unmarshal)
+func (c *Catalog) RegisterFormat(ext string, unmarshal func(data []byte, v interface{}) error) *Catalog {
+	c.formats[ext] = unmarshal
+	return c
+}
+
+// SetPluralRule sets the plural rule of the language, which returns the plural category of the count.
+// NOTE:
+//  The built-in rules: other only for zh, ja, ko, vi, th, id and ms, one for 0 and 1 in fr, one for 1 in the others;
+//  The languages with few or many, such as ru and pl, should be set by this.
+func (c *Catalog) SetPluralRule(lang string, rule func(n float64) string) *Catalog {
+	c.plurals[strings.ToLower(lang)] = rule
+	return c
+}
+
+// LoadDir loads the catalog files of the registered formats in dir, whose names are the locales, such as zh-CN.json.
+func (c *Catalog) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if _, ok := c.formats[filepath.Ext(f.Name())]; ok && !f.IsDir() {
+			if err = c.LoadFile(filepath.Join(dir, f.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFile loads the catalog file, whose name is the locale, such as zh-CN.json.
+func (c *Catalog) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	ext := filepath.Ext(path)
+	return c.Load(strings.TrimSuffix(filepath.Base(path), ext), ext, data)
+}
+
+// Load loads the messages of the locale, data is in the format of the extension, such as .json.
+// NOTE:
+//  The message is a string, or an object of the plural categories such as {"one": "...", "other": "..."};
+//  The messages override the loaded ones of the same locale and code.
+func (c *Catalog) Load(locale, ext string, data []byte) error {
+	unmarshal, ok := c.formats[ext]
+	if !ok {
+		return fmt.Errorf("unregistered catalog format: %q", ext)
+	}
+	var raw map[string]interface{}
+	if err := unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("catalog %s: %v", locale, err)
+	}
+	for code, v := range raw {
+		switch m := v.(type) {
+		case string:
+			c.Set(locale, code, map[string]string{PluralOther: m})
+		case map[string]interface{}:
+			forms := make(map[string]string, len(m))
+			for category, s := range m {
+				if forms[category], ok = s.(string); !ok {
+					return fmt.Errorf("catalog %s: %s.%s is not string", locale, code, category)
+				}
+			}
+			c.Set(locale, code, forms)
+		default:
+			return fmt.Errorf("catalog %s: %s is neither string nor plural forms", locale, code)
+		}
+	}
+	return nil
+}
+
+// Set sets the message of the code in the locale, forms is the messages of the plural categories.
+// NOTE:
+//  It is not concurrency safe, should be called during initialization.
+func (c *Catalog) Set(locale, code string, forms map[string]string) *Catalog {
+	locale = normalizeLocale(locale)
+	m, ok := c.messages[locale]
+	if !ok {
+		m = make(map[string]map[string]string)
+		c.messages[locale] = m
+	}
+	m[code] = forms
+	return c
+}
+
+// Translate returns the message of the validation error in the locale, such as zh-CN, en.
+// NOTE:
+//  The message is looked up by the code of the rule, in the locale, then in its language such as zh for zh-CN;
+//  The placeholders {field}, {count} and {value} are replaced by the label or the field selector,
+//  the count of the rule such as {count:8}, and the field value;
+//  err.Error() is returned if err is not *Error, or there is no message of the code.
+func (c *Catalog) Translate(err error, locale string) string {
+	e, ok := err.(*Error)
+	if !ok || e.Code == "" {
+		return err.Error()
+	}
+	msg, ok := c.lookup(normalizeLocale(locale), e.Code, e.Count)
+	if !ok {
+		return e.Error()
+	}
+	field := e.Label
+	if field == "" {
+		field = e.FieldSelector
+	}
+	return strings.NewReplacer(
+		"{field}", field,
+		"{count}", strconv.FormatFloat(e.Count, 'f', -1, 64),
+		"{value}", fmt.Sprint(e.Value),
+	).Replace(msg)
+}
+
+func (c *Catalog) lookup(locale, code string, count float64) (string, bool) {
+	for {
+		if forms, ok := c.messages[locale][code]; ok {
+			lang := locale
+			if i := strings.IndexByte(locale, '-'); i > 0 {
+				lang = locale[:i]
+			}
+			if msg, ok := forms[c.plural(lang, count)]; ok {
+				return msg, true
+			}
+			msg, ok := forms[PluralOther]
+			return msg, ok
+		}
+		i := strings.LastIndexByte(locale, '-')
+		if i < 0 {
+			return "", false
+		}
+		locale = locale[:i]
+	}
+}
+
+func (c *Catalog) plural(lang string, n float64) string {
+	if rule, ok := c.plurals[lang]; ok {
+		return rule(n)
+	}
+	switch lang {
+	case "zh", "ja", "ko", "vi", "th", "id", "ms":
+		return PluralOther
+	case "fr":
+		if n >= 0 && n < 2 {
+			return PluralOne
+		}
+		return PluralOther
+	}
+	if n == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+// normalizeLocale returns the lowercase locale with the hyphens, such as zh_CN to zh-cn.
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.Replace(locale, "_", "-", -1))
+}
@@ -1,8 +1,12 @@
 package validator_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 
+	tagexpr "github.com/bytedance/go-tagexpr"
 	"github.com/bytedance/go-tagexpr/validator"
 )
 
@@ -70,3 +74,269 @@ func Example() {
 	// Invalid parameter: e
 	// {"succ":false, "error":"invalid parameter: f.g"}
 }
+
+func ExampleValidator_SetCostOrder() {
+	var vd = validator.New("vd").SetCostOrder(true)
+
+	type G struct {
+		Email string `vd:"regexp('^\\w+@\\w+\\.\\w+$') && len($)<=64"`
+		Code  string `vd:"{@:len($)==6}{priority:10}"`
+	}
+	g := &G{Email: "bad", Code: "abc"}
+	fmt.Println(vd.Validate(g))
+
+	// Output:
+	// Invalid parameter: Code
+}
+
+func ExampleValidator_SetCoverage() {
+	var vd = validator.New("vd").SetCoverage(true)
+
+	type H struct {
+		Age  int    `vd:"$>=18&&$<=130"`
+		Role string `vd:"$=='admin'||$=='user'"`
+	}
+	vd.Validate(&H{Age: 20, Role: "admin"})
+	vd.Validate(&H{Age: 10, Role: "admin"})
+	vd.WriteCoverage(os.Stdout)
+
+	// Output:
+	// validator_test.H	Age@	2	$ >= 18 && $ <= 130
+	// validator_test.H	Role@	1	$ == 'admin' || $ == 'user'
+	// 	|| short=1 full=0	$ == 'admin' || $ == 'user'
+	// expressions: 2/2 evaluated, branches: 1/2 covered
+}
+
+func ExampleValidator_SetCodeStatus() {
+	var vd = validator.New("vd").SetCodeStatus("USR_NAME_LEN", 422)
+
+	type User struct {
+		Name string `vd:"{@:len($)<=8}{msg:'name is too long'}{code:'USR_NAME_LEN'}"`
+		Age  int    `vd:"$>0"`
+	}
+	err := vd.Validate(&User{Name: "Bartholomew", Age: 1})
+	fmt.Println(err.(*validator.Error).Code, vd.HTTPStatus(err))
+	err = vd.Validate(&User{Name: "Bart"})
+	fmt.Println(err.(*validator.Error).Code == "", vd.HTTPStatus(err), errors.Is(err, tagexpr.ErrValidation))
+	fmt.Println(vd.HTTPStatus(vd.Validate(&User{Name: "Bart", Age: 1})))
+
+	// Output:
+	// USR_NAME_LEN 422
+	// true 400 true
+	// 200
+}
+
+func ExampleError_MarshalJSON() {
+	var vd = validator.New("vd")
+
+	type User struct {
+		Name     string `vd:"{@:len($)<=8}{msg:'name is too long'}{code:'USR_NAME_LEN'}"`
+		Password string `vd:"{@:len($)>=8}{msg:'password is too short'}{redact:true}"`
+	}
+	b, _ := json.Marshal(vd.Validate(&User{Name: "Bartholomew"}))
+	fmt.Println(string(b))
+	b, _ = json.Marshal(vd.Validate(&User{Name: "Bart", Password: "hunter2"}))
+	fmt.Println(string(b))
+
+	// Output:
+	// [{"field":"Name","rule":"len($) \u003c= 8","message":"name is too long","value":"Bartholomew","code":"USR_NAME_LEN"}]
+	// [{"field":"Password","rule":"len($) \u003e= 8","message":"password is too short","value":"***"}]
+}
+
+func ExampleValidator_Validate_label() {
+	var vd = validator.New("vd")
+
+	type Address struct {
+		City string `vd:"{@:$!=''}{label:'城市'}"`
+		Zip  string `vd:"$!=''"`
+	}
+	type User struct {
+		Name string   `vd:"{@:$!=''}{label:'用户名'}"`
+		Addr *Address `vd:"{label:'地址'}"`
+	}
+	err := vd.Validate(&User{Addr: &Address{}})
+	fmt.Println(err, err.(*validator.Error).FieldSelector)
+	err = vd.Validate(&User{Name: "a", Addr: &Address{}})
+	fmt.Println(err, err.(*validator.Error).FieldSelector)
+	err = vd.Validate(&User{Name: "a", Addr: &Address{City: "b"}})
+	b, _ := json.Marshal(err)
+	fmt.Println(string(b))
+
+	// Output:
+	// Invalid parameter: 用户名 Name
+	// Invalid parameter: 地址.城市 Addr.City
+	// [{"field":"Addr.Zip","label":"地址.Zip","rule":"$ != ''","message":"Invalid parameter: 地址.Zip","value":""}]
+}
+
+func ExampleCatalog() {
+	var vd = validator.New("vd")
+	var catalog = validator.NewCatalog()
+	if err := catalog.LoadDir("testdata/locales"); err != nil {
+		panic(err)
+	}
+
+	type User struct {
+		Name string `vd:"{@:len($)<=(Max)$}{code:'USR_NAME_LEN'}{count:(Max)$}{label:'用户名'}"`
+		Max  int
+		City string `vd:"{@:$!=''}{code:'REQUIRED'}"`
+	}
+	err := vd.Validate(&User{Name: "Bartholomew", Max: 1})
+	fmt.Println(catalog.Translate(err, "en-US"))
+	fmt.Println(catalog.Translate(err, "zh-CN"))
+	err = vd.Validate(&User{Name: "Bartholomew", Max: 8})
+	fmt.Println(catalog.Translate(err, "en"))
+	err = vd.Validate(&User{Name: "Bart", Max: 8})
+	fmt.Println(catalog.Translate(err, "zh_CN"))
+	fmt.Println(catalog.Translate(err, "fr"))
+
+	// Output:
+	// 用户名 must be at most 1 character
+	// 用户名最多1个字符
+	// 用户名 must be at most 8 characters
+	// City不能为空
+	// Invalid parameter: City
+}
+
+func ExampleValidator_ValidateOpts_severities() {
+	var vd = validator.New("vd")
+
+	type Order struct {
+		Note  string `vd:"{@:len($)<=10}{code:'NOTE_LEN'}{severity:'warning'}{msg:'note is too long'}"`
+		Email string `vd:"{@:$!=''}{code:'EMAIL_REQUIRED'}{msg:'email is required'}"`
+	}
+	o := &Order{Note: "please deliver after 6pm"}
+
+	// the default severities
+	warnings, err := vd.ValidateOpts(o, validator.Options{})
+	fmt.Println(warnings, err)
+
+	// lenient for the internal tools
+	warnings, err = vd.ValidateOpts(o, validator.Options{
+		Severities: validator.Severities{"EMAIL_REQUIRED": validator.SeverityWarning},
+	})
+	fmt.Println(warnings, err)
+
+	// strict for the partners
+	warnings, err = vd.ValidateOpts(o, validator.Options{
+		Severities: validator.Severities{"NOTE_LEN": validator.SeverityError},
+	})
+	fmt.Println(warnings, err)
+
+	// Output:
+	// [note is too long] email is required
+	// [note is too long email is required] <nil>
+	// [] note is too long
+}
+
+func ExampleValidator_ValidateOpts_params() {
+	var vd = validator.New("vd").SetParams(tagexpr.Params{"max_items": 2})
+
+	type Cart struct {
+		Items []string `vd:"{@:len($)<=%max_items%}{msg:sprintf('at most %v items',%max_items%)}"`
+	}
+	cart := &Cart{Items: []string{"a", "b", "c"}}
+	fmt.Println(vd.Validate(cart))
+	_, err := vd.ValidateOpts(cart, validator.Options{Params: tagexpr.Params{"max_items": 10}})
+	fmt.Println(err)
+
+	// Output:
+	// at most 2 items
+	// <nil>
+}
+
+func ExampleValidator_ValidateOpts_version() {
+	var vd = validator.New("vd")
+
+	type Signup struct {
+		Phone string `vd:"{@:$!=''}{until:'2'}{msg:'phone is required'}"`
+		Email string `vd:"{@:$!=''}{since:'2'}{msg:'email is required'}"`
+		Name  string `vd:"{@:len($)<=16}{since:'1.10'}{msg:'name is too long'}"`
+	}
+	s := &Signup{Phone: "10086", Name: "Bartholomew the Great"}
+	for _, c := range []struct {
+		s       *Signup
+		version string
+	}{
+		{s, "v1.2"},
+		{s, "v1.10"},
+		{&Signup{Email: "a@b.c"}, "v2.0"},
+	} {
+		_, err := vd.ValidateOpts(c.s, validator.Options{Version: c.version})
+		fmt.Println(err)
+	}
+	fmt.Println(vd.Validate(&Signup{Email: "a@b.c"}))
+
+	// Output:
+	// <nil>
+	// name is too long
+	// <nil>
+	// phone is required
+}
+
+func ExampleValidator_SetFailMode() {
+	type User struct {
+		Admin *bool `vd:"$"`
+	}
+	u := &User{}
+	fmt.Println(validator.New("vd").Validate(u))
+	fmt.Println(validator.New("vd").SetFailMode(validator.FailClosed).Validate(u))
+	fmt.Println(validator.New("vd").SetFailMode(validator.FailOpen).Validate(u))
+
+	// Output:
+	// Invalid parameter: Admin
+	// Admin@: the result <nil> is not bool
+	// <nil>
+}
+
+func ExampleValidator_SetUnreachablePolicy() {
+	type Addr struct {
+		Zip string
+	}
+	type User struct {
+		Addr *Addr `vd:"(Addr.Zip)$!='00000'"`
+	}
+	u := &User{}
+	fmt.Println(validator.New("vd").Validate(u))
+	fmt.Println(validator.New("vd").SetUnreachablePolicy(validator.UnreachableFail).Validate(u))
+	err := validator.New("vd").SetUnreachablePolicy(validator.UnreachableError).Validate(u)
+	fmt.Println(err, errors.Is(err, tagexpr.ErrUnreachable))
+
+	// Output:
+	// <nil>
+	// Invalid parameter: Addr
+	// Addr@: unreachable true
+}
+
+func ExampleValidator_Validate_sanitize() {
+	var vd = validator.New("vd")
+
+	type User struct {
+		Email string `vd:"{@:regexp('^[a-z]+@[a-z]+\\.[a-z]+$')}{san:lower(trim($))}"`
+		Score int    `vd:"{san:clamp($,0,100)}"`
+	}
+	u := &User{Email: " Alice@Example.COM ", Score: 120}
+	fmt.Println(vd.Validate(u), u.Email, u.Score)
+
+	// Output:
+	// <nil> alice@example.com 100
+}
+
+func ExampleValidator_Report() {
+	var vd = validator.New("vd")
+
+	type User struct {
+		Name string `vd:"{@:len($)<=8}{msg:'name is too long'}{code:'USR_NAME_LEN'}"`
+		Age  int    `vd:"$>0"`
+		Note string `vd:"{@:len($)<=4}{severity:'warning'}"`
+		Zip  string `vd:"{@:$!=''}{since:'2'}"`
+	}
+	results, _ := vd.Report(&User{Name: "Bartholomew", Note: "fragile"}, validator.Options{Version: "v1"})
+	for _, r := range results {
+		fmt.Printf("%s %q pass=%v severity=%v value=%v msg=%q code=%q\n", r.FieldSelector, r.Rule, r.Pass, r.Severity, r.Value, r.Msg, r.Code)
+	}
+
+	// Output:
+	// Name "len($) <= 8" pass=false severity=error value=Bartholomew msg="name is too long" code="USR_NAME_LEN"
+	// Age "$ > 0" pass=false severity=error value=0 msg="Invalid parameter: Age" code=""
+	// Note "len($) <= 4" pass=false severity=warning value=fragile msg="Invalid parameter: Note" code=""
+}
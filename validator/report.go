@@ -0,0 +1,65 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"reflect"
+)
+
+// RuleResult the result of a rule in the report
+type RuleResult struct {
+	FieldSelector string
+	Rule          string      // simplified source form of the rule
+	Value         interface{} // value of the field when evaluating, nil if it is not reachable, masked if it has the redact expression
+	Pass          bool
+	Severity      Severity // severity of the failed rule, the failed warning rule does not fail Validate
+	Msg           string   // message of the failed rule, the same as the text of *Error
+	Code          string   // error code of the failed rule
+	Err           error    // the error returned by Validate for the rule, such as *tagexpr.EvalError by FailClosed
+}
+
+// Report evaluates all the rules of structPtr in the evaluation order with the options like ValidateOpts,
+// and returns their results, for the debugging such as "why was this rejected?".
+// NOTE:
+//  Unlike ValidateOpts, it does not stop at the first failed rule or error, nor call the error factory;
+//  The rules not effective in opts.Version are not reported;
+//  The san expressions are not evaluated, structPtr is not changed.
+func (v *Validator) Report(structPtr interface{}, opts Options) ([]RuleResult, error) {
+	expr, err := v.vm.Run(structPtr)
+	if err != nil {
+		return nil, err
+	}
+	expr.SetParams(opts.Params)
+	var results []RuleResult
+	v.evaluate(reflect.TypeOf(structPtr), expr, opts, func(selector string, pass bool, severity Severity, err error) bool {
+		r := RuleResult{FieldSelector: selector[:len(selector)-1], Pass: pass, Severity: severity, Err: err}
+		if rule, ok := expr.Expr(selector); ok {
+			r.Rule = rule.String()
+		}
+		r.Value = fieldValue(expr, selector, r.FieldSelector)
+		if !pass {
+			e := Error{
+				FieldSelector: r.FieldSelector,
+				Msg:           expr.EvalString(selector + errMsgExprName),
+				Label:         labelPath(expr, r.FieldSelector),
+			}
+			r.Msg = e.Error()
+			r.Code = expr.EvalString(selector + codeExprName)
+		}
+		results = append(results, r)
+		return true
+	})
+	return results, nil
+}
@@ -16,16 +16,39 @@
 package validator
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
 	tagexpr "github.com/bytedance/go-tagexpr"
 )
 
 const matchExprName = "@"
 const errMsgExprName = "msg"
+const priorityExprName = "priority"
+const codeExprName = "code"
+const labelExprName = "label"
+const countExprName = "count"
+const severityExprName = "severity"
+const sinceExprName = "since"
+const untilExprName = "until"
+const redactExprName = "redact"
 
 // Validator struct fields validator
 type Validator struct {
-	vm         *tagexpr.VM
-	errFactory func(fieldSelector, msg string) error
+	vm          *tagexpr.VM
+	errFactory  func(fieldSelector, msg string) error
+	costOrder   bool
+	failMode    FailMode
+	unreachable UnreachablePolicy
+	orders      sync.Map       // struct type -> []string, the match selectors in evaluation order
+	statuses    map[string]int // error code -> HTTP status
 }
 
 // New creates a struct fields validator.
@@ -37,36 +60,433 @@ func New(tagName string) *Validator {
 	return v
 }
 
+// VM returns the tag expression VM of the validator, such as for the field metadata of the validated struct types.
+func (v *Validator) VM() *tagexpr.VM {
+	return v.vm
+}
+
 // Validate validates whether the fields of structPtr is valid.
+// NOTE:
+//  The san expressions, such as {san:lower(trim($))}, are evaluated and set to the fields first;
+//  The failures of the warning rules, such as {severity:'warning'}, are ignored.
 func (v *Validator) Validate(structPtr interface{}) error {
+	_, err := v.validate(structPtr, Options{})
+	return err
+}
+
+// Severity the severity of the rule
+type Severity int
+
+// The severities of the rules
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+// String returns the name of the severity, error or warning.
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Severities overrides the severities of the rules by their codes at call time,
+// such as Severities{"USR_NAME_LEN": SeverityError} for the strict mode of the partners.
+type Severities map[string]Severity
+
+// Options the options of a validation call
+type Options struct {
+	// Severities overrides the severities of the rule codes,
+	// the failures of the warning rules are returned as warnings
+	Severities Severities
+	// Params the named parameters of the call, such as the limits of the tenant for len($)<=%max_items%,
+	// which take precedence over the ones set by SetParams
+	Params tagexpr.Params
+	// Version the API version, only the rules effective in it are evaluated if it is not empty
+	Version string
+}
+
+// ValidateOpts validates like Validate with the options of the call,
+// the failures of the warning rules are returned as warnings, in the evaluation order.
+// The API version lets v1 and v2 of an endpoint share one struct, such as:
+//  Phone string `vd:"{@:$!=''}{until:'2'}"` // deprecated since v2
+//  Email string `vd:"{@:$!=''}{since:'2'}"`
+// NOTE:
+//  The severity of the rule is error by default, or set by {severity:'warning'};
+//  The evaluation stops at the first failed error rule, the warnings after it are not reported;
+//  The rule is effective in [since, until), without since or until it has no lower or upper bound;
+//  The versions are compared by the dot-separated numbers, such as 1.2 < 1.10, the optional v prefix is ignored.
+func (v *Validator) ValidateOpts(structPtr interface{}, opts Options) (warnings []error, err error) {
+	return v.validate(structPtr, opts)
+}
+
+func (v *Validator) validate(structPtr interface{}, opts Options) (warnings []error, err error) {
 	expr, err := v.vm.Run(structPtr)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var errSelector string
-	expr.Range(func(selector string, eval func() interface{}) bool {
-		if !isMatchSelector(selector) {
+	expr.SetParams(opts.Params)
+	if err = expr.Sanitize(); err != nil {
+		return nil, err
+	}
+	v.evaluate(reflect.TypeOf(structPtr), expr, opts, func(selector string, pass bool, severity Severity, e error) bool {
+		switch {
+		case e != nil:
+			err = e
+			return false
+		case pass:
 			return true
+		case severity != SeverityWarning:
+			err = v.newError(expr, selector)
+			return false
 		}
-		valid, _ := eval().(bool)
-		if !valid {
-			errSelector = selector
-		}
-		return valid
+		warnings = append(warnings, v.newError(expr, selector))
+		return true
 	})
-	if errSelector == "" {
-		return nil
+	return warnings, err
+}
+
+// evaluate evaluates the rules effective in opts.Version in the evaluation order,
+// with the fail mode and the unreachable policy applied, and calls fn with the result of each rule until it returns false.
+// NOTE:
+//  The severity is only resolved for the failed rule;
+//  The error is the one that stops Validate, such as *tagexpr.EvalError by FailClosed.
+func (v *Validator) evaluate(structType reflect.Type, expr *tagexpr.TagExpr, opts Options, fn func(selector string, pass bool, severity Severity, err error) bool) {
+	for _, selector := range v.getOrder(structType, expr) {
+		if opts.Version != "" && !inVersion(expr, selector, opts.Version) {
+			continue
+		}
+		var pass, unreached bool
+		var err error
+		if v.unreachable == UnreachableEvaluate {
+			pass, err = expr.EvalBoolStrict(selector)
+		} else {
+			var r interface{}
+			r, unreached, err = expr.EvalWithUnreached(selector)
+			var ok bool
+			if pass, ok = r.(bool); err == nil && !ok {
+				err = &tagexpr.NotBoolError{Selector: selector, Value: r}
+			}
+		}
+		if unreached {
+			switch v.unreachable {
+			case UnreachablePass:
+				pass, err = true, nil
+			case UnreachableFail:
+				pass, err = false, nil
+			case UnreachableError:
+				pass, err = false, fmt.Errorf("%s: %w", selector, tagexpr.ErrUnreachable)
+			}
+		} else if err != nil {
+			_, notBool := err.(*tagexpr.NotBoolError)
+			switch {
+			case v.failMode == FailOpen:
+				pass, err = true, nil
+			case v.failMode == FailInvalid && notBool:
+				err = nil
+			}
+		}
+		severity := SeverityError
+		if !pass && err == nil {
+			if expr.EvalString(selector+severityExprName) == "warning" {
+				severity = SeverityWarning
+			}
+			if s, ok := opts.Severities[expr.EvalString(selector+codeExprName)]; ok {
+				severity = s
+			}
+		}
+		if !fn(selector, pass, severity, err) {
+			return
+		}
 	}
+}
+
+// newError creates the error of the failed rule by the error factory.
+func (v *Validator) newError(expr *tagexpr.TagExpr, errSelector string) error {
+	fieldSelector := errSelector[:len(errSelector)-1]
 	errMsg := expr.EvalString(errSelector + errMsgExprName)
-	return v.errFactory(errSelector[:len(errSelector)-1], errMsg)
+	err := v.errFactory(fieldSelector, errMsg)
+	if e, ok := err.(*Error); ok {
+		e.Label = labelPath(expr, fieldSelector)
+		e.Code = expr.EvalString(errSelector + codeExprName)
+		e.Count = expr.EvalFloat(errSelector + countExprName)
+		if rule, ok := expr.Expr(errSelector); ok {
+			e.Rule = rule.String()
+		}
+		e.Value = fieldValue(expr, errSelector, fieldSelector)
+	}
+	return err
+}
+
+// fieldValue returns the value of the field for the error, nil if it is not reachable,
+// or the mask of its redact expression, such as tagexpr.DefaultRedactMask for {redact:true} and '[card]' for {redact:'[card]'}.
+func fieldValue(expr *tagexpr.TagExpr, selector, fieldSelector string) interface{} {
+	f, ok := expr.Field(fieldSelector)
+	if !ok {
+		return nil
+	}
+	switch mask := expr.Eval(selector + redactExprName).(type) {
+	case string:
+		return mask
+	case bool:
+		if mask {
+			return tagexpr.DefaultRedactMask
+		}
+	}
+	return f.Interface()
+}
+
+// FailMode the handling of the rules that cannot be evaluated to bool
+type FailMode int
+
+// The fail modes
+const (
+	// FailInvalid the rule whose result is not bool, such as nil, fails as the invalid parameter, the default;
+	// the panic or the mismatched result type is returned as the error
+	FailInvalid FailMode = iota
+	// FailClosed the rule that cannot be evaluated returns its error, such as *tagexpr.NotBoolError and *tagexpr.EvalError
+	FailClosed
+	// FailOpen the rule that cannot be evaluated passes
+	FailOpen
+)
+
+// SetFailMode sets the handling of the rules that cannot be evaluated, such as the panic or the nil result,
+// FailClosed for the strict services to tell them from the invalid parameters.
+func (v *Validator) SetFailMode(mode FailMode) *Validator {
+	v.failMode = mode
+	return v
+}
+
+// UnreachablePolicy the handling of the rules whose operands are unreachable,
+// such as (P.X)$ with the nil P and (M)$['k'] without the key k
+type UnreachablePolicy int
+
+// The unreachable policies
+const (
+	// UnreachableEvaluate the rule is evaluated with the unreachable operands as nil, the default
+	UnreachableEvaluate UnreachablePolicy = iota
+	// UnreachablePass the rule passes
+	UnreachablePass
+	// UnreachableFail the rule fails as the invalid parameter
+	UnreachableFail
+	// UnreachableError the error of tagexpr.ErrUnreachable is returned
+	UnreachableError
+)
+
+// SetUnreachablePolicy sets the handling of the rules whose operands are unreachable.
+// NOTE:
+//  The paths probed by exists and missing, and the nil-safe subscripts such as (P)$?.X, are not unreachable.
+func (v *Validator) SetUnreachablePolicy(policy UnreachablePolicy) *Validator {
+	v.unreachable = policy
+	return v
 }
 
 // SetErrorFactory customizes the factory of validation error.
+// NOTE:
+//  The error code of the rule, such as {code:'USR_NAME_LEN'}, is only set to *Error.
 func (v *Validator) SetErrorFactory(errFactory func(fieldSelector, msg string) error) *Validator {
 	v.errFactory = errFactory
 	return v
 }
 
+// SetCodeStatus maps the error code of the rules to the HTTP status, which is returned by HTTPStatus.
+// NOTE:
+//  It is not concurrency safe, should be called during initialization.
+func (v *Validator) SetCodeStatus(code string, status int) *Validator {
+	if v.statuses == nil {
+		v.statuses = make(map[string]int)
+	}
+	v.statuses[code] = status
+	return v
+}
+
+// HTTPStatus returns the HTTP status of the error returned by Validate.
+// NOTE:
+//  200 if err is nil;
+//  The status mapped by SetCodeStatus if err is *Error with the mapped code, otherwise 400;
+//  500 for the other errors, such as the recovered panic or the non-struct argument.
+func (v *Validator) HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	e, ok := err.(*Error)
+	if !ok {
+		return http.StatusInternalServerError
+	}
+	if status, ok := v.statuses[e.Code]; ok && e.Code != "" {
+		return status
+	}
+	return http.StatusBadRequest
+}
+
+// SetParams sets the named parameters of the rules, such as %max_items% in len($)<=%max_items%.
+// NOTE:
+//  It should be called before the validator is used.
+func (v *Validator) SetParams(params tagexpr.Params) *Validator {
+	v.vm.SetParams(params)
+	return v
+}
+
+// SetFlagProvider sets the provider of the feature flags of the built-in function flag,
+// such as flag('new-policy') for the progressive rollout of the rules.
+func (v *Validator) SetFlagProvider(provider tagexpr.FlagProvider) *Validator {
+	v.vm.SetFlagProvider(provider)
+	return v
+}
+
+// SetJWTAlgs sets the allowed algorithms of the built-in function jwt, such as "RS256" and "ES256",
+// any algorithm but "none" is allowed by default.
+func (v *Validator) SetJWTAlgs(algs ...string) *Validator {
+	v.vm.SetJWTAlgs(algs...)
+	return v
+}
+
+// SetCompatTag sets the tag name of the go-playground/validator syntax, such as validate,
+// which is interpreted when the field has no tag of the validator.
+// NOTE:
+//  It should be called before the validator is used.
+func (v *Validator) SetCompatTag(tagName string) *Validator {
+	v.vm.SetCompatTag(tagName)
+	return v
+}
+
+// SetCostOrder sets whether to evaluate the cheap rules first,
+// such as comparisons before regexps and custom functions.
+// NOTE:
+//  The rules of the same priority are reordered by the estimated cost,
+//  both across the fields and within the && and || chains of a field;
+//  It should be called before the validator is used.
+func (v *Validator) SetCostOrder(enable bool) *Validator {
+	v.costOrder = enable
+	v.vm.SetCostOrder(enable)
+	return v
+}
+
+// SetRecover sets whether to recover the panics when evaluating the rules, the default is true.
+// NOTE:
+//  The recovered panic is returned by Validate as *tagexpr.EvalError;
+//  Disable it in tests to get the original panic and stack.
+func (v *Validator) SetRecover(enable bool) *Validator {
+	v.vm.SetRecover(enable)
+	return v
+}
+
+// SetCoverage sets whether to record the evaluations of the rules and the branches of && and || taken.
+// NOTE:
+//  It should be called before the validator is used, such as in TestMain.
+func (v *Validator) SetCoverage(enable bool) *Validator {
+	v.vm.SetCoverage(enable)
+	return v
+}
+
+// Coverage returns the coverage of the rules of all the validated struct types.
+func (v *Validator) Coverage() []tagexpr.ExprCoverage {
+	return v.vm.Coverage()
+}
+
+// WriteCoverage writes the coverage report of the rules, for finding the dead or untested ones.
+func (v *Validator) WriteCoverage(w io.Writer) error {
+	return v.vm.WriteCoverage(w)
+}
+
+// getOrder returns the match selectors of the struct type in evaluation order.
+// NOTE:
+//  The rules with higher priority, such as {priority:10}, are evaluated first;
+//  The default priority is 0, the priority expression should be constant.
+func (v *Validator) getOrder(t reflect.Type, expr *tagexpr.TagExpr) []string {
+	if order, ok := v.orders.Load(t); ok {
+		return order.([]string)
+	}
+	var order []string
+	priority := make(map[string]float64)
+	cost := make(map[string]int)
+	expr.Range(func(selector string, _ func() interface{}) bool {
+		if !isMatchSelector(selector) {
+			return true
+		}
+		order = append(order, selector)
+		priority[selector] = expr.EvalFloat(selector + priorityExprName)
+		if e, ok := expr.Expr(selector); ok {
+			cost[selector] = e.Cost()
+		}
+		return true
+	})
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if priority[a] != priority[b] {
+			return priority[a] > priority[b]
+		}
+		return v.costOrder && cost[a] < cost[b]
+	})
+	v.orders.Store(t, order)
+	return order
+}
+
+// labelPath returns the labels path of the field, such as 地址.城市 for Addr.City,
+// the field name is used if it has no label; "" if none of the fields has label.
+func labelPath(expr *tagexpr.TagExpr, fieldSelector string) string {
+	names := strings.Split(fieldSelector, ".")
+	var labeled bool
+	labels := make([]string, len(names))
+	for i, name := range names {
+		labels[i] = expr.EvalString(strings.Join(names[:i+1], ".") + "@" + labelExprName)
+		if labels[i] == "" {
+			labels[i] = name
+		} else {
+			labeled = true
+		}
+	}
+	if !labeled {
+		return ""
+	}
+	return strings.Join(labels, ".")
+}
+
+// inVersion reports whether the rule is effective in the version, by its {since:'X'} and {until:'Y'}.
+func inVersion(expr *tagexpr.TagExpr, selector, version string) bool {
+	if since := expr.EvalString(selector + sinceExprName); since != "" && compareVersion(version, since) < 0 {
+		return false
+	}
+	if until := expr.EvalString(selector + untilExprName); until != "" && compareVersion(version, until) >= 0 {
+		return false
+	}
+	return true
+}
+
+// compareVersion compares the dot-separated versions, such as v1.2 and 1.10,
+// the missing parts are 0 and the non-numeric parts are compared as strings.
+func compareVersion(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := "0", "0"
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case xerr == nil && yerr == nil:
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+		case x != y:
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
 func isMatchSelector(selector string) bool {
 	n := len(selector)
 	return n > 1 && selector[n-1] == '@' && selector[n-2] != '@'
@@ -75,6 +495,11 @@ func isMatchSelector(selector string) bool {
 // Error validate error
 type Error struct {
 	FieldSelector, Msg string
+	Label              string      // the labels path of the field, such as {label:'用户名'}, "" if it has no label
+	Code               string      // the error code of the rule, such as {code:'USR_NAME_LEN'}
+	Count              float64     // the count of the rule for the plural messages of Catalog, such as {count:8}
+	Rule               string      // simplified source form of the failed rule
+	Value              interface{} // value of the field, nil if it is not reachable, masked if it has the redact expression
 }
 
 // Error implements error interface.
@@ -82,9 +507,48 @@ func (e *Error) Error() string {
 	if e.Msg != "" {
 		return e.Msg
 	}
+	if e.Label != "" {
+		return "Invalid parameter: " + e.Label
+	}
 	return "Invalid parameter: " + e.FieldSelector
 }
 
+// Is reports whether the target is tagexpr.ErrValidation.
+func (e *Error) Is(target error) bool {
+	return target == tagexpr.ErrValidation
+}
+
+// errorJSON the JSON object of the validation error
+type errorJSON struct {
+	Field   string      `json:"field"`
+	Label   string      `json:"label,omitempty"`
+	Rule    string      `json:"rule"`
+	Message string      `json:"message"`
+	Value   interface{} `json:"value"`
+	Code    string      `json:"code,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, such as:
+//  [{"field":"Name","rule":"$ != ''","message":"Invalid parameter: Name","value":""}]
+// NOTE:
+//  It is always an array, so that the response shape is stable;
+//  The value of the field with the redact expression is masked, such as {redact:true} for the passwords;
+//  The value that cannot be marshaled is formatted by fmt.Sprint.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	obj := errorJSON{
+		Field:   e.FieldSelector,
+		Label:   e.Label,
+		Rule:    e.Rule,
+		Message: e.Error(),
+		Value:   e.Value,
+		Code:    e.Code,
+	}
+	if _, err := json.Marshal(e.Value); err != nil {
+		obj.Value = fmt.Sprint(e.Value)
+	}
+	return json.Marshal([]errorJSON{obj})
+}
+
 func defaultErrorFactory(fieldSelector, msg string) error {
 	return &Error{
 		FieldSelector: fieldSelector,
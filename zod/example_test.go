@@ -0,0 +1,40 @@
+package zod_test
+
+import (
+	"os"
+
+	"github.com/bytedance/go-tagexpr/zod"
+)
+
+func Example() {
+	type Addr struct {
+		Zip string `json:"zip" vd:"regexp('^\\d{5}$')"`
+	}
+	type User struct {
+		Name string   `json:"name" vd:"len($)>=3&&len($)<=32"`
+		Age  int      `json:"age" vd:"$>=18&&$<130"`
+		Role string   `json:"role" vd:"$=='admin'||$=='user'"`
+		Tags []string `json:"tags,omitempty" vd:"len($)<=3"`
+		Addr *Addr    `json:"addr"`
+		Ver  string   `json:"ver" vd:"$!=''&&semver($)"`
+	}
+	if err := zod.New("vd").Generate(os.Stdout, new(User)); err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// import { z } from "zod";
+	//
+	// export const AddrSchema = z.object({
+	//   zip: z.string().regex(/^\d{5}$/),
+	// });
+	//
+	// export const UserSchema = z.object({
+	//   name: z.string().min(3).max(32),
+	//   age: z.number().int().gte(18).lt(130),
+	//   role: z.enum(["admin", "user"]),
+	//   tags: z.array(z.string()).max(3).optional(),
+	//   addr: AddrSchema.nullable(),
+	//   ver: z.string().min(1),
+	// });
+}
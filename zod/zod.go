@@ -0,0 +1,280 @@
+// Copyright 2019 Bytedance Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package zod generates the TypeScript Zod schemas from the struct tag expressions,
+// so that the frontend enforces the same length, range and pattern constraints as the backend.
+package zod
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/go-tagexpr/constraint"
+)
+
+// Generator Zod schema generator
+type Generator struct {
+	extractor *constraint.Extractor
+}
+
+// New creates a Zod schema generator that uses @tagName as the tag name.
+func New(tagName string) *Generator {
+	return &Generator{
+		extractor: constraint.New(tagName),
+	}
+}
+
+// Generate writes the TypeScript module that exports the Zod schemas of the structs.
+// NOTE:
+//  Each named struct type is exported as the const <TypeName>Schema,
+//  and the nested struct types are exported before the ones that use them;
+//  The conditions that cannot be expressed in Zod are left to the backend.
+func (g *Generator) Generate(w io.Writer, structOrStructPtr ...interface{}) error {
+	gen := &generator{
+		w:       bufio.NewWriter(w),
+		emitted: make(map[reflect.Type]bool),
+	}
+	gen.printf("import { z } from \"zod\";\n")
+	for _, v := range structOrStructPtr {
+		if v == nil {
+			return errors.New("cannot generate nil interface")
+		}
+		schema, err := g.extractor.Extract(v)
+		if err != nil {
+			return err
+		}
+		if schema.Type.Name() == "" {
+			return fmt.Errorf("cannot generate unnamed struct type %s", schema.Type)
+		}
+		gen.emit(schema)
+	}
+	return gen.w.Flush()
+}
+
+type generator struct {
+	w        *bufio.Writer
+	emitted  map[reflect.Type]bool
+	visiting []reflect.Type
+}
+
+func (g *generator) printf(format string, a ...interface{}) {
+	fmt.Fprintf(g.w, format, a...)
+}
+
+// emit writes the named schema after its dependencies.
+func (g *generator) emit(schema *constraint.Schema) {
+	if g.emitted[schema.Type] {
+		return
+	}
+	g.emitted[schema.Type] = true
+	g.visiting = append(g.visiting, schema.Type)
+	g.emitDeps(schema)
+	g.visiting = g.visiting[:len(g.visiting)-1]
+	g.printf("\nexport const %s = %s;\n", schemaName(schema.Type), g.object(schema, ""))
+}
+
+func (g *generator) emitDeps(schema *constraint.Schema) {
+	for _, f := range schema.Fields {
+		switch {
+		case f.Elem == nil:
+		case f.Elem.Type.Name() == "":
+			g.emitDeps(f.Elem)
+		default:
+			g.emit(f.Elem)
+		}
+	}
+}
+
+func schemaName(t reflect.Type) string {
+	return t.Name() + "Schema"
+}
+
+func (g *generator) object(schema *constraint.Schema, indent string) string {
+	var b strings.Builder
+	b.WriteString("z.object({\n")
+	for _, f := range schema.Fields {
+		fmt.Fprintf(&b, "%s  %s: %s,\n", indent, propertyName(f.JSONName), g.field(f, indent+"  "))
+	}
+	b.WriteString(indent + "})")
+	return b.String()
+}
+
+var identRegexp = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func propertyName(name string) string {
+	if identRegexp.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+func (g *generator) field(f *constraint.Field, indent string) string {
+	t := f.Type
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t, nullable = t.Elem(), true
+	}
+	s := g.value(t, f.Elem, &f.Constraints, indent)
+	if nullable && !f.Required {
+		s += ".nullable()"
+	}
+	if f.OmitEmpty && !f.Required {
+		s += ".optional()"
+	}
+	return s
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// value returns the schema of the type with the constraints,
+// c is nil for the elements of the slice, array and map.
+func (g *generator) value(t reflect.Type, elem *constraint.Schema, c *constraint.Constraints, indent string) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		if c != nil && len(c.Enum) > 0 {
+			return enum(c.Enum)
+		}
+		return "z.string()" + length(c) + pattern(c)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if c != nil && len(c.Enum) > 0 {
+			return enum(c.Enum)
+		}
+		return "z.number().int()" + numRange(c)
+	case reflect.Float32, reflect.Float64:
+		if c != nil && len(c.Enum) > 0 {
+			return enum(c.Enum)
+		}
+		return "z.number()" + numRange(c)
+	case reflect.Bool:
+		return "z.boolean()"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "z.string()" // base64
+		}
+		return "z.array(" + g.value(t.Elem(), elem, nil, indent) + ")" + length(c)
+	case reflect.Map:
+		return "z.record(" + g.value(t.Elem(), elem, nil, indent) + ")"
+	case reflect.Struct:
+		switch {
+		case t == timeType:
+			return "z.string().datetime({ offset: true })"
+		case t.Name() == "":
+			return g.object(elem, indent)
+		}
+		for _, v := range g.visiting {
+			if v == t {
+				return "z.lazy(() => " + schemaName(t) + ")"
+			}
+		}
+		return schemaName(t)
+	default:
+		return "z.any()"
+	}
+}
+
+func length(c *constraint.Constraints) string {
+	if c == nil {
+		return ""
+	}
+	min, max := c.MinLength, c.MaxLength
+	if min == nil && c.Required {
+		one := 1
+		min = &one
+	}
+	switch {
+	case min != nil && max != nil && *min == *max:
+		return fmt.Sprintf(".length(%d)", *min)
+	case min != nil && max != nil:
+		return fmt.Sprintf(".min(%d).max(%d)", *min, *max)
+	case min != nil:
+		return fmt.Sprintf(".min(%d)", *min)
+	case max != nil:
+		return fmt.Sprintf(".max(%d)", *max)
+	}
+	return ""
+}
+
+func numRange(c *constraint.Constraints) string {
+	if c == nil {
+		return ""
+	}
+	var s string
+	if c.Minimum != nil {
+		if c.ExclusiveMinimum {
+			s += ".gt(" + number(*c.Minimum) + ")"
+		} else {
+			s += ".gte(" + number(*c.Minimum) + ")"
+		}
+	}
+	if c.Maximum != nil {
+		if c.ExclusiveMaximum {
+			s += ".lt(" + number(*c.Maximum) + ")"
+		} else {
+			s += ".lte(" + number(*c.Maximum) + ")"
+		}
+	}
+	return s
+}
+
+// pattern returns the regex refinement, the RE2 syntax is mostly compatible with the JavaScript one.
+func pattern(c *constraint.Constraints) string {
+	if c == nil || c.Pattern == "" {
+		return ""
+	}
+	p, flags := c.Pattern, ""
+	if strings.HasPrefix(p, "(?i)") {
+		p, flags = p[4:], "i"
+	}
+	return ".regex(/" + strings.Replace(p, "/", `\/`, -1) + "/" + flags + ")"
+}
+
+func enum(values []interface{}) string {
+	literals := make([]string, len(values))
+	allString := true
+	for i, v := range values {
+		switch v := v.(type) {
+		case string:
+			literals[i] = strconv.Quote(v)
+		case float64:
+			literals[i], allString = number(v), false
+		default:
+			literals[i], allString = fmt.Sprint(v), false
+		}
+	}
+	if allString {
+		return "z.enum([" + strings.Join(literals, ", ") + "])"
+	}
+	if len(literals) == 1 {
+		return "z.literal(" + literals[0] + ")"
+	}
+	for i, l := range literals {
+		literals[i] = "z.literal(" + l + ")"
+	}
+	return "z.union([" + strings.Join(literals, ", ") + "])"
+}
+
+func number(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}